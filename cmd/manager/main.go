@@ -16,6 +16,8 @@ import (
 	"github.com/atlassian-labs/cyclops/pkg/notifications"
 	"github.com/atlassian-labs/cyclops/pkg/notifications/notifierbuilder"
 	"github.com/operator-framework/operator-lib/leader"
+	"github.com/prometheus/client_golang/api"
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
 	"gopkg.in/alecthomas/kingpin.v2"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
 	"sigs.k8s.io/controller-runtime/pkg/client/config"
@@ -33,7 +35,7 @@ var (
 
 	debug = app.Flag("debug", "Run with debug logging").Short('d').Bool()
 
-	cloudProviderName     = app.Flag("cloud-provider", "Which cloud provider to use, options: [aws]").Default("aws").String()
+	cloudProviderName     = app.Flag("cloud-provider", "Which cloud provider to use, options: [aws, gcp, azure, clusterapi, fake]").Default("aws").String()
 	messagingProviderName = app.Flag("messaging-provider", "Which message provider to use, options: [slack] (Optional)").Default("").String()
 
 	addr      = app.Flag("address", "Address to listen on for /metrics").Default(":8080").String()
@@ -44,8 +46,14 @@ var (
 	deleteCNR                        = app.Flag("delete-cnr", "Whether or not to automatically delete CNRs").Default("false").Bool()
 	deleteCNRExpiry                  = app.Flag("delete-cnr-expiry", "Delete the CNR this long after it was created and is successful").Default("168h").Duration()
 	deleteCNRRequeue                 = app.Flag("delete-cnr-requeue", "How often to check if a CNR can be deleted").Default("24h").Duration()
+	maxCNRRetries                    = app.Flag("max-cnr-retries", "How many times a failed CNR can be retried").Default("3").Int()
+	maxNodesInFlight                 = app.Flag("max-nodes-in-flight", "The maximum number of nodes being cycled at once across all CNRs. 0 for no limit").Default("0").Int64()
+	maxNodesCycledPerHour            = app.Flag("max-nodes-cycled-per-hour", "The maximum number of nodes selected for cycling within an hour across all CNRs. 0 for no limit").Default("0").Int64()
+	prometheusAddress                = app.Flag("prometheus-address", "Address of the Prometheus used to evaluate the Prometheus checks of CNRs").Default("").String()
+	prometheusCheckErrorTimeout      = app.Flag("prometheus-check-error-timeout", "How long the Prometheus checks of a CNR can fail to be evaluated before it is healed. 0 to wait indefinitely").Default("30m").Duration()
 	defaultCNScyclingExpiry          = app.Flag("default-cns-cycling-expiry", "Fail the CNS if it has been cycling for this long").Default("3h").Duration()
 	unhealthyPodTerminationThreshold = app.Flag("unhealthy-pod-termination-after", "How long to tolerate an un-evictable yet unhealthy pod before forcefully removing it").Default("5m").Duration()
+	pdbBlockedWarningThreshold       = app.Flag("pdb-blocked-warning-after", "How long the eviction of a pod can be blocked by a PodDisruptionBudget before a warning is raised").Default("10m").Duration()
 )
 
 var log = logf.Log.WithName("cmd")
@@ -112,18 +120,38 @@ func main() {
 		}
 	}
 
+	// Setup the Prometheus client shared by the Prometheus checks of all CNRs if it is enabled
+	var prometheusAPI promv1.API
+	if *prometheusAddress != "" {
+		prometheusClient, err := api.NewClient(api.Config{
+			Address: *prometheusAddress,
+		})
+		if err != nil {
+			log.Error(err, "Unable to create Prometheus client")
+			os.Exit(1)
+		}
+		prometheusAPI = promv1.NewAPI(prometheusClient)
+	}
+
 	// Configure the CNR transitioner options
 	cnrOptions := cnrTransitioner.Options{
-		DeleteCNR:          *deleteCNR,
-		DeleteCNRExpiry:    *deleteCNRExpiry,
-		DeleteCNRRequeue:   *deleteCNRRequeue,
-		HealthCheckTimeout: *healthCheckTimeout,
+		DeleteCNR:                   *deleteCNR,
+		DeleteCNRExpiry:             *deleteCNRExpiry,
+		DeleteCNRRequeue:            *deleteCNRRequeue,
+		HealthCheckTimeout:          *healthCheckTimeout,
+		MaxRetries:                  *maxCNRRetries,
+		MaxNodesInFlight:            *maxNodesInFlight,
+		MaxNodesCycledPerHour:       *maxNodesCycledPerHour,
+		PrometheusAPI:               prometheusAPI,
+		PrometheusCheckErrorTimeout: *prometheusCheckErrorTimeout,
+		Namespace:                   *namespace,
 	}
 
 	// Configure the CNS transitioner options
 	cnsOptions := cnsTransitioner.Options{
 		DefaultCNScyclingExpiry:          *defaultCNScyclingExpiry,
 		UnhealthyPodTerminationThreshold: *unhealthyPodTerminationThreshold,
+		PDBBlockedWarningThreshold:       *pdbBlockedWarningThreshold,
 	}
 
 	// Set up and register the controllers that will share resources between them
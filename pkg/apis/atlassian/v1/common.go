@@ -1,15 +1,67 @@
 package v1
 
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
 // HasValidMethod returns true if the Method of the CycleSettings is a valid value.
 func (in *CycleSettings) HasValidMethod() bool {
 	switch in.Method {
-	case CycleNodeRequestMethodDrain, CycleNodeRequestMethodWait:
+	case CycleNodeRequestMethodDrain, CycleNodeRequestMethodWait, CycleNodeRequestMethodReplaceInPlace:
 		return true
 	default:
 		return false
 	}
 }
 
+// ResolveConcurrency returns the number of nodes to work on in parallel out of the numNodes selected for cycling. An
+// unset Concurrency defaults to all of the nodes, except with the ReplaceInPlace method where it defaults to one node
+// at a time, since nodes are removed before they are replaced. Percentages are rounded up, and the result is clamped
+// to between 1 and numNodes so that cycling always makes progress.
+func (in *CycleSettings) ResolveConcurrency(numNodes int) (int64, error) {
+	if in.Concurrency.Type == intstr.Int && in.Concurrency.IntVal <= 0 {
+		if in.Method == CycleNodeRequestMethodReplaceInPlace {
+			return 1, nil
+		}
+		return int64(numNodes), nil
+	}
+
+	concurrency, err := intstr.GetScaledValueFromIntOrPercent(&in.Concurrency, numNodes, true)
+	if err != nil {
+		return 0, err
+	}
+
+	if concurrency > numNodes {
+		concurrency = numNodes
+	}
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return int64(concurrency), nil
+}
+
+// PodWave returns the wave the pod is evicted in when draining a node with the Order of the DrainSettings. Lower
+// waves are evicted first, and every pod is in wave 0 if there is no Order.
+func (in *DrainSettings) PodWave(pod *corev1.Pod) int64 {
+	switch in.Order {
+	case DrainOrderPriorityClass:
+		if pod.Spec.Priority != nil {
+			return int64(*pod.Spec.Priority)
+		}
+	case DrainOrderWaveAnnotation:
+		if wave, err := strconv.ParseInt(pod.Annotations[DrainWaveAnnotation], 10, 64); err == nil {
+			return wave
+		}
+	}
+	return 0
+}
+
 // buildNodeGroupNames builds a union of cloud provider node group names
 // based on nodeGroupsList and nodeGroupName
 func buildNodeGroupNames(nodeGroupsList []string, nodeGroupName string) []string {
@@ -31,3 +83,150 @@ func buildNodeGroupNames(nodeGroupsList []string, nodeGroupName string) []string
 
 	return nodeGroups
 }
+
+// Validate returns an error if the PrometheusCheck is not valid. The syntax of its Threshold is checked by
+// checks.ValidatePrometheusCheck.
+func (in *PrometheusCheck) Validate() error {
+	if in.Query == "" {
+		return fmt.Errorf("query cannot be empty")
+	}
+	if in.Threshold == "" {
+		return fmt.Errorf("threshold cannot be empty")
+	}
+	if in.Window != nil && in.Window.Duration < 0 {
+		return fmt.Errorf("window cannot be less than 0 seconds")
+	}
+	return nil
+}
+
+// Validate returns an error if the HealthCheck is not valid. The syntax of its body assertions is checked by
+// checks.ValidateHealthCheck.
+func (in *HealthCheck) Validate() error {
+	switch in.Protocol {
+	case "", HealthCheckProtocolHTTP:
+		if in.GRPCService != "" {
+			return fmt.Errorf("grpcService can only be used with the grpc protocol")
+		}
+	case HealthCheckProtocolTCP, HealthCheckProtocolGRPC:
+		if in.RegexMatch != "" || len(in.BodyAssertions) > 0 || len(in.HeaderMatch) > 0 {
+			return fmt.Errorf("regexMatch, bodyAssertions and headerMatch can only be used with the http protocol")
+		}
+		if in.Protocol == HealthCheckProtocolTCP && in.GRPCService != "" {
+			return fmt.Errorf("grpcService can only be used with the grpc protocol")
+		}
+	default:
+		return fmt.Errorf("unknown protocol %q", in.Protocol)
+	}
+
+	for header, regex := range in.HeaderMatch {
+		if _, err := regexp.Compile(regex); err != nil {
+			return fmt.Errorf("invalid regex for header %s: %v", header, err)
+		}
+	}
+
+	if in.Protocol == HealthCheckProtocolTCP && len(in.Headers) > 0 {
+		return fmt.Errorf("headers can't be used with the tcp protocol")
+	}
+	return validateHTTPHeaders(in.Headers)
+}
+
+// Validate returns an error if the PreTerminationCheck, its cancel request or its HealthCheck, is not valid. The
+// body templates are checked by checks.ValidatePreTerminationCheck.
+func (in *PreTerminationCheck) Validate() error {
+	if err := validatePreTerminationRequest(in.Method, in.Headers); err != nil {
+		return fmt.Errorf("trigger: %v", err)
+	}
+
+	if in.Cancel != nil {
+		if in.Cancel.Endpoint == "" {
+			return fmt.Errorf("cancel: endpoint cannot be empty")
+		}
+		if err := validatePreTerminationRequest(in.Cancel.Method, in.Cancel.Headers); err != nil {
+			return fmt.Errorf("cancel: %v", err)
+		}
+	}
+
+	return in.HealthCheck.Validate()
+}
+
+// validatePreTerminationRequest returns an error if the method or headers of a pre-termination request are not valid
+func validatePreTerminationRequest(method string, headers []HTTPHeader) error {
+	switch method {
+	case "", http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+	default:
+		return fmt.Errorf("unsupported method %q", method)
+	}
+
+	return validateHTTPHeaders(headers)
+}
+
+// validateHTTPHeaders returns an error if any of the headers doesn't have a name, or doesn't have exactly one of a
+// value or a Secret reference.
+func validateHTTPHeaders(headers []HTTPHeader) error {
+	for _, header := range headers {
+		if header.Name == "" {
+			return fmt.Errorf("header name cannot be empty")
+		}
+		if header.SecretKeyRef == nil {
+			continue
+		}
+		if header.Value != "" {
+			return fmt.Errorf("header %s can't have both a value and a secretKeyRef", header.Name)
+		}
+		if header.SecretKeyRef.Name == "" || header.SecretKeyRef.Key == "" {
+			return fmt.Errorf("secretKeyRef of header %s must have a name and key", header.Name)
+		}
+	}
+	return nil
+}
+
+// Validate returns an error if the KubernetesHealthCheck is not valid.
+func (in *KubernetesHealthCheck) Validate() error {
+	switch in.Type {
+	case KubernetesHealthCheckDaemonSetPodsReady:
+		if in.Name != "" && in.Namespace == "" {
+			return fmt.Errorf("namespace must be given with the name of the DaemonSet")
+		}
+	case KubernetesHealthCheckServiceReadyEndpoints, KubernetesHealthCheckDeploymentAvailable:
+		if in.Namespace == "" || in.Name == "" {
+			return fmt.Errorf("namespace and name must be given for the %s type", in.Type)
+		}
+	default:
+		return fmt.Errorf("unknown type %q", in.Type)
+	}
+
+	if in.MinReadyEndpoints < 0 {
+		return fmt.Errorf("minReadyEndpoints cannot be less than 0")
+	}
+	if in.WaitPeriod == nil || in.WaitPeriod.Duration <= 0 {
+		return fmt.Errorf("waitPeriod must be greater than 0 seconds")
+	}
+	return nil
+}
+
+// String describes the KubernetesHealthCheck for events and status messages.
+func (in *KubernetesHealthCheck) String() string {
+	if in.Name == "" {
+		return string(in.Type)
+	}
+	return fmt.Sprintf("%s %s/%s", in.Type, in.Namespace, in.Name)
+}
+
+// Validate returns an error if the fields of the MaintenanceWindow can't be used together. The schedule, days, times
+// and time zone are parsed by maintenance.ValidateWindow.
+func (in *MaintenanceWindow) Validate() error {
+	if in.Schedule != "" {
+		if len(in.Days) > 0 || in.StartTime != "" || in.EndTime != "" {
+			return fmt.Errorf("schedule can't be used with days, startTime or endTime")
+		}
+		if in.Duration == nil || in.Duration.Duration <= 0 {
+			return fmt.Errorf("schedule %q requires a positive duration", in.Schedule)
+		}
+		return nil
+	}
+
+	if len(in.Days) == 0 && in.StartTime == "" && in.EndTime == "" {
+		return fmt.Errorf("either schedule or days, startTime and endTime must be provided")
+	}
+	return nil
+}
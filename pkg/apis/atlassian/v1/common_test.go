@@ -2,10 +2,162 @@ package v1
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
+func TestResolveConcurrency(t *testing.T) {
+	tests := []struct {
+		name        string
+		method      CycleNodeRequestMethod
+		concurrency intstr.IntOrString
+		numNodes    int
+		expect      int64
+		expectError bool
+	}{
+		{"unset defaults to all nodes", CycleNodeRequestMethodDrain, intstr.IntOrString{}, 7, 7, false},
+		{"unset replacing in place defaults to one node", CycleNodeRequestMethodReplaceInPlace, intstr.IntOrString{}, 7, 1, false},
+		{"number", CycleNodeRequestMethodDrain, intstr.FromInt(3), 7, 3, false},
+		{"number replacing in place", CycleNodeRequestMethodReplaceInPlace, intstr.FromInt(3), 7, 3, false},
+		{"number clamped to the nodes", CycleNodeRequestMethodDrain, intstr.FromInt(10), 7, 7, false},
+		{"percentage rounds up", CycleNodeRequestMethodDrain, intstr.FromString("10%"), 15, 2, false},
+		{"small percentage is at least one node", CycleNodeRequestMethodDrain, intstr.FromString("1%"), 7, 1, false},
+		{"zero percent is at least one node", CycleNodeRequestMethodDrain, intstr.FromString("0%"), 7, 1, false},
+		{"percentage clamped to the nodes", CycleNodeRequestMethodDrain, intstr.FromString("200%"), 7, 7, false},
+		{"invalid percentage", CycleNodeRequestMethodDrain, intstr.FromString("ten"), 7, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings := CycleSettings{Method: tt.method, Concurrency: tt.concurrency}
+			concurrency, err := settings.ResolveConcurrency(tt.numNodes)
+			assert.Equal(t, tt.expectError, err != nil)
+			assert.Equal(t, tt.expect, concurrency)
+		})
+	}
+}
+
+func TestHealthCheckValidate(t *testing.T) {
+	tests := []struct {
+		name        string
+		healthCheck HealthCheck
+		expectError bool
+	}{
+		{"default protocol", HealthCheck{Endpoint: "http://{{ .NodeIP }}/health", RegexMatch: "ok"}, false},
+		{"http", HealthCheck{Protocol: HealthCheckProtocolHTTP, Endpoint: "http://{{ .NodeIP }}/health"}, false},
+		{"tcp", HealthCheck{Protocol: HealthCheckProtocolTCP, Endpoint: "{{ .NodeIP }}:9000"}, false},
+		{"grpc with service", HealthCheck{Protocol: HealthCheckProtocolGRPC, Endpoint: "{{ .NodeIP }}:9000", GRPCService: "agent"}, false},
+		{"tcp with regex", HealthCheck{Protocol: HealthCheckProtocolTCP, Endpoint: "{{ .NodeIP }}:9000", RegexMatch: "ok"}, true},
+		{"tcp with grpc service", HealthCheck{Protocol: HealthCheckProtocolTCP, Endpoint: "{{ .NodeIP }}:9000", GRPCService: "agent"}, true},
+		{"http with grpc service", HealthCheck{Endpoint: "http://{{ .NodeIP }}/health", GRPCService: "agent"}, true},
+		{"unknown protocol", HealthCheck{Protocol: "udp", Endpoint: "{{ .NodeIP }}:9000"}, true},
+		{"body assertions", HealthCheck{Endpoint: "http://{{ .NodeIP }}/health", BodyAssertions: []string{`.status == "ok"`}}, false},
+		{"grpc with body assertion", HealthCheck{Protocol: HealthCheckProtocolGRPC, Endpoint: "{{ .NodeIP }}:9000", BodyAssertions: []string{`.status == "ok"`}}, true},
+		{"header match", HealthCheck{Endpoint: "http://{{ .NodeIP }}/health", HeaderMatch: map[string]string{"X-Ready": "^true$"}}, false},
+		{"invalid header regex", HealthCheck{Endpoint: "http://{{ .NodeIP }}/health", HeaderMatch: map[string]string{"X-Ready": "("}}, true},
+		{"secret header", HealthCheck{Endpoint: "http://{{ .NodeIP }}/health", Headers: []HTTPHeader{{Name: "Authorization", SecretKeyRef: &SecretKeyReference{Name: "token", Key: "header"}}}}, false},
+		{"grpc with header", HealthCheck{Protocol: HealthCheckProtocolGRPC, Endpoint: "{{ .NodeIP }}:9000", Headers: []HTTPHeader{{Name: "authorization", Value: "Bearer token"}}}, false},
+		{"tcp with header", HealthCheck{Protocol: HealthCheckProtocolTCP, Endpoint: "{{ .NodeIP }}:9000", Headers: []HTTPHeader{{Name: "authorization", Value: "Bearer token"}}}, true},
+		{"header without name", HealthCheck{Endpoint: "http://{{ .NodeIP }}/health", Headers: []HTTPHeader{{Value: "Bearer token"}}}, true},
+		{"header with value and secret", HealthCheck{Endpoint: "http://{{ .NodeIP }}/health", Headers: []HTTPHeader{{Name: "Authorization", Value: "Bearer token", SecretKeyRef: &SecretKeyReference{Name: "token", Key: "header"}}}}, true},
+		{"secret header without key", HealthCheck{Endpoint: "http://{{ .NodeIP }}/health", Headers: []HTTPHeader{{Name: "Authorization", SecretKeyRef: &SecretKeyReference{Name: "token"}}}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expectError, tt.healthCheck.Validate() != nil)
+		})
+	}
+}
+
+func TestPreTerminationCheckValidate(t *testing.T) {
+	valid := PreTerminationCheck{
+		Endpoint: "http://{{ .NodeIP }}/trigger",
+		Headers:  []HTTPHeader{{Name: "Authorization", SecretKeyRef: &SecretKeyReference{Name: "token", Key: "header"}}},
+	}
+	assert.NoError(t, valid.Validate())
+
+	invalidTrigger := valid
+	invalidTrigger.Headers = []HTTPHeader{{Name: "Authorization", SecretKeyRef: &SecretKeyReference{Key: "header"}}}
+	assert.Error(t, invalidTrigger.Validate())
+
+	invalidHealthCheck := valid
+	invalidHealthCheck.HealthCheck = HealthCheck{Protocol: "udp"}
+	assert.Error(t, invalidHealthCheck.Validate())
+
+	withBody := valid
+	withBody.Method = "PUT"
+	withBody.Body = `{"node": {{ json .NodeName }}, "group": "{{ .NodeGroup }}"}`
+	withBody.Cancel = &PreTerminationCancel{Endpoint: "http://{{ .NodeIP }}/cancel", Method: "DELETE", Body: `{"node": {{ json .NodeName }}}`}
+	assert.NoError(t, withBody.Validate())
+
+	invalidMethod := withBody
+	invalidMethod.Method = "TRACE"
+	assert.Error(t, invalidMethod.Validate())
+
+	invalidCancel := withBody
+	invalidCancel.Cancel = &PreTerminationCancel{Method: "DELETE"}
+	assert.Error(t, invalidCancel.Validate())
+}
+
+func TestKubernetesHealthCheckValidate(t *testing.T) {
+	waitPeriod := &metav1.Duration{Duration: time.Minute}
+
+	tests := []struct {
+		name        string
+		check       KubernetesHealthCheck
+		expectError bool
+	}{
+		{"all daemonset pods", KubernetesHealthCheck{Type: KubernetesHealthCheckDaemonSetPodsReady, WaitPeriod: waitPeriod}, false},
+		{"named daemonset", KubernetesHealthCheck{Type: KubernetesHealthCheckDaemonSetPodsReady, Namespace: "kube-system", Name: "agent", WaitPeriod: waitPeriod}, false},
+		{"named daemonset without namespace", KubernetesHealthCheck{Type: KubernetesHealthCheckDaemonSetPodsReady, Name: "agent", WaitPeriod: waitPeriod}, true},
+		{"service", KubernetesHealthCheck{Type: KubernetesHealthCheckServiceReadyEndpoints, Namespace: "default", Name: "web", MinReadyEndpoints: 2, WaitPeriod: waitPeriod}, false},
+		{"service without name", KubernetesHealthCheck{Type: KubernetesHealthCheckServiceReadyEndpoints, Namespace: "default", WaitPeriod: waitPeriod}, true},
+		{"service negative endpoints", KubernetesHealthCheck{Type: KubernetesHealthCheckServiceReadyEndpoints, Namespace: "default", Name: "web", MinReadyEndpoints: -1, WaitPeriod: waitPeriod}, true},
+		{"deployment", KubernetesHealthCheck{Type: KubernetesHealthCheckDeploymentAvailable, Namespace: "default", Name: "web", WaitPeriod: waitPeriod}, false},
+		{"deployment without wait period", KubernetesHealthCheck{Type: KubernetesHealthCheckDeploymentAvailable, Namespace: "default", Name: "web"}, true},
+		{"unknown type", KubernetesHealthCheck{Type: "PodsReady", WaitPeriod: waitPeriod}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expectError, tt.check.Validate() != nil)
+		})
+	}
+}
+
+func TestDrainSettingsPodWave(t *testing.T) {
+	priority := int32(1000)
+	withPriority := &corev1.Pod{Spec: corev1.PodSpec{Priority: &priority}}
+	withAnnotation := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{DrainWaveAnnotation: "-2"}}}
+	withInvalidAnnotation := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{DrainWaveAnnotation: "first"}}}
+
+	tests := []struct {
+		name   string
+		order  DrainOrder
+		pod    *corev1.Pod
+		expect int64
+	}{
+		{"no order", "", withPriority, 0},
+		{"priority", DrainOrderPriorityClass, withPriority, 1000},
+		{"no priority", DrainOrderPriorityClass, withAnnotation, 0},
+		{"annotation", DrainOrderWaveAnnotation, withAnnotation, -2},
+		{"no annotation", DrainOrderWaveAnnotation, withPriority, 0},
+		{"invalid annotation", DrainOrderWaveAnnotation, withInvalidAnnotation, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings := DrainSettings{Order: tt.order}
+			assert.Equal(t, tt.expect, settings.PodWave(tt.pod))
+		})
+	}
+}
+
 func TestBuildNodeGroupNames(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -87,3 +239,62 @@ func TestBuildNodeGroupNames(t *testing.T) {
 		})
 	}
 }
+
+func TestHasValidMethod(t *testing.T) {
+	tests := []struct {
+		method CycleNodeRequestMethod
+		expect bool
+	}{
+		{CycleNodeRequestMethodDrain, true},
+		{CycleNodeRequestMethodWait, true},
+		{CycleNodeRequestMethodReplaceInPlace, true},
+		{"", false},
+		{"Terminate", false},
+	}
+
+	for _, test := range tests {
+		t.Run(string(test.method), func(t *testing.T) {
+			settings := CycleSettings{Method: test.method}
+			assert.Equal(t, test.expect, settings.HasValidMethod())
+		})
+	}
+}
+
+func TestPrometheusCheckValidate(t *testing.T) {
+	tests := []struct {
+		name        string
+		check       PrometheusCheck
+		expectError bool
+	}{
+		{"valid", PrometheusCheck{Query: "up", Threshold: "> 0"}, false},
+		{"without query", PrometheusCheck{Threshold: "> 0"}, true},
+		{"without threshold", PrometheusCheck{Query: "up"}, true},
+		{"negative window", PrometheusCheck{Query: "up", Threshold: "> 0", Window: &metav1.Duration{Duration: -time.Minute}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expectError, tt.check.Validate() != nil)
+		})
+	}
+}
+
+func TestMaintenanceWindowValidate(t *testing.T) {
+	tests := []struct {
+		name        string
+		window      MaintenanceWindow
+		expectError bool
+	}{
+		{"days and times", MaintenanceWindow{Days: []string{"Mon"}, StartTime: "22:00", EndTime: "02:00"}, false},
+		{"schedule", MaintenanceWindow{Schedule: "0 22 * * 1-5", Duration: &metav1.Duration{Duration: time.Hour}}, false},
+		{"schedule without duration", MaintenanceWindow{Schedule: "0 22 * * 1-5"}, true},
+		{"schedule with days", MaintenanceWindow{Schedule: "0 22 * * *", Duration: &metav1.Duration{Duration: time.Hour}, Days: []string{"Mon"}}, true},
+		{"empty window", MaintenanceWindow{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expectError, tt.window.Validate() != nil)
+		})
+	}
+}
@@ -2,6 +2,7 @@ package v1
 
 import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 // CycleNodeRequestMethod is the method to use when cycling nodes.
@@ -15,18 +16,89 @@ const (
 	// CycleNodeRequestMethodWait waits for pods to leave the node before terminating it.
 	// It will ignore DaemonSets and select pods. These can be configured in the CRD spec.
 	CycleNodeRequestMethodWait = "Wait"
+
+	// CycleNodeRequestMethodReplaceInPlace drains and terminates nodes before their replacements are created,
+	// keeping the desired capacity of the node group as it is. This is for node groups which can't scale up
+	// above their current size. Nodes are cycled in batches of up to Concurrency nodes, and the next batch is
+	// only started once the node group has replaced the terminated nodes.
+	CycleNodeRequestMethodReplaceInPlace = "ReplaceInPlace"
+)
+
+// CycleNodeRequestZoneStrategy is the strategy to use for selecting nodes across availability zones.
+type CycleNodeRequestZoneStrategy string
+
+const (
+	// CycleNodeRequestZoneStrategyOneZoneAtATime cycles all of the nodes in one zone before moving on to the next.
+	// Zones are cycled in alphabetical order.
+	CycleNodeRequestZoneStrategyOneZoneAtATime = "OneZoneAtATime"
+
+	// CycleNodeRequestZoneStrategySpreadAcrossZones spreads each batch of nodes evenly across the zones, taking the
+	// nodes that are already being cycled into account.
+	CycleNodeRequestZoneStrategySpreadAcrossZones = "SpreadAcrossZones"
+)
+
+// CycleNodeRequestSelectionStrategy is the strategy to use for ordering the nodes to cycle.
+type CycleNodeRequestSelectionStrategy string
+
+const (
+	// CycleNodeRequestSelectionStrategyOldestFirst cycles the nodes with the oldest creation timestamp first.
+	CycleNodeRequestSelectionStrategyOldestFirst = "OldestFirst"
+
+	// CycleNodeRequestSelectionStrategyFewestPodsFirst cycles the nodes running the fewest pods first, not counting
+	// DaemonSet and static pods.
+	CycleNodeRequestSelectionStrategyFewestPodsFirst = "FewestPodsFirst"
+
+	// CycleNodeRequestSelectionStrategyLeastRequestedFirst cycles the nodes with the lowest share of their
+	// allocatable cpu and memory requested by pods first.
+	CycleNodeRequestSelectionStrategyLeastRequestedFirst = "LeastRequestedFirst"
+
+	// CycleNodeRequestSelectionStrategyNodeNamesOrder cycles the nodes in the order they are given in NodeNames.
+	CycleNodeRequestSelectionStrategyNodeNamesOrder = "NodeNamesOrder"
+)
+
+// DrainOrder is the order pods are evicted in when a node is drained.
+type DrainOrder string
+
+const (
+	// DrainOrderPriorityClass evicts pods in waves by the priority of their PriorityClass, lowest priority first.
+	// Pods without a priority are treated as having a priority of 0.
+	DrainOrderPriorityClass = "PriorityClass"
+
+	// DrainOrderWaveAnnotation evicts pods in waves by the integer in their DrainWaveAnnotation, lowest wave first.
+	// Pods without the annotation, or with a value that isn't an integer, are in wave 0.
+	DrainOrderWaveAnnotation = "WaveAnnotation"
 )
 
+// DrainWaveAnnotation is the pod annotation giving the wave a pod is evicted in with the WaveAnnotation DrainOrder
+const DrainWaveAnnotation = "cyclops.atlassian.com/drain-wave"
+
 // CycleSettings are configuration options to control how nodes are cycled
 // +k8s:openapi-gen=true
 type CycleSettings struct {
 	// Method describes the type of cycle operation to use.
-	// +kubebuilder:validation:Enum=Drain;Wait
+	// +kubebuilder:validation:Enum=Drain;Wait;ReplaceInPlace
 	Method CycleNodeRequestMethod `json:"method"`
 
-	// Concurrency is the number of nodes that one CycleNodeRequest will work on in parallel.
-	// Defaults to the size of the node group.
-	Concurrency int64 `json:"concurrency,omitempty"`
+	// Concurrency is the number of nodes that one CycleNodeRequest will work on in parallel. It is either a
+	// number of nodes, or a percentage of the nodes selected for the CycleNodeRequest such as "10%", which is
+	// rounded up. Defaults to the number of nodes selected, or to one node with the ReplaceInPlace method.
+	// +kubebuilder:validation:XIntOrString
+	Concurrency intstr.IntOrString `json:"concurrency,omitempty"`
+
+	// SelectionStrategy controls the order the nodes are cycled in. The order is worked out again each time nodes are
+	// selected, so it follows changes in the pods running on the nodes. Defaults to the order the nodes are listed in.
+	// +kubebuilder:validation:Enum=OldestFirst;FewestPodsFirst;LeastRequestedFirst;NodeNamesOrder
+	SelectionStrategy CycleNodeRequestSelectionStrategy `json:"selectionStrategy,omitempty"`
+
+	// ZoneStrategy controls how nodes are selected across the zones in the topology.kubernetes.io/zone label of the
+	// nodes. The replacements for a batch of nodes must come up in the same zones before cycling continues.
+	// Defaults to selecting nodes without regard to their zone.
+	// +kubebuilder:validation:Enum=OneZoneAtATime;SpreadAcrossZones
+	ZoneStrategy CycleNodeRequestZoneStrategy `json:"zoneStrategy,omitempty"`
+
+	// Rollout cycles a small canary batch of nodes first and ramps the batch size up to the Concurrency, baking
+	// after each batch before selecting the next one. Defaults to cycling every batch at the full Concurrency.
+	Rollout *CycleRollout `json:"rollout,omitempty"`
 
 	// LabelsToRemove is an array of labels to remove off of the pods running on the node
 	// This can be used to remove a pod from a service/endpoint before evicting/deleting
@@ -46,30 +118,208 @@ type CycleSettings struct {
 	// in-progress CNS request timeout from the time it's worked on by the controller.
 	// If no cyclingTimeout is provided, CNS will use the default controller CNS cyclingTimeout.
 	CyclingTimeout *metav1.Duration `json:"cyclingTimeout,omitempty"`
+
+	// Drain controls how pods are evicted from the nodes with the Drain and ReplaceInPlace methods. Defaults to
+	// evicting every drainable pod on a node at once.
+	Drain *DrainSettings `json:"drain,omitempty"`
 }
 
+// DrainSettings limit how many pods are evicted from a node at once, and the order they are evicted in. When an Order
+// is set the pods are evicted in waves, and each wave is only started once every pod of the previous wave has left
+// the node.
+// +k8s:openapi-gen=true
+type DrainSettings struct {
+	// MaxConcurrentEvictions is the most pods that are evicted from a node at the same time. Evicted pods count
+	// against it until they have left the node. Defaults to no limit.
+	// +kubebuilder:validation:Minimum=0
+	MaxConcurrentEvictions int32 `json:"maxConcurrentEvictions,omitempty"`
+
+	// Order groups the pods on a node into waves that are evicted one after the other. Defaults to evicting all of
+	// the pods in a single wave.
+	// +kubebuilder:validation:Enum=PriorityClass;WaveAnnotation
+	Order DrainOrder `json:"order,omitempty"`
+
+	// BlockedWarningAfter is how long the eviction of a pod can be blocked by a PodDisruptionBudget before a warning
+	// event and notification are raised. Overrides the --pdb-blocked-warning-after flag of the controller.
+	BlockedWarningAfter *metav1.Duration `json:"blockedWarningAfter,omitempty"`
+
+	// BlockedEscalation removes pods whose eviction has been blocked by a PodDisruptionBudget for too long. By
+	// default blocked pods are waited for until the CycleNodeStatus times out.
+	BlockedEscalation *DrainEscalation `json:"blockedEscalation,omitempty"`
+}
+
+// DrainEscalationAction is how a pod whose eviction is blocked is removed from the node.
+type DrainEscalationAction string
+
+const (
+	// DrainEscalationActionDelete deletes the pod with its termination grace period. Deleting a pod bypasses its
+	// PodDisruptionBudget.
+	DrainEscalationActionDelete = "Delete"
+
+	// DrainEscalationActionForceDelete deletes the pod immediately, without waiting for it to terminate.
+	DrainEscalationActionForceDelete = "ForceDelete"
+)
+
+// DrainEscalation removes pods whose eviction has been blocked by a PodDisruptionBudget for longer than After.
+// +k8s:openapi-gen=true
+type DrainEscalation struct {
+	// Action is how the pods are removed from the node.
+	// +kubebuilder:validation:Enum=Delete;ForceDelete
+	Action DrainEscalationAction `json:"action"`
+
+	// After is how long the eviction of a pod must have been blocked for before it is removed. It must be longer
+	// than BlockedWarningAfter when that is set. Pods are only removed once they have been warned about, so an
+	// After shorter than the --pdb-blocked-warning-after flag of the controller is extended to it.
+	After metav1.Duration `json:"after"`
+}
+
+// CycleRollout configures a canary batch and a progressive ramp-up of the batch size. Each batch of the ramp-up must
+// finish, and the CycleNodeRequest holds in the Baking phase for the BakeDuration, before the next batch is selected.
+// Once the ramp-up is complete the rest of the nodes are cycled at the full Concurrency without baking.
+// +k8s:openapi-gen=true
+type CycleRollout struct {
+	// CanaryBatchSize is the number of nodes in the first batch. Defaults to 1.
+	CanaryBatchSize int64 `json:"canaryBatchSize,omitempty"`
+
+	// BakeDuration is how long to wait after a batch of the ramp-up has finished, and the new nodes have passed their
+	// health checks, before the next batch is selected. The health checks are repeated while baking, and any failure
+	// sends the CycleNodeRequest to Healing.
+	BakeDuration *metav1.Duration `json:"bakeDuration,omitempty"`
+
+	// Steps are the sizes of the batches after the canary batch, either as a number of nodes or a percentage of the
+	// Concurrency such as "25%", which is rounded up. For example ["25%", "100%"].
+	Steps []intstr.IntOrString `json:"steps,omitempty"`
+}
+
+// MaintenanceWindow defines a recurring period of time in which nodes are allowed to be cycled. The window is either
+// a cron Schedule that opens it for a Duration, or a time of day range on a set of Days.
+// +k8s:openapi-gen=true
+type MaintenanceWindow struct {
+	// Schedule is a standard 5 field cron expression for when the window opens, e.g. "0 22 * * 1-5".
+	// Requires Duration, and can't be used with Days, StartTime or EndTime.
+	Schedule string `json:"schedule,omitempty"`
+
+	// Duration is how long the window stays open for each time the Schedule fires.
+	Duration *metav1.Duration `json:"duration,omitempty"`
+
+	// Days is the list of days of the week that the window opens on, e.g. ["Mon", "Tue"]. Defaults to every day.
+	Days []string `json:"days,omitempty"`
+
+	// StartTime is the time of day the window opens, in the 24 hour "15:04" format.
+	StartTime string `json:"startTime,omitempty"`
+
+	// EndTime is the time of day the window closes, in the 24 hour "15:04" format. If it is not after the StartTime
+	// then the window closes on the following day.
+	EndTime string `json:"endTime,omitempty"`
+
+	// TimeZone is the IANA time zone name the window is defined in, e.g. "Australia/Sydney". Defaults to UTC.
+	TimeZone string `json:"timeZone,omitempty"`
+}
+
+// HealthCheckProtocol is the protocol used to perform a health check.
+type HealthCheckProtocol string
+
+const (
+	// HealthCheckProtocolHTTP makes a http GET request to the endpoint and checks the status code and body of the
+	// response. This is the default protocol.
+	HealthCheckProtocolHTTP = "http"
+
+	// HealthCheckProtocolTCP passes when a tcp connection can be made to the endpoint, given as "host:port".
+	HealthCheckProtocolTCP = "tcp"
+
+	// HealthCheckProtocolGRPC calls the Check method of the grpc.health.v1 Health service at the endpoint, given as
+	// "host:port", and passes when it returns SERVING.
+	HealthCheckProtocolGRPC = "grpc"
+)
+
 // HealthCheck defines the health check configuration for the NodeGroup
 // +k8s:openapi-gen=true
 type HealthCheck struct {
 	// Endpoint url of the health check. Optional: {{ .NodeIP }} gets replaced by the private IP of the node being scaled up.
+	// For the tcp and grpc protocols it is a "host:port" address instead of a url.
 	Endpoint string `json:"endpoint"`
 
+	// Protocol used to perform the health check. Defaults to http.
+	// +kubebuilder:validation:Enum=http;tcp;grpc
+	Protocol HealthCheckProtocol `json:"protocol,omitempty"`
+
+	// GRPCService is the name of the service to check with the grpc protocol. Defaults to the overall health of the
+	// server.
+	GRPCService string `json:"grpcService,omitempty"`
+
 	// WaitPeriod is the time allowed for the health check to pass before considering the
 	// service unhealthy and failing the CycleNodeRequest.
 	WaitPeriod *metav1.Duration `json:"waitPeriod"`
 
 	// ValidStatusCodes keeps track of the list of possible status codes returned by
-	// the endpoint denoting the service as healthy. Defaults to [200].
+	// the endpoint denoting the service as healthy. Defaults to [200]. Only used with the http protocol.
 	ValidStatusCodes []uint `json:"validStatusCodes,omitempty"`
 
 	// RegexMatch specifies a regex string the body of the http result to should. By default no matching is done.
+	// Only used with the http protocol.
 	RegexMatch string `json:"regexMatch,omitempty"`
 
+	// BodyAssertions are assertions on the JSON body of the http response. Each is a JSONPath, optionally followed by
+	// "| length", then an operator and a JSON value, e.g. `.status == "ok"` or `.peers | length >= 3`. The operators
+	// are <, <=, >, >=, == and !=, and only == and != can be used with values that are not numbers. When the path
+	// matches more than one value, every value must satisfy the assertion. Only used with the http protocol.
+	BodyAssertions []string `json:"bodyAssertions,omitempty"`
+
+	// HeaderMatch maps the names of http response headers to a regex that one of the values of the header must match.
+	// Only used with the http protocol.
+	HeaderMatch map[string]string `json:"headerMatch,omitempty"`
+
+	// Headers are added to the http request, such as an Authorization header with a bearer token read from a Secret.
+	// Only used with the http protocol.
+	Headers []HTTPHeader `json:"headers,omitempty"`
+
 	// TLS configuration for the http client to make requests. Can either make standard https requests
-	// or optionally forward certs signed by the root CA for mTLS.
+	// or optionally forward certs signed by the root CA for mTLS. The grpc protocol uses TLS when a RootCA
+	// or Certificate is configured.
 	TLSConfig `json:"tls,omitempty"`
 }
 
+// KubernetesHealthCheckType is the type of a Kubernetes health check.
+type KubernetesHealthCheckType string
+
+const (
+	// KubernetesHealthCheckDaemonSetPodsReady passes when all of the DaemonSet pods scheduled on the new node are
+	// Ready. Namespace and Name can be given to only check the pods of one DaemonSet, which must then have a pod on
+	// the node.
+	KubernetesHealthCheckDaemonSetPodsReady = "DaemonSetPodsReady"
+
+	// KubernetesHealthCheckServiceReadyEndpoints passes when the Service given by Namespace and Name has at least
+	// MinReadyEndpoints ready endpoints.
+	KubernetesHealthCheckServiceReadyEndpoints = "ServiceReadyEndpoints"
+
+	// KubernetesHealthCheckDeploymentAvailable passes when the Deployment given by Namespace and Name has at least
+	// as many available replicas as it desires.
+	KubernetesHealthCheckDeploymentAvailable = "DeploymentAvailable"
+)
+
+// KubernetesHealthCheck defines a health check on new nodes that looks at objects in the API server instead of
+// making a request to the node.
+// +k8s:openapi-gen=true
+type KubernetesHealthCheck struct {
+	// Type is the kind of check to perform.
+	// +kubebuilder:validation:Enum=DaemonSetPodsReady;ServiceReadyEndpoints;DeploymentAvailable
+	Type KubernetesHealthCheckType `json:"type"`
+
+	// Namespace of the object to check. Required for the ServiceReadyEndpoints and DeploymentAvailable types.
+	Namespace string `json:"namespace,omitempty"`
+
+	// Name of the object to check. Required for the ServiceReadyEndpoints and DeploymentAvailable types.
+	Name string `json:"name,omitempty"`
+
+	// MinReadyEndpoints is the number of ready endpoints the Service needs for the ServiceReadyEndpoints type.
+	// Defaults to 1.
+	MinReadyEndpoints int32 `json:"minReadyEndpoints,omitempty"`
+
+	// WaitPeriod is the time allowed for the health check to pass before considering the
+	// service unhealthy and failing the CycleNodeRequest.
+	WaitPeriod *metav1.Duration `json:"waitPeriod"`
+}
+
 // PreTerminationCheck defines the configuration for the check done before terminating an instance. The trigger can be
 // considered a http sigterm and the subsequent check to know when the process has completed it's triggered action.
 // +k8s:openapi-gen=true
@@ -81,6 +331,24 @@ type PreTerminationCheck struct {
 	// the endpoint denoting the service as healthy. Defaults to [200].
 	ValidStatusCodes []uint `json:"validStatusCodes,omitempty"`
 
+	// Headers are added to the trigger request, such as an Authorization header with a bearer token read from a Secret.
+	Headers []HTTPHeader `json:"headers,omitempty"`
+
+	// Method is the http method of the trigger request. Defaults to POST.
+	// +kubebuilder:validation:Enum=GET;POST;PUT;PATCH;DELETE
+	Method string `json:"method,omitempty"`
+
+	// Body is a template for the JSON body of the trigger request. The fields {{ .NodeName }}, {{ .NodeIP }},
+	// {{ .ProviderID }}, {{ .NodeGroup }} and {{ .CycleNodeRequest }} are available, and the json function quotes a
+	// value as a JSON string, e.g. {"node": {{ json .NodeName }}}. The rendered body must be valid JSON, and is sent
+	// with a Content-Type of application/json unless Headers set one. By default no body is sent.
+	Body string `json:"body,omitempty"`
+
+	// Cancel is an optional request sent for a node if the CycleNodeRequest fails or is cancelled after the trigger
+	// was sent, but before the node was cordoned and handed off to be terminated. It lets the upstream host undo the
+	// action started by the trigger.
+	Cancel *PreTerminationCancel `json:"cancel,omitempty"`
+
 	// HealthCheck denotes the configuration for performing health checks after the trigger has been sent. This works the
 	// exact same way as health check on new nodes.
 	HealthCheck `json:"healthCheck"`
@@ -90,6 +358,28 @@ type PreTerminationCheck struct {
 	TLSConfig `json:"tls,omitempty"`
 }
 
+// PreTerminationCancel defines the request sent to undo a pre-termination trigger. It uses the TLS configuration of
+// the trigger.
+// +k8s:openapi-gen=true
+type PreTerminationCancel struct {
+	// Endpoint url of the cancel request. Optional: {{ .NodeIP }} gets replaced by the private IP of the node.
+	Endpoint string `json:"endpoint"`
+
+	// Method is the http method of the cancel request. Defaults to POST.
+	// +kubebuilder:validation:Enum=GET;POST;PUT;PATCH;DELETE
+	Method string `json:"method,omitempty"`
+
+	// Headers are added to the cancel request.
+	Headers []HTTPHeader `json:"headers,omitempty"`
+
+	// Body is a template for the JSON body of the cancel request, with the same fields as the Body of the trigger.
+	Body string `json:"body,omitempty"`
+
+	// ValidStatusCodes keeps track of the list of possible status codes returned by
+	// the endpoint denoting the cancel as successful. Defaults to [200].
+	ValidStatusCodes []uint `json:"validStatusCodes,omitempty"`
+}
+
 // TLSConfig defined the tls configuration for the http client to make a request.
 // +k8s:openapi-gen=true
 type TLSConfig struct {
@@ -103,4 +393,53 @@ type TLSConfig struct {
 	// Key is the private key which forms a pair with the certificate. It is
 	// sent as part of the request to the upstream host for mTLS.
 	Key string `json:"key,omitempty"`
+
+	// SecretName is the name of a Secret in the namespace of the CycleNodeRequest holding the TLS material in the
+	// ca.crt, tls.crt and tls.key keys, as in a kubernetes.io/tls Secret. It is read each time a request is made and
+	// takes precedence over RootCA, Certificate and Key. Any of the keys can be left out.
+	SecretName string `json:"secretName,omitempty"`
+}
+
+// HTTPHeader is a header added to a http request, with a value given inline or read from a Secret.
+// +k8s:openapi-gen=true
+type HTTPHeader struct {
+	// Name of the header.
+	Name string `json:"name"`
+
+	// Value of the header.
+	Value string `json:"value,omitempty"`
+
+	// SecretKeyRef reads the value of the header from a key of a Secret in the namespace of the CycleNodeRequest each
+	// time a request is made. Can't be used with Value.
+	SecretKeyRef *SecretKeyReference `json:"secretKeyRef,omitempty"`
+}
+
+// SecretKeyReference selects a key of a Secret in the namespace of the CycleNodeRequest.
+// +k8s:openapi-gen=true
+type SecretKeyReference struct {
+	// Name of the Secret.
+	Name string `json:"name"`
+
+	// Key of the Secret to read.
+	Key string `json:"key"`
+}
+
+// PrometheusCheck defines a Prometheus query used as a global gate on cycling, such as the error rate of a service or
+// the alerts firing for a team. It is checked before each batch of nodes is selected and while a batch is baking.
+// +k8s:openapi-gen=true
+type PrometheusCheck struct {
+	// Name describes the check in events and status messages.
+	Name string `json:"name"`
+
+	// Query is the PromQL expression to evaluate, e.g. `ALERTS{alertstate="firing",team="x"}`.
+	Query string `json:"query"`
+
+	// Threshold is the comparison every sample returned by the query must satisfy for the check to pass, given as an
+	// operator and a number such as "< 0.01" or "== 0". The operators are <, <=, >, >=, == and !=. A query that
+	// returns no samples passes.
+	Threshold string `json:"threshold"`
+
+	// Window is the period the query is evaluated over, ending at the time of the check. Every sample within the
+	// window must satisfy the Threshold. Defaults to only evaluating the query at the time of the check.
+	Window *metav1.Duration `json:"window,omitempty"`
 }
@@ -29,15 +29,36 @@ type CycleNodeRequestSpec struct {
 	// HealthChecks stores the settings to configure instance custom health checks
 	HealthChecks []HealthCheck `json:"healthChecks,omitempty"`
 
+	// KubernetesHealthChecks stores the settings to configure health checks on new instances which look at
+	// objects in the API server, such as the DaemonSet pods on the node or the available replicas of a Deployment
+	KubernetesHealthChecks []KubernetesHealthCheck `json:"kubernetesHealthChecks,omitempty"`
+
 	// PreTerminationChecks stores the settings to configure instance pre-termination checks
 	PreTerminationChecks []PreTerminationCheck `json:"preTerminationChecks,omitempty"`
 
+	// PrometheusChecks are Prometheus queries that must pass before each batch of nodes is selected, and while a batch
+	// of the rollout is baking. They require the controller to be given the address of Prometheus.
+	PrometheusChecks []PrometheusCheck `json:"prometheusChecks,omitempty"`
+
 	// SkipInitialHealthChecks is an optional flag to skip the initial set of node health checks before cycling begins
 	// This does not affect the health checks performed as part of the pre-termination checks.
 	SkipInitialHealthChecks bool `json:"skipInitialHealthChecks,omitempty"`
 
 	// SkipPreTerminationChecks is an optional flag to skip pre-termination checks during cycling
 	SkipPreTerminationChecks bool `json:"skipPreTerminationChecks,omitempty"`
+
+	// MaintenanceWindows is an optional list of windows in which the nodes can be cycled. New nodes are only selected
+	// for cycling while one of the windows is open. Nodes can be cycled at any time if none are given.
+	MaintenanceWindows []MaintenanceWindow `json:"maintenanceWindows,omitempty"`
+
+	// Paused stops new nodes from being selected for cycling. Nodes that are already being cycled are left to finish.
+	// Setting it back to false resumes the CycleNodeRequest.
+	Paused bool `json:"paused,omitempty"`
+
+	// Cancel requests a graceful abort of the CycleNodeRequest. No new nodes are selected, nodes that were detached
+	// but not yet handed off for draining are re-attached to their node group and uncordoned, and the
+	// CycleNodeRequest ends in the Cancelled phase. Nodes that are already being drained are left to finish.
+	Cancel bool `json:"cancel,omitempty"`
 }
 
 // CycleNodeRequestStatus defines the observed state of CycleNodeRequest
@@ -63,6 +84,14 @@ type CycleNodeRequestStatus struct {
 	// we fail the request.
 	ScaleUpStarted *metav1.Time `json:"scaleUpStarted,omitempty"`
 
+	// NodeGroupSize stores the number of instances in the node groups when the request started. The ReplaceInPlace
+	// method waits for the node groups to be back at this size before selecting more nodes to cycle.
+	NodeGroupSize int `json:"nodeGroupSize,omitempty"`
+
+	// Concurrency is the number of nodes worked on in parallel, resolved from the Concurrency in the CycleSettings
+	// against the number of nodes selected for cycling.
+	Concurrency int64 `json:"concurrency,omitempty"`
+
 	// EquilibriumWaitStarted stores the time when we started waiting for equilibrium of Kube nodes and node group instances.
 	// This is used to give some leeway if we start a request at the same time as a cluster scaling event.
 	// If we breach the time limit we fail the request.
@@ -89,6 +118,43 @@ type CycleNodeRequestStatus struct {
 
 	// PreTerminationChecks keeps track of the instance pre termination check information
 	PreTerminationChecks map[string]PreTerminationCheckStatusList `json:"preTerminationChecks,omitempty"`
+
+	// Retries counts how many times the CycleNodeRequest has been retried after failing
+	Retries int `json:"retries,omitempty"`
+
+	// PlannedOrder stores the names of the nodes left to cycle, in the order they are planned to be selected. It is
+	// updated each time nodes are selected.
+	PlannedOrder []string `json:"plannedOrder,omitempty"`
+
+	// ZoneReplacements stores how many replacement nodes are expected in each zone for the current batch of nodes.
+	// It is only set when a ZoneStrategy is used, to confirm the replacements came up in the same zones.
+	ZoneReplacements map[string]int `json:"zoneReplacements,omitempty"`
+
+	// BatchStarted stores the time the current batch of nodes was selected. Nodes created after this time are
+	// counted as replacements for the batch.
+	BatchStarted *metav1.Time `json:"batchStarted,omitempty"`
+
+	// RolloutBatch counts the batches of nodes selected for the rollout in the CycleSettings, to work out the size of
+	// the next batch.
+	RolloutBatch int `json:"rolloutBatch,omitempty"`
+
+	// BakeStarted stores the time the CycleNodeRequest started baking the last batch of nodes
+	BakeStarted *metav1.Time `json:"bakeStarted,omitempty"`
+
+	// PrometheusChecksErroringSince stores the time the Prometheus checks first failed to be evaluated. It is cleared
+	// once they can be evaluated again, and is used to heal the CycleNodeRequest if they can't be for too long.
+	PrometheusChecksErroringSince *metav1.Time `json:"prometheusChecksErroringSince,omitempty"`
+
+	// NodeSelectionTimes stores the time each node was selected for cycling within the last hour. It is used to
+	// enforce the cluster-wide budget of nodes cycled per hour across all CycleNodeRequests.
+	NodeSelectionTimes []metav1.Time `json:"nodeSelectionTimes,omitempty"`
+
+	// Conditions are the latest observations of the state of the CycleNodeRequest
+	// +listType=map
+	// +listMapKey=type
+	// +patchStrategy=merge
+	// +patchMergeKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
 }
 
 // CycleNodeRequestNode stores a current node that is being worked on
@@ -105,6 +171,9 @@ type CycleNodeRequestNode struct {
 
 	// Private ip of the instance
 	PrivateIP string `json:"privateIp,omitempty"`
+
+	// Zone is the availability zone of the node from its topology.kubernetes.io/zone label
+	Zone string `json:"zone,omitempty"`
 }
 
 // HealthCheckStatus groups all health checks status information for a node
@@ -115,6 +184,10 @@ type HealthCheckStatus struct {
 	// Checks keeps track of the list of health checks performed on the node and which have already passed
 	Checks []bool `json:"checks,omitempty"`
 
+	// KubernetesChecks keeps track of the list of Kubernetes health checks performed on the node and which have
+	// already passed
+	KubernetesChecks []bool `json:"kubernetesChecks,omitempty"`
+
 	// Skip denotes whether a node is part of a nodegroup before cycling has begun. If this is the case,
 	// health checks on the instance are skipped, like this only new instances are checked.
 	Skip bool `json:"skip,omitempty"`
@@ -133,6 +206,9 @@ type PreTerminationCheckStatus struct {
 
 	// Check keeps track of health check result performed on the node
 	Check bool `json:"check,omitempty"`
+
+	// Cancel marks the timestamp at which the cancel request is sent.
+	Cancel *metav1.Time `json:"cancel,omitempty"`
 }
 
 // CycleNodeRequestPhase is the phase that the cycleNodeRequest is in
@@ -160,11 +236,56 @@ const (
 	// CycleNodeRequestWaitingTermination is for cycleNodeRequests that are waiting for a current batch of nodes to terminate
 	CycleNodeRequestWaitingTermination CycleNodeRequestPhase = "WaitingTermination"
 
+	// CycleNodeRequestWaitingReplacement is for cycleNodeRequests using the ReplaceInPlace method that are waiting for
+	// the node group to replace a terminated batch of nodes
+	CycleNodeRequestWaitingReplacement CycleNodeRequestPhase = "WaitingReplacement"
+
+	// CycleNodeRequestBaking is for cycleNodeRequests with a rollout that are waiting for the bake duration to pass
+	// after a batch of nodes has finished, before selecting the next batch
+	CycleNodeRequestBaking CycleNodeRequestPhase = "Baking"
+
 	// CycleNodeRequestSuccessful is for successful cycleNodeRequests
 	CycleNodeRequestSuccessful CycleNodeRequestPhase = "Successful"
 
 	// CycleNodeRequestHealing is for the state before Failing where cyclops will try to put the cluster back in a consistent state
 	CycleNodeRequestHealing CycleNodeRequestPhase = "Healing"
+
+	// CycleNodeRequestCancelling is for cycleNodeRequests that have been cancelled and are rolling back the nodes that
+	// were being worked on
+	CycleNodeRequestCancelling CycleNodeRequestPhase = "Cancelling"
+
+	// CycleNodeRequestCancelled is for cycleNodeRequests that have been cancelled and finished rolling back
+	CycleNodeRequestCancelled CycleNodeRequestPhase = "Cancelled"
+)
+
+const (
+	// CycleNodeRequestRetryAnnotation is added to a Failed CycleNodeRequest to retry it. The nodes that are left to
+	// cycle are picked up from where it failed. The annotation is removed once the retry has been handled.
+	CycleNodeRequestRetryAnnotation = "cyclops.atlassian.com/retry"
+)
+
+const (
+	// CycleNodeRequestConditionProgressing is True while the CycleNodeRequest is cycling nodes, and False once it has
+	// finished
+	CycleNodeRequestConditionProgressing = "Progressing"
+
+	// CycleNodeRequestConditionDegraded is True when the CycleNodeRequest has failed, or is healing after a failure
+	CycleNodeRequestConditionDegraded = "Degraded"
+
+	// CycleNodeRequestConditionHealthChecksPassing is True when the health checks on the new nodes have passed, and
+	// False while waiting for them to pass
+	CycleNodeRequestConditionHealthChecksPassing = "HealthChecksPassing"
+
+	// CycleNodeRequestConditionPaused is True when the CycleNodeRequest has been paused and is not selecting new nodes
+	CycleNodeRequestConditionPaused = "Paused"
+
+	// CycleNodeRequestConditionWaitingForBudget is True when the CycleNodeRequest is waiting for the cluster-wide
+	// cycling budget before selecting new nodes
+	CycleNodeRequestConditionWaitingForBudget = "WaitingForBudget"
+
+	// CycleNodeRequestConditionPrometheusChecksPassing is True when the Prometheus checks have passed, and False while
+	// waiting for them to pass before selecting new nodes
+	CycleNodeRequestConditionPrometheusChecksPassing = "PrometheusChecksPassing"
 )
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -174,7 +295,7 @@ const (
 // +kubebuilder:resource:path=cyclenoderequests,shortName=cnr,scope=Namespaced
 // +kubebuilder:printcolumn:name="Node Group Name",type="string",JSONPath=".spec.nodeGroupName",description="The node group being cycled"
 // +kubebuilder:printcolumn:name="Method",type="string",JSONPath=".spec.cycleSettings.method",description="The method being used for the cycle operation"
-// +kubebuilder:printcolumn:name="Concurrency",type="integer",JSONPath=".spec.cycleSettings.concurrency",description="Max nodes the request is cycling at once"
+// +kubebuilder:printcolumn:name="Concurrency",type="string",JSONPath=".spec.cycleSettings.concurrency",description="Max nodes the request is cycling at once"
 // +kubebuilder:printcolumn:name="Status",type="string",JSONPath=".status.phase",description="The status of the request"
 // +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp",description="Age of the request"
 type CycleNodeRequest struct {
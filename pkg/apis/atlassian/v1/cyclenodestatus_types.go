@@ -31,6 +31,34 @@ type CycleNodeStatusStatus struct {
 
 	// TimeoutTimestamp stores the timestamp of when this CNS will timeout
 	TimeoutTimestamp *metav1.Time `json:"timeoutTimestamp,omitempty"`
+
+	// BlockedPods are the pods on the node whose eviction is blocked, such as by a PodDisruptionBudget
+	BlockedPods []BlockedPod `json:"blockedPods,omitempty"`
+
+	// Conditions are the latest observations of the state of the CycleNodeStatus
+	// +listType=map
+	// +listMapKey=type
+	// +patchStrategy=merge
+	// +patchMergeKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+}
+
+// BlockedPod is a pod whose eviction is blocked, and the PodDisruptionBudgets blocking it
+type BlockedPod struct {
+	// Name of the pod
+	Name string `json:"name"`
+
+	// Namespace of the pod
+	Namespace string `json:"namespace"`
+
+	// PodDisruptionBudgets are the names of the PodDisruptionBudgets in the namespace of the pod that select it
+	PodDisruptionBudgets []string `json:"podDisruptionBudgets,omitempty"`
+
+	// BlockedSince is when the eviction of the pod was first blocked
+	BlockedSince metav1.Time `json:"blockedSince"`
+
+	// Warned is true once a warning has been raised for the pod being blocked for too long
+	Warned bool `json:"warned,omitempty"`
 }
 
 // CycleNodeStatusPhase is the phase that the cycleNodeStatus is in
@@ -65,6 +93,17 @@ const (
 	CycleNodeStatusSuccessful CycleNodeStatusPhase = "Successful"
 )
 
+const (
+	// CycleNodeStatusConditionProgressing is True while the node is being cycled, and False once it has finished
+	CycleNodeStatusConditionProgressing = "Progressing"
+
+	// CycleNodeStatusConditionDegraded is True when cycling the node has failed
+	CycleNodeStatusConditionDegraded = "Degraded"
+
+	// CycleNodeStatusConditionWaitingForPDB is True while draining the node is blocked by a PodDisruptionBudget
+	CycleNodeStatusConditionWaitingForPDB = "WaitingForPDB"
+)
+
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
 // CycleNodeStatus is the Schema for the cyclenodestatus API
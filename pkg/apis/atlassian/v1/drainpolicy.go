@@ -0,0 +1,25 @@
+package v1
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// Matches returns true if the DrainPolicy applies to the pod. Pods in other namespaces never match.
+func (in *DrainPolicy) Matches(pod *corev1.Pod) (bool, error) {
+	if pod.Namespace != in.Namespace {
+		return false, nil
+	}
+	if in.Spec.Selector == nil {
+		return true, nil
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(in.Spec.Selector)
+	if err != nil {
+		return false, fmt.Errorf("invalid selector of drain policy %s/%s: %v", in.Namespace, in.Name, err)
+	}
+	return selector.Matches(labels.Set(pod.Labels)), nil
+}
@@ -0,0 +1,68 @@
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DrainPolicyMethod is how the pods a DrainPolicy applies to are removed from a node being drained.
+type DrainPolicyMethod string
+
+const (
+	// DrainPolicyMethodEvict evicts the pods, respecting their PodDisruptionBudgets. This is the default method.
+	DrainPolicyMethodEvict = "Evict"
+
+	// DrainPolicyMethodDelete deletes the pods without regard to their PodDisruptionBudgets.
+	DrainPolicyMethodDelete = "Delete"
+
+	// DrainPolicyMethodWaitForCompletion leaves the pods to finish on their own, such as the pods of a Job. The node
+	// is not terminated until they have completed, or the CycleNodeStatus times out.
+	DrainPolicyMethodWaitForCompletion = "WaitForCompletion"
+)
+
+// DrainPolicySpec defines how the pods it selects are drained from nodes being cycled
+// +k8s:openapi-gen=true
+type DrainPolicySpec struct {
+	// Selector selects the pods in the namespace of the DrainPolicy it applies to. Defaults to all of the pods in the
+	// namespace. When more than one DrainPolicy selects a pod, the first one in order of name is used.
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+
+	// Method is how the pods are removed from the node. Defaults to Evict.
+	// +kubebuilder:validation:Enum=Evict;Delete;WaitForCompletion
+	Method DrainPolicyMethod `json:"method,omitempty"`
+
+	// GracePeriodSeconds overrides the termination grace period of the pods when they are evicted or deleted.
+	// +kubebuilder:validation:Minimum=0
+	GracePeriodSeconds *int64 `json:"gracePeriodSeconds,omitempty"`
+
+	// ForceDeleteAfter is how long a pod whose eviction is blocked must have been unhealthy for before it is
+	// forcibly deleted. Overrides the --unhealthy-pod-termination-after flag of the controller for the pods.
+	ForceDeleteAfter *metav1.Duration `json:"forceDeleteAfter,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// DrainPolicy is the Schema for the drainpolicies API. It lets the owner of a namespace control how their pods are
+// drained from the nodes being cycled.
+// +k8s:openapi-gen=true
+// +kubebuilder:resource:path=drainpolicies,shortName=dp,scope=Namespaced
+// +kubebuilder:printcolumn:name="Method",type="string",JSONPath=".spec.method",description="How the pods are removed from the node"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp",description="Age of the policy"
+type DrainPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec DrainPolicySpec `json:"spec,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// DrainPolicyList contains a list of DrainPolicy
+type DrainPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []DrainPolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&DrainPolicy{}, &DrainPolicyList{})
+}
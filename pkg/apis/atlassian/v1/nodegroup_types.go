@@ -22,22 +22,71 @@ type NodeGroupSpec struct {
 	// Healthchecks stores the settings to configure instance custom health checks
 	HealthChecks []HealthCheck `json:"healthChecks,omitempty"`
 
+	// KubernetesHealthChecks stores the settings to configure health checks on new instances which look at
+	// objects in the API server, such as the DaemonSet pods on the node or the available replicas of a Deployment
+	KubernetesHealthChecks []KubernetesHealthCheck `json:"kubernetesHealthChecks,omitempty"`
+
 	// PreTerminationChecks stores the settings to configure instance pre-termination checks
 	PreTerminationChecks []PreTerminationCheck `json:"preTerminationChecks,omitempty"`
 
+	// PrometheusChecks are Prometheus queries that must pass before each batch of nodes is selected, and while a batch
+	// of the rollout is baking. They require the controller to be given the address of Prometheus.
+	PrometheusChecks []PrometheusCheck `json:"prometheusChecks,omitempty"`
+
 	// SkipInitialHealthChecks is an optional flag to skip the initial set of node health checks before cycling begins
 	// This does not affect the health checks performed as part of the pre-termination checks.
 	SkipInitialHealthChecks bool `json:"skipInitialHealthChecks,omitempty"`
 
 	// SkipPreTerminationChecks is an optional flag to skip pre-termination checks during cycling
 	SkipPreTerminationChecks bool `json:"skipPreTerminationChecks,omitempty"`
+
+	// MaintenanceWindows is an optional list of windows in which the nodes can be cycled. CycleNodeRequests are only
+	// created by the observer while one of the windows is open. Nodes can be cycled at any time if none are given.
+	MaintenanceWindows []MaintenanceWindow `json:"maintenanceWindows,omitempty"`
 }
 
 // NodeGroupStatus defines the observed state of NodeGroup
 // +k8s:openapi-gen=true
 type NodeGroupStatus struct {
+	// NodesCount is the number of nodes in the cluster that match the NodeGroup's node selector
+	NodesCount int `json:"nodesCount,omitempty"`
+
+	// NodesOutOfDate is the number of nodes that were found to be out of date the last time the NodeGroup was checked
+	NodesOutOfDate int `json:"nodesOutOfDate,omitempty"`
+
+	// OutOfDateReasons are the reasons the nodes were found to be out of date the last time the NodeGroup was checked
+	OutOfDateReasons []string `json:"outOfDateReasons,omitempty"`
+
+	// LastCheckTime is the last time the observer checked the NodeGroup for out of date nodes. NodeGroups with a
+	// CycleNodeRequest in progress, or outside of their maintenance windows, are not checked.
+	LastCheckTime *metav1.Time `json:"lastCheckTime,omitempty"`
+
+	// LastCycleNodeRequest is the name of the latest CycleNodeRequest created for the NodeGroup
+	LastCycleNodeRequest string `json:"lastCycleNodeRequest,omitempty"`
+
+	// LastCycleNodeRequestPhase is the phase of the latest CycleNodeRequest created for the NodeGroup
+	LastCycleNodeRequestPhase CycleNodeRequestPhase `json:"lastCycleNodeRequestPhase,omitempty"`
+
+	// LastSuccessfulCycleTime is the time the latest successful CycleNodeRequest for the NodeGroup finished
+	LastSuccessfulCycleTime *metav1.Time `json:"lastSuccessfulCycleTime,omitempty"`
+
+	// Conditions are the latest observations of the state of the NodeGroup, based on the CycleNodeRequests created
+	// for it
+	// +listType=map
+	// +listMapKey=type
+	// +patchStrategy=merge
+	// +patchMergeKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
 }
 
+const (
+	// NodeGroupConditionProgressing is True while a CycleNodeRequest is cycling the nodes of the NodeGroup
+	NodeGroupConditionProgressing = "Progressing"
+
+	// NodeGroupConditionDegraded is True when the latest CycleNodeRequest for the NodeGroup has failed
+	NodeGroupConditionDegraded = "Degraded"
+)
+
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
 // NodeGroup is the Schema for the nodegroups API
@@ -46,7 +95,11 @@ type NodeGroupStatus struct {
 // +kubebuilder:resource:path=nodegroups,shortName=ng,scope=Cluster
 // +kubebuilder:printcolumn:name="Node Group Name",type="string",JSONPath=".spec.nodeGroupName",description="The name of the node group in the cloud provider"
 // +kubebuilder:printcolumn:name="Method",type="string",JSONPath=".spec.cycleSettings.method",description="The method to use when cycling nodes"
-// +kubebuilder:printcolumn:name="Concurrency",type="integer",JSONPath=".spec.cycleSettings.concurrency",description="The number of nodes to cycle in parallel"
+// +kubebuilder:printcolumn:name="Concurrency",type="string",JSONPath=".spec.cycleSettings.concurrency",description="The number of nodes to cycle in parallel"
+// +kubebuilder:printcolumn:name="Nodes",type="integer",JSONPath=".status.nodesCount",description="The number of nodes in the node group"
+// +kubebuilder:printcolumn:name="Out Of Date",type="integer",JSONPath=".status.nodesOutOfDate",description="The number of nodes that were out of date at the last check"
+// +kubebuilder:printcolumn:name="Last Cycle",type="string",JSONPath=".status.lastCycleNodeRequestPhase",description="The phase of the latest CycleNodeRequest for the node group"
+// +kubebuilder:printcolumn:name="Last Check",type="date",JSONPath=".status.lastCheckTime",description="The last time the node group was checked for out of date nodes"
 type NodeGroup struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
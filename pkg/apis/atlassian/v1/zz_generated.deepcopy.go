@@ -7,8 +7,31 @@ package v1
 import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BlockedPod) DeepCopyInto(out *BlockedPod) {
+	*out = *in
+	if in.PodDisruptionBudgets != nil {
+		in, out := &in.PodDisruptionBudgets, &out.PodDisruptionBudgets
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.BlockedSince.DeepCopyInto(&out.BlockedSince)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BlockedPod.
+func (in *BlockedPod) DeepCopy() *BlockedPod {
+	if in == nil {
+		return nil
+	}
+	out := new(BlockedPod)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CycleNodeRequest) DeepCopyInto(out *CycleNodeRequest) {
 	*out = *in
@@ -108,6 +131,13 @@ func (in *CycleNodeRequestSpec) DeepCopyInto(out *CycleNodeRequestSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.KubernetesHealthChecks != nil {
+		in, out := &in.KubernetesHealthChecks, &out.KubernetesHealthChecks
+		*out = make([]KubernetesHealthCheck, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	if in.PreTerminationChecks != nil {
 		in, out := &in.PreTerminationChecks, &out.PreTerminationChecks
 		*out = make([]PreTerminationCheck, len(*in))
@@ -115,6 +145,20 @@ func (in *CycleNodeRequestSpec) DeepCopyInto(out *CycleNodeRequestSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.PrometheusChecks != nil {
+		in, out := &in.PrometheusChecks, &out.PrometheusChecks
+		*out = make([]PrometheusCheck, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.MaintenanceWindows != nil {
+		in, out := &in.MaintenanceWindows, &out.MaintenanceWindows
+		*out = make([]MaintenanceWindow, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
@@ -175,6 +219,44 @@ func (in *CycleNodeRequestStatus) DeepCopyInto(out *CycleNodeRequestStatus) {
 			(*out)[key] = *val.DeepCopy()
 		}
 	}
+	if in.PlannedOrder != nil {
+		in, out := &in.PlannedOrder, &out.PlannedOrder
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ZoneReplacements != nil {
+		in, out := &in.ZoneReplacements, &out.ZoneReplacements
+		*out = make(map[string]int, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.BatchStarted != nil {
+		in, out := &in.BatchStarted, &out.BatchStarted
+		*out = (*in).DeepCopy()
+	}
+	if in.BakeStarted != nil {
+		in, out := &in.BakeStarted, &out.BakeStarted
+		*out = (*in).DeepCopy()
+	}
+	if in.PrometheusChecksErroringSince != nil {
+		in, out := &in.PrometheusChecksErroringSince, &out.PrometheusChecksErroringSince
+		*out = (*in).DeepCopy()
+	}
+	if in.NodeSelectionTimes != nil {
+		in, out := &in.NodeSelectionTimes, &out.NodeSelectionTimes
+		*out = make([]metav1.Time, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
@@ -278,6 +360,20 @@ func (in *CycleNodeStatusStatus) DeepCopyInto(out *CycleNodeStatusStatus) {
 		in, out := &in.TimeoutTimestamp, &out.TimeoutTimestamp
 		*out = (*in).DeepCopy()
 	}
+	if in.BlockedPods != nil {
+		in, out := &in.BlockedPods, &out.BlockedPods
+		*out = make([]BlockedPod, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
@@ -291,9 +387,41 @@ func (in *CycleNodeStatusStatus) DeepCopy() *CycleNodeStatusStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CycleRollout) DeepCopyInto(out *CycleRollout) {
+	*out = *in
+	if in.BakeDuration != nil {
+		in, out := &in.BakeDuration, &out.BakeDuration
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.Steps != nil {
+		in, out := &in.Steps, &out.Steps
+		*out = make([]intstr.IntOrString, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CycleRollout.
+func (in *CycleRollout) DeepCopy() *CycleRollout {
+	if in == nil {
+		return nil
+	}
+	out := new(CycleRollout)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CycleSettings) DeepCopyInto(out *CycleSettings) {
 	*out = *in
+	out.Concurrency = in.Concurrency
+	if in.Rollout != nil {
+		in, out := &in.Rollout, &out.Rollout
+		*out = new(CycleRollout)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.LabelsToRemove != nil {
 		in, out := &in.LabelsToRemove, &out.LabelsToRemove
 		*out = make([]string, len(*in))
@@ -324,6 +452,11 @@ func (in *CycleSettings) DeepCopyInto(out *CycleSettings) {
 		*out = new(metav1.Duration)
 		**out = **in
 	}
+	if in.Drain != nil {
+		in, out := &in.Drain, &out.Drain
+		*out = new(DrainSettings)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -337,6 +470,161 @@ func (in *CycleSettings) DeepCopy() *CycleSettings {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DrainEscalation) DeepCopyInto(out *DrainEscalation) {
+	*out = *in
+	out.After = in.After
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DrainEscalation.
+func (in *DrainEscalation) DeepCopy() *DrainEscalation {
+	if in == nil {
+		return nil
+	}
+	out := new(DrainEscalation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DrainPolicy) DeepCopyInto(out *DrainPolicy) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DrainPolicy.
+func (in *DrainPolicy) DeepCopy() *DrainPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(DrainPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DrainPolicy) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DrainPolicyList) DeepCopyInto(out *DrainPolicyList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]DrainPolicy, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DrainPolicyList.
+func (in *DrainPolicyList) DeepCopy() *DrainPolicyList {
+	if in == nil {
+		return nil
+	}
+	out := new(DrainPolicyList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DrainPolicyList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DrainPolicySpec) DeepCopyInto(out *DrainPolicySpec) {
+	*out = *in
+	if in.Selector != nil {
+		in, out := &in.Selector, &out.Selector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.GracePeriodSeconds != nil {
+		in, out := &in.GracePeriodSeconds, &out.GracePeriodSeconds
+		*out = new(int64)
+		**out = **in
+	}
+	if in.ForceDeleteAfter != nil {
+		in, out := &in.ForceDeleteAfter, &out.ForceDeleteAfter
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DrainPolicySpec.
+func (in *DrainPolicySpec) DeepCopy() *DrainPolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DrainPolicySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DrainSettings) DeepCopyInto(out *DrainSettings) {
+	*out = *in
+	if in.BlockedWarningAfter != nil {
+		in, out := &in.BlockedWarningAfter, &out.BlockedWarningAfter
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.BlockedEscalation != nil {
+		in, out := &in.BlockedEscalation, &out.BlockedEscalation
+		*out = new(DrainEscalation)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DrainSettings.
+func (in *DrainSettings) DeepCopy() *DrainSettings {
+	if in == nil {
+		return nil
+	}
+	out := new(DrainSettings)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HTTPHeader) DeepCopyInto(out *HTTPHeader) {
+	*out = *in
+	if in.SecretKeyRef != nil {
+		in, out := &in.SecretKeyRef, &out.SecretKeyRef
+		*out = new(SecretKeyReference)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HTTPHeader.
+func (in *HTTPHeader) DeepCopy() *HTTPHeader {
+	if in == nil {
+		return nil
+	}
+	out := new(HTTPHeader)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *HealthCheck) DeepCopyInto(out *HealthCheck) {
 	*out = *in
@@ -350,6 +638,25 @@ func (in *HealthCheck) DeepCopyInto(out *HealthCheck) {
 		*out = make([]uint, len(*in))
 		copy(*out, *in)
 	}
+	if in.BodyAssertions != nil {
+		in, out := &in.BodyAssertions, &out.BodyAssertions
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.HeaderMatch != nil {
+		in, out := &in.HeaderMatch, &out.HeaderMatch
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Headers != nil {
+		in, out := &in.Headers, &out.Headers
+		*out = make([]HTTPHeader, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	out.TLSConfig = in.TLSConfig
 	return
 }
@@ -376,6 +683,11 @@ func (in *HealthCheckStatus) DeepCopyInto(out *HealthCheckStatus) {
 		*out = make([]bool, len(*in))
 		copy(*out, *in)
 	}
+	if in.KubernetesChecks != nil {
+		in, out := &in.KubernetesChecks, &out.KubernetesChecks
+		*out = make([]bool, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -389,13 +701,60 @@ func (in *HealthCheckStatus) DeepCopy() *HealthCheckStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KubernetesHealthCheck) DeepCopyInto(out *KubernetesHealthCheck) {
+	*out = *in
+	if in.WaitPeriod != nil {
+		in, out := &in.WaitPeriod, &out.WaitPeriod
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubernetesHealthCheck.
+func (in *KubernetesHealthCheck) DeepCopy() *KubernetesHealthCheck {
+	if in == nil {
+		return nil
+	}
+	out := new(KubernetesHealthCheck)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MaintenanceWindow) DeepCopyInto(out *MaintenanceWindow) {
+	*out = *in
+	if in.Duration != nil {
+		in, out := &in.Duration, &out.Duration
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.Days != nil {
+		in, out := &in.Days, &out.Days
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaintenanceWindow.
+func (in *MaintenanceWindow) DeepCopy() *MaintenanceWindow {
+	if in == nil {
+		return nil
+	}
+	out := new(MaintenanceWindow)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NodeGroup) DeepCopyInto(out *NodeGroup) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 	return
 }
 
@@ -467,6 +826,13 @@ func (in *NodeGroupSpec) DeepCopyInto(out *NodeGroupSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.KubernetesHealthChecks != nil {
+		in, out := &in.KubernetesHealthChecks, &out.KubernetesHealthChecks
+		*out = make([]KubernetesHealthCheck, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	if in.PreTerminationChecks != nil {
 		in, out := &in.PreTerminationChecks, &out.PreTerminationChecks
 		*out = make([]PreTerminationCheck, len(*in))
@@ -474,6 +840,20 @@ func (in *NodeGroupSpec) DeepCopyInto(out *NodeGroupSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.PrometheusChecks != nil {
+		in, out := &in.PrometheusChecks, &out.PrometheusChecks
+		*out = make([]PrometheusCheck, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.MaintenanceWindows != nil {
+		in, out := &in.MaintenanceWindows, &out.MaintenanceWindows
+		*out = make([]MaintenanceWindow, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
@@ -490,6 +870,26 @@ func (in *NodeGroupSpec) DeepCopy() *NodeGroupSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NodeGroupStatus) DeepCopyInto(out *NodeGroupStatus) {
 	*out = *in
+	if in.OutOfDateReasons != nil {
+		in, out := &in.OutOfDateReasons, &out.OutOfDateReasons
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.LastCheckTime != nil {
+		in, out := &in.LastCheckTime, &out.LastCheckTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastSuccessfulCycleTime != nil {
+		in, out := &in.LastSuccessfulCycleTime, &out.LastSuccessfulCycleTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
@@ -503,6 +903,34 @@ func (in *NodeGroupStatus) DeepCopy() *NodeGroupStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PreTerminationCancel) DeepCopyInto(out *PreTerminationCancel) {
+	*out = *in
+	if in.Headers != nil {
+		in, out := &in.Headers, &out.Headers
+		*out = make([]HTTPHeader, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ValidStatusCodes != nil {
+		in, out := &in.ValidStatusCodes, &out.ValidStatusCodes
+		*out = make([]uint, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PreTerminationCancel.
+func (in *PreTerminationCancel) DeepCopy() *PreTerminationCancel {
+	if in == nil {
+		return nil
+	}
+	out := new(PreTerminationCancel)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PreTerminationCheck) DeepCopyInto(out *PreTerminationCheck) {
 	*out = *in
@@ -511,6 +939,18 @@ func (in *PreTerminationCheck) DeepCopyInto(out *PreTerminationCheck) {
 		*out = make([]uint, len(*in))
 		copy(*out, *in)
 	}
+	if in.Headers != nil {
+		in, out := &in.Headers, &out.Headers
+		*out = make([]HTTPHeader, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Cancel != nil {
+		in, out := &in.Cancel, &out.Cancel
+		*out = new(PreTerminationCancel)
+		(*in).DeepCopyInto(*out)
+	}
 	in.HealthCheck.DeepCopyInto(&out.HealthCheck)
 	out.TLSConfig = in.TLSConfig
 	return
@@ -533,6 +973,10 @@ func (in *PreTerminationCheckStatus) DeepCopyInto(out *PreTerminationCheckStatus
 		in, out := &in.Trigger, &out.Trigger
 		*out = (*in).DeepCopy()
 	}
+	if in.Cancel != nil {
+		in, out := &in.Cancel, &out.Cancel
+		*out = (*in).DeepCopy()
+	}
 	return
 }
 
@@ -569,6 +1013,43 @@ func (in *PreTerminationCheckStatusList) DeepCopy() *PreTerminationCheckStatusLi
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PrometheusCheck) DeepCopyInto(out *PrometheusCheck) {
+	*out = *in
+	if in.Window != nil {
+		in, out := &in.Window, &out.Window
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PrometheusCheck.
+func (in *PrometheusCheck) DeepCopy() *PrometheusCheck {
+	if in == nil {
+		return nil
+	}
+	out := new(PrometheusCheck)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretKeyReference) DeepCopyInto(out *SecretKeyReference) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretKeyReference.
+func (in *SecretKeyReference) DeepCopy() *SecretKeyReference {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretKeyReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TLSConfig) DeepCopyInto(out *TLSConfig) {
 	*out = *in
@@ -0,0 +1,257 @@
+// Package checks evaluates the checks of CycleNodeRequests, and validates the parts of them that the API types don't
+// check on their own.
+package checks
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"k8s.io/client-go/util/jsonpath"
+
+	v1 "github.com/atlassian-labs/cyclops/pkg/apis/atlassian/v1"
+)
+
+// comparisonOperators are the comparisons a HealthCheck body assertion and a PrometheusCheck Threshold can use. The
+// two character operators come first so that "<=" isn't read as "<".
+var comparisonOperators = []string{"<=", ">=", "==", "!=", "<", ">"}
+
+// ValidateHealthCheck returns an error if the HealthCheck is not valid, including the syntax of its body assertions.
+func ValidateHealthCheck(healthCheck *v1.HealthCheck) error {
+	if err := healthCheck.Validate(); err != nil {
+		return err
+	}
+
+	for _, assertion := range healthCheck.BodyAssertions {
+		if _, err := parseBodyAssertion(assertion); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MatchHeaders returns an error describing the first header in the HeaderMatch of the health check that is missing
+// from the http response, or which has no value matching its regex.
+func MatchHeaders(healthCheck *v1.HealthCheck, header http.Header) error {
+	names := make([]string, 0, len(healthCheck.HeaderMatch))
+	for name := range healthCheck.HeaderMatch {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		r, err := regexp.Compile(healthCheck.HeaderMatch[name])
+		if err != nil {
+			return fmt.Errorf("invalid regex for header %s: %v", name, err)
+		}
+
+		values := header.Values(name)
+		if len(values) == 0 {
+			return fmt.Errorf("header %s is missing", name)
+		}
+
+		var matched bool
+		for _, value := range values {
+			if r.MatchString(value) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return fmt.Errorf("header %s %q did not match regex %s", name, strings.Join(values, ", "), healthCheck.HeaderMatch[name])
+		}
+	}
+
+	return nil
+}
+
+// AssertBody returns an error describing the first of the BodyAssertions of the health check that the JSON body of the
+// http response does not satisfy.
+func AssertBody(healthCheck *v1.HealthCheck, body []byte) error {
+	if len(healthCheck.BodyAssertions) == 0 {
+		return nil
+	}
+
+	var data interface{}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return fmt.Errorf("body is not valid json: %v", err)
+	}
+
+	for _, assertion := range healthCheck.BodyAssertions {
+		parsed, err := parseBodyAssertion(assertion)
+		if err != nil {
+			return err
+		}
+		if err := parsed.evaluate(data); err != nil {
+			return fmt.Errorf("assertion `%s` failed: %v", assertion, err)
+		}
+	}
+
+	return nil
+}
+
+// bodyAssertion is a parsed HealthCheck body assertion
+type bodyAssertion struct {
+	path     *jsonpath.JSONPath
+	length   bool
+	operator string
+	value    interface{}
+}
+
+// parseBodyAssertion parses an assertion made of a JSONPath, an optional "| length", an operator and a JSON value
+func parseBodyAssertion(assertion string) (*bodyAssertion, error) {
+	index, operator := indexOperator(assertion)
+	if index < 0 {
+		return nil, fmt.Errorf("invalid body assertion %q: must contain one of %v", assertion, comparisonOperators)
+	}
+
+	parsed := &bodyAssertion{operator: operator}
+
+	path := strings.TrimSpace(assertion[:index])
+	if pipe := strings.LastIndex(path, "|"); pipe >= 0 {
+		if strings.TrimSpace(path[pipe+1:]) != "length" {
+			return nil, fmt.Errorf("invalid body assertion %q: only \"| length\" can follow the path", assertion)
+		}
+		parsed.length = true
+		path = strings.TrimSpace(path[:pipe])
+	}
+	path = strings.TrimPrefix(path, "$")
+	if !strings.HasPrefix(path, ".") && !strings.HasPrefix(path, "[") {
+		return nil, fmt.Errorf("invalid body assertion %q: path must start with \".\"", assertion)
+	}
+
+	parsed.path = jsonpath.New("assertion")
+	if err := parsed.path.Parse("{" + path + "}"); err != nil {
+		return nil, fmt.Errorf("invalid body assertion %q: %v", assertion, err)
+	}
+
+	if err := json.Unmarshal([]byte(strings.TrimSpace(assertion[index+len(operator):])), &parsed.value); err != nil {
+		return nil, fmt.Errorf("invalid body assertion %q: value must be json: %v", assertion, err)
+	}
+
+	if _, isNumber := parsed.value.(float64); !isNumber && operator != "==" && operator != "!=" {
+		return nil, fmt.Errorf("invalid body assertion %q: %s can only compare numbers", assertion, operator)
+	}
+
+	return parsed, nil
+}
+
+// indexOperator returns the index of the first comparison operator in the assertion which is not inside brackets or a
+// quoted string, so the operators in JSONPath filters and values are ignored.
+func indexOperator(assertion string) (int, string) {
+	var depth int
+	var quote rune
+
+	for i, c := range assertion {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+			continue
+		case c == '"' || c == '\'':
+			quote = c
+			continue
+		case c == '[' || c == '(':
+			depth++
+			continue
+		case c == ']' || c == ')':
+			depth--
+			continue
+		case depth > 0:
+			continue
+		}
+
+		for _, operator := range comparisonOperators {
+			if strings.HasPrefix(assertion[i:], operator) {
+				return i, operator
+			}
+		}
+	}
+
+	return -1, ""
+}
+
+// evaluate returns an error if the values at the path of the assertion don't satisfy it
+func (a *bodyAssertion) evaluate(data interface{}) error {
+	results, err := a.path.FindResults(data)
+	if err != nil {
+		return err
+	}
+
+	var values []interface{}
+	for _, result := range results {
+		for _, value := range result {
+			values = append(values, value.Interface())
+		}
+	}
+
+	if a.length {
+		length := len(values)
+		if len(values) == 1 {
+			switch typed := values[0].(type) {
+			case nil:
+				length = 0
+			case []interface{}:
+				length = len(typed)
+			case map[string]interface{}:
+				length = len(typed)
+			case string:
+				length = len(typed)
+			}
+		}
+		values = []interface{}{float64(length)}
+	}
+
+	if len(values) == 0 {
+		return fmt.Errorf("path matched no values")
+	}
+
+	for _, value := range values {
+		if !a.compare(value) {
+			got, _ := json.Marshal(value)
+			return fmt.Errorf("got %s", got)
+		}
+	}
+
+	return nil
+}
+
+// compare returns true if the value satisfies the operator and value of the assertion
+func (a *bodyAssertion) compare(value interface{}) bool {
+	if limit, ok := a.value.(float64); ok {
+		number, ok := value.(float64)
+		if !ok {
+			return a.operator == "!="
+		}
+		return compareNumbers(a.operator, number, limit)
+	}
+
+	equal := reflect.DeepEqual(value, a.value)
+	if a.operator == "!=" {
+		return !equal
+	}
+	return equal
+}
+
+// compareNumbers returns the result of comparing the value to the limit with one of the comparisonOperators
+func compareNumbers(operator string, value, limit float64) bool {
+	switch operator {
+	case "<=":
+		return value <= limit
+	case ">=":
+		return value >= limit
+	case "==":
+		return value == limit
+	case "!=":
+		return value != limit
+	case "<":
+		return value < limit
+	default:
+		return value > limit
+	}
+}
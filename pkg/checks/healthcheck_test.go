@@ -0,0 +1,125 @@
+package checks
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	v1 "github.com/atlassian-labs/cyclops/pkg/apis/atlassian/v1"
+)
+
+func TestValidateHealthCheck(t *testing.T) {
+	tests := []struct {
+		name        string
+		healthCheck v1.HealthCheck
+		expectError bool
+	}{
+		{"body assertions", v1.HealthCheck{Endpoint: "http://{{ .NodeIP }}/health", BodyAssertions: []string{`.status == "ok"`}}, false},
+		{"invalid body assertion", v1.HealthCheck{Endpoint: "http://{{ .NodeIP }}/health", BodyAssertions: []string{`.status`}}, true},
+		{"invalid protocol", v1.HealthCheck{Protocol: "udp", Endpoint: "{{ .NodeIP }}:9000"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expectError, ValidateHealthCheck(&tt.healthCheck) != nil)
+		})
+	}
+}
+
+func TestAssertBody(t *testing.T) {
+	body := []byte(`{"status": "ok", "ready": true, "version": null, "peers": [{"name": "a", "up": true}, {"name": "b", "up": true}, {"name": "c", "up": false}], "load": 0.5}`)
+
+	tests := []struct {
+		assertion   string
+		expectError string
+	}{
+		{`.status == "ok"`, ""},
+		{`$.status == "ok"`, ""},
+		{`.status != "degraded"`, ""},
+		{`.status == "degraded"`, "got \"ok\""},
+		{`.ready == true`, ""},
+		{`.version == null`, ""},
+		{`.peers | length >= 3`, ""},
+		{`.peers | length > 3`, "got 3"},
+		{`.peers[*].name | length == 3`, ""},
+		{`.peers[?(@.up==true)].name | length >= 2`, ""},
+		{`.version | length == 0`, ""},
+		{`.version | length > 0`, "got 0"},
+		{`.peers[*].up == true`, "got false"},
+		{`.load < 0.8`, ""},
+		{`.load<0.1`, "got 0.5"},
+		{`.status < 1`, "got \"ok\""},
+		{`.missing == "ok"`, "missing is not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.assertion, func(t *testing.T) {
+			healthCheck := v1.HealthCheck{BodyAssertions: []string{tt.assertion}}
+			assert.NoError(t, ValidateHealthCheck(&healthCheck))
+
+			err := AssertBody(&healthCheck, body)
+			if tt.expectError == "" {
+				assert.NoError(t, err)
+			} else if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), tt.assertion)
+				assert.Contains(t, err.Error(), tt.expectError)
+			}
+		})
+	}
+
+	t.Run("body is not json", func(t *testing.T) {
+		healthCheck := v1.HealthCheck{BodyAssertions: []string{`.status == "ok"`}}
+		assert.Error(t, AssertBody(&healthCheck, []byte("ok")))
+	})
+}
+
+func TestParseBodyAssertion(t *testing.T) {
+	tests := []struct {
+		assertion   string
+		expectError bool
+	}{
+		{`.status == "ok"`, false},
+		{`.peers | length >= 3`, false},
+		{`.peers[?(@.name=="a")].up == true`, false},
+		{`.status`, true},
+		{`status == "ok"`, true},
+		{`.status == ok`, true},
+		{`.status > "ok"`, true},
+		{`.peers | count >= 3`, true},
+		{`.peers[ == 1`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.assertion, func(t *testing.T) {
+			_, err := parseBodyAssertion(tt.assertion)
+			assert.Equal(t, tt.expectError, err != nil, "error: %v", err)
+		})
+	}
+}
+
+func TestMatchHeaders(t *testing.T) {
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	header.Add("X-Status", "draining")
+	header.Add("X-Status", "ready")
+
+	tests := []struct {
+		name        string
+		headerMatch map[string]string
+		expectError bool
+	}{
+		{"no headers", nil, false},
+		{"matches", map[string]string{"content-type": "^application/json"}, false},
+		{"one of the values matches", map[string]string{"X-Status": "^ready$"}, false},
+		{"does not match", map[string]string{"Content-Type": "^text/"}, true},
+		{"missing", map[string]string{"X-Version": ".*"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			healthCheck := v1.HealthCheck{HeaderMatch: tt.headerMatch}
+			assert.Equal(t, tt.expectError, MatchHeaders(&healthCheck, header) != nil)
+		})
+	}
+}
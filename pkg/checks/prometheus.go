@@ -0,0 +1,37 @@
+package checks
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	v1 "github.com/atlassian-labs/cyclops/pkg/apis/atlassian/v1"
+)
+
+// ValidatePrometheusCheck returns an error if the PrometheusCheck is not valid, including the syntax of its Threshold.
+func ValidatePrometheusCheck(check *v1.PrometheusCheck) error {
+	if err := check.Validate(); err != nil {
+		return err
+	}
+	_, err := ThresholdPassed(check, 0)
+	return err
+}
+
+// ThresholdPassed returns true if the value satisfies the Threshold of the PrometheusCheck.
+func ThresholdPassed(check *v1.PrometheusCheck, value float64) (bool, error) {
+	threshold := strings.TrimSpace(check.Threshold)
+	for _, operator := range comparisonOperators {
+		if !strings.HasPrefix(threshold, operator) {
+			continue
+		}
+
+		limit, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimPrefix(threshold, operator)), 64)
+		if err != nil {
+			return false, fmt.Errorf("invalid threshold %q: %v", check.Threshold, err)
+		}
+
+		return compareNumbers(operator, value, limit), nil
+	}
+
+	return false, fmt.Errorf("invalid threshold %q: must start with one of %v", check.Threshold, comparisonOperators)
+}
@@ -0,0 +1,44 @@
+package checks
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	v1 "github.com/atlassian-labs/cyclops/pkg/apis/atlassian/v1"
+)
+
+func TestValidatePrometheusCheck(t *testing.T) {
+	assert.NoError(t, ValidatePrometheusCheck(&v1.PrometheusCheck{Query: "up", Threshold: "> 0"}))
+	assert.Error(t, ValidatePrometheusCheck(&v1.PrometheusCheck{Query: "up", Threshold: "0"}))
+	assert.Error(t, ValidatePrometheusCheck(&v1.PrometheusCheck{Threshold: "> 0"}))
+}
+
+func TestThresholdPassed(t *testing.T) {
+	tests := []struct {
+		threshold   string
+		value       float64
+		expect      bool
+		expectError bool
+	}{
+		{"< 0.01", 0.001, true, false},
+		{"< 0.01", 0.01, false, false},
+		{"<= 0.01", 0.01, true, false},
+		{"> 5", 6, true, false},
+		{">=5", 5, true, false},
+		{"== 0", 0, true, false},
+		{"== 0", 1, false, false},
+		{"!= 0", 1, true, false},
+		{"0.01", 0, false, true},
+		{"< one", 0, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.threshold, func(t *testing.T) {
+			check := v1.PrometheusCheck{Threshold: tt.threshold}
+			passed, err := ThresholdPassed(&check, tt.value)
+			assert.Equal(t, tt.expectError, err != nil)
+			assert.Equal(t, tt.expect, passed)
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package checks
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+
+	v1 "github.com/atlassian-labs/cyclops/pkg/apis/atlassian/v1"
+)
+
+// ValidatePreTerminationCheck returns an error if the PreTerminationCheck is not valid, including its HealthCheck and
+// the body templates of its trigger and cancel requests. The bodies are rendered with placeholder values to check
+// that they produce valid JSON.
+func ValidatePreTerminationCheck(preTerminationCheck *v1.PreTerminationCheck) error {
+	if err := preTerminationCheck.Validate(); err != nil {
+		return err
+	}
+
+	data := PreTerminationRequestData{
+		NodeName:         "node",
+		NodeIP:           "10.0.0.1",
+		ProviderID:       "aws:///us-east-1a/i-0123456789abcdef0",
+		NodeGroup:        "node-group",
+		CycleNodeRequest: "cycle-node-request",
+	}
+	if _, err := RenderRequestBody(preTerminationCheck.Body, data); err != nil {
+		return fmt.Errorf("trigger: %v", err)
+	}
+	if preTerminationCheck.Cancel != nil {
+		if _, err := RenderRequestBody(preTerminationCheck.Cancel.Body, data); err != nil {
+			return fmt.Errorf("cancel: %v", err)
+		}
+	}
+
+	return ValidateHealthCheck(&preTerminationCheck.HealthCheck)
+}
+
+// PreTerminationRequestData holds the fields available to the body templates of pre-termination requests
+type PreTerminationRequestData struct {
+	NodeName         string
+	NodeIP           string
+	ProviderID       string
+	NodeGroup        string
+	CycleNodeRequest string
+}
+
+// requestBodyFuncs are the functions available to the body templates of pre-termination requests
+var requestBodyFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// RenderRequestBody renders the body template of a pre-termination request. It returns nil if there is no body, and
+// an error if the template doesn't render valid JSON.
+func RenderRequestBody(body string, data PreTerminationRequestData) ([]byte, error) {
+	if body == "" {
+		return nil, nil
+	}
+
+	tmpl, err := template.New("body").Funcs(requestBodyFuncs).Parse(body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid body template: %v", err)
+	}
+
+	var rendered strings.Builder
+	if err := tmpl.Execute(&rendered, data); err != nil {
+		return nil, fmt.Errorf("failed to render body: %v", err)
+	}
+
+	if !json.Valid([]byte(rendered.String())) {
+		return nil, fmt.Errorf("rendered body is not valid JSON: %s", rendered.String())
+	}
+	return []byte(rendered.String()), nil
+}
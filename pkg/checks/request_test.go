@@ -0,0 +1,60 @@
+package checks
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	v1 "github.com/atlassian-labs/cyclops/pkg/apis/atlassian/v1"
+)
+
+func TestValidatePreTerminationCheck(t *testing.T) {
+	valid := v1.PreTerminationCheck{
+		Endpoint: "http://{{ .NodeIP }}/trigger",
+		Method:   "PUT",
+		Body:     `{"node": {{ json .NodeName }}, "group": "{{ .NodeGroup }}"}`,
+		Cancel:   &v1.PreTerminationCancel{Endpoint: "http://{{ .NodeIP }}/cancel", Method: "DELETE", Body: `{"node": {{ json .NodeName }}}`},
+	}
+	assert.NoError(t, ValidatePreTerminationCheck(&valid))
+
+	invalidMethod := valid
+	invalidMethod.Method = "TRACE"
+	assert.Error(t, ValidatePreTerminationCheck(&invalidMethod))
+
+	invalidBody := valid
+	invalidBody.Body = `{"node": {{ .NodeName }}}`
+	assert.Error(t, ValidatePreTerminationCheck(&invalidBody))
+
+	unknownField := valid
+	unknownField.Body = `{"node": {{ json .Node }}}`
+	assert.Error(t, ValidatePreTerminationCheck(&unknownField))
+
+	invalidCancelBody := valid
+	invalidCancelBody.Cancel = &v1.PreTerminationCancel{Endpoint: "http://{{ .NodeIP }}/cancel", Body: `{`}
+	assert.Error(t, ValidatePreTerminationCheck(&invalidCancelBody))
+
+	invalidBodyAssertion := valid
+	invalidBodyAssertion.HealthCheck = v1.HealthCheck{BodyAssertions: []string{`.status`}}
+	assert.Error(t, ValidatePreTerminationCheck(&invalidBodyAssertion))
+}
+
+func TestRenderRequestBody(t *testing.T) {
+	data := PreTerminationRequestData{
+		NodeName:         "node-1",
+		NodeIP:           "10.0.0.1",
+		ProviderID:       "aws:///us-east-1a/i-1",
+		NodeGroup:        "group",
+		CycleNodeRequest: "cnr",
+	}
+
+	body, err := RenderRequestBody("", data)
+	assert.NoError(t, err)
+	assert.Nil(t, body)
+
+	body, err = RenderRequestBody(`{"node": {{ json .NodeName }}, "ip": "{{ .NodeIP }}", "id": {{ json .ProviderID }}, "group": {{ json .NodeGroup }}, "cnr": {{ json .CycleNodeRequest }}}`, data)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"node": "node-1", "ip": "10.0.0.1", "id": "aws:///us-east-1a/i-1", "group": "group", "cnr": "cnr"}`, string(body))
+
+	_, err = RenderRequestBody(`{"node": {{ .NodeName }}}`, data)
+	assert.Error(t, err)
+}
@@ -0,0 +1,157 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	atlassianv1 "github.com/atlassian-labs/cyclops/pkg/apis/atlassian/v1"
+	"github.com/atlassian-labs/cyclops/pkg/cli/kubeplug"
+)
+
+// SubCommands returns the sub commands used to control CNRs that have already been applied
+func (c *cycle) SubCommands() []kubeplug.SubCommand {
+	return []kubeplug.SubCommand{
+		{
+			Use:     "pause <cnr names>",
+			Short:   "Pause CNRs. Nodes already being cycled will finish, but no new nodes are selected",
+			Example: "kubectl cycle pause example-123-system",
+			Args:    cobra.MinimumNArgs(1),
+			Plugger: kubeplug.PluggerFunc(func(plug *kubeplug.Plug) {
+				c.updateCNRs(plug, "[pausing]", setPaused(true))
+			}),
+		},
+		{
+			Use:     "resume <cnr names>",
+			Short:   "Resume paused CNRs",
+			Example: "kubectl cycle resume example-123-system",
+			Args:    cobra.MinimumNArgs(1),
+			Plugger: kubeplug.PluggerFunc(func(plug *kubeplug.Plug) {
+				c.updateCNRs(plug, "[resuming]", setPaused(false))
+			}),
+		},
+		{
+			Use:     "cancel <cnr names>",
+			Short:   "Cancel CNRs. Nodes that have not started draining are re-attached to their node group and uncordoned",
+			Example: "kubectl cycle cancel example-123-system",
+			Args:    cobra.MinimumNArgs(1),
+			Plugger: kubeplug.PluggerFunc(func(plug *kubeplug.Plug) {
+				c.updateCNRs(plug, "[cancelling]", setCancel)
+			}),
+		},
+		{
+			Use:     "retry <cnr names>",
+			Short:   "Retry Failed CNRs, picking up the nodes that are left to cycle from where they failed",
+			Example: "kubectl cycle retry example-123-system",
+			Args:    cobra.MinimumNArgs(1),
+			Plugger: kubeplug.PluggerFunc(func(plug *kubeplug.Plug) {
+				c.updateCNRs(plug, "[retrying]", setRetry)
+			}),
+		},
+	}
+}
+
+// setPaused returns an update func to pause or resume a CNR
+func setPaused(paused bool) func(*atlassianv1.CycleNodeRequest) error {
+	return func(cnr *atlassianv1.CycleNodeRequest) error {
+		if err := checkInProgress(cnr); err != nil {
+			return err
+		}
+		if cnr.Spec.Paused == paused {
+			return fmt.Errorf("cnr already has paused=%t", paused)
+		}
+		cnr.Spec.Paused = paused
+		return nil
+	}
+}
+
+// setCancel is an update func to cancel a CNR
+func setCancel(cnr *atlassianv1.CycleNodeRequest) error {
+	if err := checkInProgress(cnr); err != nil {
+		return err
+	}
+	if cnr.Spec.Cancel {
+		return fmt.Errorf("cnr has already been cancelled")
+	}
+	cnr.Spec.Cancel = true
+	return nil
+}
+
+// setRetry is an update func to retry a Failed CNR
+func setRetry(cnr *atlassianv1.CycleNodeRequest) error {
+	if cnr.Status.Phase != atlassianv1.CycleNodeRequestFailed {
+		return fmt.Errorf("only Failed cnrs can be retried, cnr has phase %s", cnr.Status.Phase)
+	}
+	if _, ok := cnr.Annotations[atlassianv1.CycleNodeRequestRetryAnnotation]; ok {
+		return fmt.Errorf("cnr is already being retried")
+	}
+	if cnr.Annotations == nil {
+		cnr.Annotations = map[string]string{}
+	}
+	cnr.Annotations[atlassianv1.CycleNodeRequestRetryAnnotation] = "true"
+	return nil
+}
+
+// checkInProgress returns an error if the CNR has finished or is already rolling back
+func checkInProgress(cnr *atlassianv1.CycleNodeRequest) error {
+	switch cnr.Status.Phase {
+	case atlassianv1.CycleNodeRequestSuccessful, atlassianv1.CycleNodeRequestFailed, atlassianv1.CycleNodeRequestCancelled:
+		return fmt.Errorf("cnr has already finished with phase %s", cnr.Status.Phase)
+	case atlassianv1.CycleNodeRequestHealing, atlassianv1.CycleNodeRequestCancelling:
+		return fmt.Errorf("cnr is already rolling back with phase %s", cnr.Status.Phase)
+	}
+	return nil
+}
+
+// updateCNRs runs the update func on each of the CNRs named in the arguments and patches them
+func (c *cycle) updateCNRs(plug *kubeplug.Plug, action string, update func(*atlassianv1.CycleNodeRequest) error) {
+	c.plug = plug
+
+	if c.dryMode() {
+		action = "[dry mode]"
+	}
+
+	var successCount int
+	for _, name := range c.plug.Args {
+		c.plug.Message(fmt.Sprint(c.plug.CLI.Cyan(action), " "))
+		c.plug.Message(c.plug.CLI.Yellow(name))
+
+		if err := c.patchCNR(name, update); err != nil {
+			c.plug.MessageLn("")
+			c.plug.MessageRed("[ failed ] ")
+			c.plug.MessageLn(fmt.Sprint("to update ", c.plug.CLI.Yellow(name), " because ", err))
+			continue
+		}
+
+		c.plug.MessageGreenLn(" OK")
+		successCount++
+	}
+
+	c.plug.DecorateLn(separator)
+	c.plug.MessageGreenLn(fmt.Sprintf("DONE! Updated %d CNRs successfully", successCount))
+
+	if successCount != len(c.plug.Args) {
+		c.plug.MessageFail(fmt.Sprintf("%d CNRs failed", len(c.plug.Args)-successCount))
+	}
+}
+
+// patchCNR gets the CNR and patches it with the changes made by the update func
+func (c *cycle) patchCNR(name string, update func(*atlassianv1.CycleNodeRequest) error) error {
+	var cnr atlassianv1.CycleNodeRequest
+	if err := c.plug.Client.Get(context.TODO(), client.ObjectKey{Namespace: c.cyclopsNamespace(), Name: name}, &cnr); err != nil {
+		return err
+	}
+
+	patch := client.MergeFrom(cnr.DeepCopy())
+	if err := update(&cnr); err != nil {
+		return err
+	}
+
+	var options []client.PatchOption
+	if c.dryMode() {
+		options = append(options, client.DryRunAll)
+	}
+	return c.plug.Client.Patch(context.TODO(), &cnr, patch, options...)
+}
@@ -0,0 +1,116 @@
+package cli
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/atlassian-labs/cyclops/pkg/apis"
+	atlassianv1 "github.com/atlassian-labs/cyclops/pkg/apis/atlassian/v1"
+	"github.com/atlassian-labs/cyclops/pkg/cli/kubeplug"
+)
+
+func newTestCNR(name string, phase atlassianv1.CycleNodeRequestPhase, paused bool) *atlassianv1.CycleNodeRequest {
+	return &atlassianv1.CycleNodeRequest{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "kube-system"},
+		Spec:       atlassianv1.CycleNodeRequestSpec{Paused: paused},
+		Status:     atlassianv1.CycleNodeRequestStatus{Phase: phase},
+	}
+}
+
+func newTestCycle(t *testing.T, objects ...runtime.Object) *cycle {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, apis.AddToScheme(scheme))
+
+	dryMode := false
+	return &cycle{
+		plug: &kubeplug.Plug{
+			Client: fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(objects...).Build(),
+		},
+		dryModeFlag: &dryMode,
+	}
+}
+
+func TestSetPaused(t *testing.T) {
+	tests := []struct {
+		name    string
+		cnr     *atlassianv1.CycleNodeRequest
+		paused  bool
+		wantErr bool
+	}{
+		{"pause in progress", newTestCNR("a", atlassianv1.CycleNodeRequestScalingUp, false), true, false},
+		{"resume paused", newTestCNR("a", atlassianv1.CycleNodeRequestInitialised, true), false, false},
+		{"pause already paused", newTestCNR("a", atlassianv1.CycleNodeRequestInitialised, true), true, true},
+		{"pause successful", newTestCNR("a", atlassianv1.CycleNodeRequestSuccessful, false), true, true},
+		{"resume failed", newTestCNR("a", atlassianv1.CycleNodeRequestFailed, true), false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := setPaused(tt.paused)(tt.cnr)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.paused, tt.cnr.Spec.Paused)
+		})
+	}
+}
+
+func TestSetCancel(t *testing.T) {
+	tests := []struct {
+		name    string
+		cnr     *atlassianv1.CycleNodeRequest
+		wantErr bool
+	}{
+		{"cancel in progress", newTestCNR("a", atlassianv1.CycleNodeRequestWaitingTermination, false), false},
+		{"cancel paused", newTestCNR("a", atlassianv1.CycleNodeRequestInitialised, true), false},
+		{"cancel healing", newTestCNR("a", atlassianv1.CycleNodeRequestHealing, false), true},
+		{"cancel cancelled", newTestCNR("a", atlassianv1.CycleNodeRequestCancelled, false), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := setCancel(tt.cnr)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.True(t, tt.cnr.Spec.Cancel)
+			assert.Error(t, setCancel(tt.cnr))
+		})
+	}
+}
+
+func TestSetRetry(t *testing.T) {
+	failed := newTestCNR("a", atlassianv1.CycleNodeRequestFailed, false)
+	assert.NoError(t, setRetry(failed))
+	assert.Contains(t, failed.Annotations, atlassianv1.CycleNodeRequestRetryAnnotation)
+	assert.Error(t, setRetry(failed))
+
+	assert.Error(t, setRetry(newTestCNR("a", atlassianv1.CycleNodeRequestScalingUp, false)))
+}
+
+func TestPatchCNR(t *testing.T) {
+	c := newTestCycle(t, newTestCNR("example", atlassianv1.CycleNodeRequestScalingUp, false))
+
+	assert.NoError(t, c.patchCNR("example", setPaused(true)))
+
+	var cnr atlassianv1.CycleNodeRequest
+	assert.NoError(t, c.plug.Client.Get(context.TODO(), client.ObjectKey{Namespace: "kube-system", Name: "example"}, &cnr))
+	assert.True(t, cnr.Spec.Paused)
+
+	var resumed atlassianv1.CycleNodeRequest
+	assert.NoError(t, c.patchCNR("example", setPaused(false)))
+	assert.NoError(t, c.plug.Client.Get(context.TODO(), client.ObjectKey{Namespace: "kube-system", Name: "example"}, &resumed))
+	assert.False(t, resumed.Spec.Paused)
+
+	assert.Error(t, c.patchCNR("missing", setPaused(true)))
+}
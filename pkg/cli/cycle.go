@@ -10,6 +10,7 @@ import (
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/client-go/kubernetes/scheme"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
@@ -86,6 +87,16 @@ kubectl cycle --name example-123 --all -o yaml
 
 # cycle system node group without the initial health checks
 kubectl cycle --name example-123 system --skip-initial-health-checks
+
+# pause a CNR, letting the nodes in progress finish, and resume it later
+kubectl cycle pause example-123-system
+kubectl cycle resume example-123-system
+
+# cancel a CNR, re-attaching and uncordoning the nodes that have not started draining
+kubectl cycle cancel example-123-system
+
+# retry a Failed CNR from where it failed
+kubectl cycle retry example-123-system
 `
 }
 
@@ -195,7 +206,7 @@ func (c *cycle) generateCNRs(nodeGroups *atlassianv1.NodeGroupList, name, namesp
 		}
 
 		if newConcurrencyValue, set := c.concurrencyOverride(); set {
-			cnr.Spec.CycleSettings.Concurrency = newConcurrencyValue
+			cnr.Spec.CycleSettings.Concurrency = intstr.FromInt(int(newConcurrencyValue))
 		}
 
 		if newCyclingTimeoutValue, set := c.cyclingTimeoutOverride(); set {
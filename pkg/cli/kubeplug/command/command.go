@@ -25,16 +25,21 @@ type Describer interface {
 }
 
 // RunOrDie runs the cobra command or panics
-func RunOrDie(usage, version, example string, run func(*cobra.Command, []string), ff []FlagFlagger, cf []CmdFlagger) {
+func RunOrDie(usage, version, example string, run func(*cobra.Command, []string), ff []FlagFlagger, cf []CmdFlagger, subCommands ...*cobra.Command) {
 	cmd := &cobra.Command{
 		Use:     usage,
 		Version: version,
 		Example: example,
 
+		// Allow positional arguments alongside sub commands
+		Args: cobra.ArbitraryArgs,
+
 		Run: func(cmd *cobra.Command, args []string) {
 			run(cmd, args)
 		},
 	}
+	cmd.CompletionOptions.DisableDefaultCmd = true
+	cmd.AddCommand(subCommands...)
 
 	flags := cmd.PersistentFlags()
 
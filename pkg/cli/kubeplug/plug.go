@@ -51,6 +51,29 @@ type Plugger interface {
 	Run(plug *Plug)
 }
 
+// PluggerFunc is an adapter to allow the use of ordinary functions as a Plugger
+type PluggerFunc func(plug *Plug)
+
+// Run calls f(plug)
+func (f PluggerFunc) Run(plug *Plug) {
+	f(plug)
+}
+
+// SubCommand describes a sub command of an Application. It is run with the same setup Plug and flags as the
+// Application
+type SubCommand struct {
+	Use     string
+	Short   string
+	Example string
+	Args    cobra.PositionalArgs
+	Plugger
+}
+
+// SubCommander defines an interface for an Application that has sub commands
+type SubCommander interface {
+	SubCommands() []SubCommand
+}
+
 // Application collects all the interface components needed to start an application as a kubectl plugin
 type Application interface {
 	Plugger
@@ -78,7 +101,7 @@ func Do(description command.Describer, plugger Plugger, moreFlags ...command.Cmd
 		WithScheme(scheme.Scheme).
 		WithLabelSelector(labels.Everything().String())
 
-	runCmd := func(cmd *cobra.Command, args []string) {
+	setup := func(cmd *cobra.Command, args []string) {
 		plug.Client = k8s.NewCLIClientOrDie(plug.ConfigFlags)
 		plug.Namespace = k8s.NamespaceFlag(cmd)
 		plug.Args = args
@@ -96,10 +119,31 @@ func Do(description command.Describer, plugger Plugger, moreFlags ...command.Cmd
 			Out:    os.Stdout,
 			ErrOut: os.Stderr,
 		}
+	}
 
+	runCmd := func(cmd *cobra.Command, args []string) {
+		setup(cmd, args)
 		plugger.Run(plug)
 	}
 
+	// Sub commands are run with the same Plug setup as the main command
+	var subCommands []*cobra.Command
+	if subCommander, ok := plugger.(SubCommander); ok {
+		for _, subCommand := range subCommander.SubCommands() {
+			subPlugger := subCommand.Plugger
+			subCommands = append(subCommands, &cobra.Command{
+				Use:     subCommand.Use,
+				Short:   subCommand.Short,
+				Example: subCommand.Example,
+				Args:    subCommand.Args,
+				Run: func(cmd *cobra.Command, args []string) {
+					setup(cmd, args)
+					subPlugger.Run(plug)
+				},
+			})
+		}
+	}
+
 	command.RunOrDie(
 		description.Usage(),
 		description.Version(),
@@ -107,6 +151,7 @@ func Do(description command.Describer, plugger Plugger, moreFlags ...command.Cmd
 		runCmd,
 		[]command.FlagFlagger{plug.ConfigFlags, plug.ResourceFlags},
 		append([]command.CmdFlagger{plug.PrintFlags}, moreFlags...),
+		subCommands...,
 	)
 }
 
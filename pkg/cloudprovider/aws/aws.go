@@ -129,6 +129,12 @@ func (p *provider) InstancesExist(providerIDs []string) (validProviderIDs []stri
 	return validProviderIDs, nil
 }
 
+// ReplacesTerminatedInstances returns true, as terminating an instance doesn't change the desired capacity of its
+// Autoscaling group
+func (p *provider) ReplacesTerminatedInstances() bool {
+	return true
+}
+
 // TerminateInstance terminates an instance
 func (p *provider) TerminateInstance(providerID string) error {
 	instanceID, err := providerIDToInstanceID(providerID)
@@ -0,0 +1,376 @@
+package azure
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute"
+	"github.com/atlassian-labs/cyclops/pkg/cloudprovider"
+	"github.com/go-logr/logr"
+)
+
+const (
+	// ProviderName is the name of the provider
+	ProviderName = "azure"
+
+	// detachedTag is set on scale set VMs which have been detached. Azure tag names can't contain "/"
+	detachedTag      = "cyclops-detached"
+	detachedTagValue = "true"
+
+	provisioningStateSucceeded = "Succeeded"
+)
+
+var providerIDRegex = regexp.MustCompile(`(?i)^azure:\/\/\/subscriptions\/([^/]+)\/resourceGroups\/([^/]+)\/providers\/Microsoft\.Compute\/virtualMachineScaleSets\/([^/]+)\/virtualMachines\/(\d+)$`)
+
+// vmRef identifies a virtual machine in a scale set
+type vmRef struct {
+	subscription  string
+	resourceGroup string
+	scaleSet      string
+	instanceID    string
+}
+
+// providerID returns the Kubernetes provider ID for the virtual machine referenced. The resource group is
+// lower cased, the same as the Azure cloud provider does when registering nodes.
+func (r vmRef) providerID() string {
+	return fmt.Sprintf("azure:///subscriptions/%s/resourceGroups/%s/providers/Microsoft.Compute/virtualMachineScaleSets/%s/virtualMachines/%s",
+		r.subscription, strings.ToLower(r.resourceGroup), r.scaleSet, r.instanceID)
+}
+
+// equals compares two references, ignoring case like the Azure API does
+func (r vmRef) equals(other vmRef) bool {
+	return strings.EqualFold(r.subscription, other.subscription) &&
+		strings.EqualFold(r.resourceGroup, other.resourceGroup) &&
+		strings.EqualFold(r.scaleSet, other.scaleSet) &&
+		r.instanceID == other.instanceID
+}
+
+func providerIDToVMRef(providerID string) (vmRef, error) {
+	res := providerIDRegex.FindStringSubmatch(providerID)
+	if len(res) != 5 {
+		return vmRef{}, fmt.Errorf("unable to extract scale set vm from provider ID")
+	}
+	return vmRef{subscription: res[1], resourceGroup: res[2], scaleSet: res[3], instanceID: res[4]}, nil
+}
+
+// vmIDToVMRef converts the resource ID of a scale set VM into a reference
+func vmIDToVMRef(vmID string) (vmRef, error) {
+	return providerIDToVMRef("azure://" + vmID)
+}
+
+// nodeGroupNameToScaleSet converts a node group name in the format <resourceGroup>/<scaleSetName> into
+// the resource group and name of the scale set
+func nodeGroupNameToScaleSet(nodeGroupName string) (resourceGroup, name string, err error) {
+	parts := strings.Split(nodeGroupName, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("node group name %q must be in the format <resourceGroup>/<scaleSetName>", nodeGroupName)
+	}
+	return parts[0], parts[1], nil
+}
+
+// isNotFound returns true if the error returned by the Azure API is a 404
+func isNotFound(err error) bool {
+	var respErr *azcore.ResponseError
+	if errors.As(err, &respErr) {
+		return respErr.StatusCode == http.StatusNotFound
+	}
+	return false
+}
+
+// isDetached returns true if the VM has been tagged as detached from its scale set
+func isDetached(vm *armcompute.VirtualMachineScaleSetVM) bool {
+	value, ok := vm.Tags[detachedTag]
+	return ok && value != nil && *value == detachedTagValue
+}
+
+type provider struct {
+	scaleSetService scaleSetService
+	logger          logr.Logger
+}
+
+// scaleSet stores a virtual machine scale set and the virtual machines in it
+type scaleSet struct {
+	resourceGroup string
+	name          string
+	nodeGroup     string
+	capacity      int64
+	vms           []*armcompute.VirtualMachineScaleSetVM
+}
+
+type scaleSets struct {
+	scaleSetService scaleSetService
+	groups          []*scaleSet
+	logger          logr.Logger
+}
+
+type instance struct {
+	ref           vmRef
+	vm            *armcompute.VirtualMachineScaleSetVM
+	nodeGroupName string
+}
+
+// Name returns the name of the cloud provider
+func (p *provider) Name() string {
+	return ProviderName
+}
+
+// GetNodeGroups gets virtual machine scale sets. Names must be in the format <resourceGroup>/<scaleSetName>
+func (p *provider) GetNodeGroups(names []string) (cloudprovider.NodeGroups, error) {
+	var groups []*scaleSet
+	for _, name := range names {
+		resourceGroup, scaleSetName, err := nodeGroupNameToScaleSet(name)
+		if err != nil {
+			return nil, err
+		}
+
+		vmss, err := p.scaleSetService.GetScaleSet(resourceGroup, scaleSetName)
+		if err != nil {
+			return nil, err
+		}
+
+		vms, err := p.scaleSetService.ListScaleSetVMs(resourceGroup, scaleSetName)
+		if err != nil {
+			return nil, err
+		}
+
+		var capacity int64
+		if vmss.SKU != nil && vmss.SKU.Capacity != nil {
+			capacity = *vmss.SKU.Capacity
+		}
+
+		groups = append(groups, &scaleSet{
+			resourceGroup: resourceGroup,
+			name:          scaleSetName,
+			nodeGroup:     name,
+			capacity:      capacity,
+			vms:           vms,
+		})
+	}
+
+	if len(groups) == 0 {
+		return nil, fmt.Errorf("virtual machine scale sets not found: %v", names)
+	}
+
+	return &scaleSets{
+		scaleSetService: p.scaleSetService,
+		groups:          groups,
+		logger:          p.logger,
+	}, nil
+}
+
+// InstancesExist returns a list of the instances that exist
+func (p *provider) InstancesExist(providerIDs []string) (validProviderIDs []string, err error) {
+	for _, providerID := range providerIDs {
+		ref, err := providerIDToVMRef(providerID)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := p.scaleSetService.GetScaleSetVM(ref.resourceGroup, ref.scaleSet, ref.instanceID); err != nil {
+			if isNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+
+		validProviderIDs = append(validProviderIDs, providerID)
+	}
+
+	return validProviderIDs, nil
+}
+
+// ReplacesTerminatedInstances returns false, as deleting a virtual machine from a scale set also reduces its capacity
+func (p *provider) ReplacesTerminatedInstances() bool {
+	return false
+}
+
+// TerminateInstance deletes a virtual machine from its scale set
+func (p *provider) TerminateInstance(providerID string) error {
+	ref, err := providerIDToVMRef(providerID)
+	if err != nil {
+		return err
+	}
+
+	return p.scaleSetService.DeleteScaleSetVM(ref.resourceGroup, ref.scaleSet, ref.instanceID)
+}
+
+// instances returns a map of the VMs in the scale sets that have not been detached and match the filter
+// with providerID as key and cloudprovider.Instance as value
+func (s *scaleSets) instances(filter func(*armcompute.VirtualMachineScaleSetVM) bool) map[string]cloudprovider.Instance {
+	instances := make(map[string]cloudprovider.Instance)
+	for _, group := range s.groups {
+		for _, vm := range group.vms {
+			if isDetached(vm) || !filter(vm) {
+				continue
+			}
+			if vm.ID == nil {
+				continue
+			}
+			ref, err := vmIDToVMRef(*vm.ID)
+			if err != nil {
+				s.logger.Info("skip vm which failed vm id to providerID conversion", "id", *vm.ID)
+				continue
+			}
+			instances[ref.providerID()] = &instance{
+				ref:           ref,
+				vm:            vm,
+				nodeGroupName: group.nodeGroup,
+			}
+		}
+	}
+	return instances
+}
+
+// Instances returns a map of all VMs in the scale sets which have not been detached
+// with providerID as key and cloudprovider.Instance as value
+func (s *scaleSets) Instances() map[string]cloudprovider.Instance {
+	return s.instances(func(*armcompute.VirtualMachineScaleSetVM) bool {
+		return true
+	})
+}
+
+// ReadyInstances returns a map of VMs which have been provisioned successfully
+// with providerID as key and cloudprovider.Instance as value
+func (s *scaleSets) ReadyInstances() map[string]cloudprovider.Instance {
+	return s.instances(vmReady)
+}
+
+// NotReadyInstances returns a map of VMs which have not been provisioned successfully
+// with providerID as key and cloudprovider.Instance as value
+func (s *scaleSets) NotReadyInstances() map[string]cloudprovider.Instance {
+	return s.instances(func(vm *armcompute.VirtualMachineScaleSetVM) bool {
+		return !vmReady(vm)
+	})
+}
+
+// getScaleSetByVM finds the scale set that contains the VM
+func (s *scaleSets) getScaleSetByVM(ref vmRef) (*scaleSet, *armcompute.VirtualMachineScaleSetVM, error) {
+	for _, group := range s.groups {
+		for _, vm := range group.vms {
+			if vm.ID == nil {
+				continue
+			}
+			vmRef, err := vmIDToVMRef(*vm.ID)
+			if err != nil {
+				continue
+			}
+			if vmRef.equals(ref) {
+				return group, vm, nil
+			}
+		}
+	}
+	return nil, nil, fmt.Errorf("failed to find target node group for instance: %v", ref.instanceID)
+}
+
+// DetachInstance tags the VM as detached, and then increases the capacity of the scale set by one to create a
+// replacement VM. Scale sets don't support removing a VM without deleting it, so the detached VM stays in the
+// scale set but is no longer counted as one of its instances. Deleting the VM in TerminateInstance returns
+// the capacity to its original size. The VM is tagged first so that a failed attempt doesn't leave the capacity
+// raised for a VM that is still counted, which would raise it again when retried.
+func (s *scaleSets) DetachInstance(providerID string) (alreadyDetaching bool, err error) {
+	ref, err := providerIDToVMRef(providerID)
+	if err != nil {
+		return false, err
+	}
+
+	group, vm, err := s.getScaleSetByVM(ref)
+	if err != nil {
+		return false, err
+	}
+
+	if isDetached(vm) {
+		return true, fmt.Errorf("instance %v is already detached from %v", ref.instanceID, group.nodeGroup)
+	}
+
+	tags := vm.Tags
+	detachedTags := make(map[string]*string, len(tags)+1)
+	for key, value := range tags {
+		detachedTags[key] = value
+	}
+	value := detachedTagValue
+	detachedTags[detachedTag] = &value
+
+	vm.Tags = detachedTags
+	if err := s.scaleSetService.UpdateScaleSetVM(group.resourceGroup, group.name, ref.instanceID, vm); err != nil {
+		vm.Tags = tags
+		return false, err
+	}
+
+	if err := s.scaleSetService.SetScaleSetCapacity(group.resourceGroup, group.name, group.capacity+1); err != nil {
+		// Count the VM as part of the scale set again, since no replacement is being created for it
+		vm.Tags = tags
+		if untagErr := s.scaleSetService.UpdateScaleSetVM(group.resourceGroup, group.name, ref.instanceID, vm); untagErr != nil {
+			s.logger.Error(untagErr, "Unable to remove detached tag after failing to scale out", "instance", ref.instanceID)
+		}
+		return false, err
+	}
+	group.capacity++
+
+	return false, nil
+}
+
+// AttachInstance removes the detached tag from the VM so it is counted as part of the scale set again.
+// The capacity of the scale set is left as is, the same as attaching an instance to an auto scaling group.
+func (s *scaleSets) AttachInstance(providerID, nodeGroup string) (alreadyAttached bool, err error) {
+	ref, err := providerIDToVMRef(providerID)
+	if err != nil {
+		return false, err
+	}
+
+	group, vm, err := s.getScaleSetByVM(ref)
+	if err != nil {
+		return false, err
+	}
+
+	if group.nodeGroup != nodeGroup {
+		return false, fmt.Errorf("instance %v is in %v and cannot be attached to %v", ref.instanceID, group.nodeGroup, nodeGroup)
+	}
+
+	if !isDetached(vm) {
+		return true, fmt.Errorf("instance %v is already attached to %v", ref.instanceID, nodeGroup)
+	}
+
+	delete(vm.Tags, detachedTag)
+	return false, s.scaleSetService.UpdateScaleSetVM(group.resourceGroup, group.name, ref.instanceID, vm)
+}
+
+// vmReady returns whether the VM has been provisioned successfully
+func vmReady(vm *armcompute.VirtualMachineScaleSetVM) bool {
+	return vm.Properties != nil && vm.Properties.ProvisioningState != nil &&
+		*vm.Properties.ProvisioningState == provisioningStateSucceeded
+}
+
+// ID returns the instance ID of the VM in its scale set
+func (i *instance) ID() string {
+	return i.ref.instanceID
+}
+
+// String returns the instance ID of the VM in its scale set
+func (i *instance) String() string {
+	return i.ID()
+}
+
+// OutOfDate returns if the VM has not had the latest scale set model applied
+func (i *instance) OutOfDate() bool {
+	if i.vm.Properties == nil || i.vm.Properties.LatestModelApplied == nil {
+		return false
+	}
+	return !*i.vm.Properties.LatestModelApplied
+}
+
+// MatchesProviderID returns if the instance matches the providerID
+func (i *instance) MatchesProviderID(providerID string) bool {
+	if ref, err := providerIDToVMRef(providerID); err == nil {
+		return i.ref.equals(ref)
+	}
+	return false
+}
+
+// NodeGroupName returns cloud provider node group name for the instance
+func (i *instance) NodeGroupName() string {
+	return i.nodeGroupName
+}
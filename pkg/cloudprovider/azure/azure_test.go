@@ -0,0 +1,285 @@
+package azure
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute"
+	"github.com/stretchr/testify/assert"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/atlassian-labs/cyclops/pkg/test"
+)
+
+const (
+	testVMIDPrefix       = "/subscriptions/sub-id/resourceGroups/My-RG/providers/Microsoft.Compute/virtualMachineScaleSets/nodes/virtualMachines/"
+	testProviderIDPrefix = "azure:///subscriptions/sub-id/resourceGroups/my-rg/providers/Microsoft.Compute/virtualMachineScaleSets/nodes/virtualMachines/"
+)
+
+func strPtr(s string) *string {
+	return &s
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+func newTestVM(instanceID string, provisioningState string, latestModelApplied bool) *armcompute.VirtualMachineScaleSetVM {
+	return &armcompute.VirtualMachineScaleSetVM{
+		ID:         strPtr(testVMIDPrefix + instanceID),
+		InstanceID: strPtr(instanceID),
+		Properties: &armcompute.VirtualMachineScaleSetVMProperties{
+			ProvisioningState:  strPtr(provisioningState),
+			LatestModelApplied: boolPtr(latestModelApplied),
+		},
+	}
+}
+
+func newTestScaleSetService() *test.MockScaleSetService {
+	capacity := int64(2)
+	return &test.MockScaleSetService{
+		ScaleSets: map[string]*armcompute.VirtualMachineScaleSet{
+			"nodes": {
+				Name: strPtr("nodes"),
+				SKU:  &armcompute.SKU{Capacity: &capacity},
+			},
+		},
+		ScaleSetVMs: map[string][]*armcompute.VirtualMachineScaleSetVM{
+			"nodes": {
+				newTestVM("0", provisioningStateSucceeded, false),
+				newTestVM("1", "Creating", true),
+			},
+		},
+	}
+}
+
+func newTestProvider(service *test.MockScaleSetService) *provider {
+	return &provider{
+		scaleSetService: service,
+		logger:          logf.Log.WithName("azure-test"),
+	}
+}
+
+func Test_providerIDToVMRef(t *testing.T) {
+	tests := []struct {
+		name       string
+		providerID string
+		ref        vmRef
+		wantErr    bool
+	}{
+		{
+			"expected format",
+			testProviderIDPrefix + "3",
+			vmRef{subscription: "sub-id", resourceGroup: "my-rg", scaleSet: "nodes", instanceID: "3"},
+			false,
+		},
+		{
+			"mixed case",
+			"azure:///subscriptions/sub-id/resourcegroups/My-RG/providers/microsoft.compute/virtualmachinescalesets/nodes/virtualmachines/3",
+			vmRef{subscription: "sub-id", resourceGroup: "My-RG", scaleSet: "nodes", instanceID: "3"},
+			false,
+		},
+		{
+			"incorrect format. standalone vm",
+			"azure:///subscriptions/sub-id/resourceGroups/my-rg/providers/Microsoft.Compute/virtualMachines/node-1",
+			vmRef{},
+			true,
+		},
+		{
+			"incorrect format. missing instance id",
+			testProviderIDPrefix,
+			vmRef{},
+			true,
+		},
+		{
+			"incorrect format. wrong scheme",
+			"gce://my-project/us-central1-a/nodes-abcd",
+			vmRef{},
+			true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ref, err := providerIDToVMRef(tt.providerID)
+			if tt.wantErr {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.ref, ref)
+		})
+	}
+}
+
+func Test_nodeGroupNameToScaleSet(t *testing.T) {
+	resourceGroup, name, err := nodeGroupNameToScaleSet("my-rg/nodes")
+	assert.NoError(t, err)
+	assert.Equal(t, "my-rg", resourceGroup)
+	assert.Equal(t, "nodes", name)
+
+	_, _, err = nodeGroupNameToScaleSet("nodes")
+	assert.Error(t, err)
+
+	_, _, err = nodeGroupNameToScaleSet("my-rg/")
+	assert.Error(t, err)
+}
+
+func TestGetNodeGroups(t *testing.T) {
+	p := newTestProvider(newTestScaleSetService())
+
+	nodeGroups, err := p.GetNodeGroups([]string{"my-rg/nodes"})
+	assert.NoError(t, err)
+
+	instances := nodeGroups.Instances()
+	assert.Len(t, instances, 2)
+
+	// The resource group is lower cased in the provider ID
+	outOfDate := instances[testProviderIDPrefix+"0"]
+	if assert.NotNil(t, outOfDate) {
+		assert.Equal(t, "0", outOfDate.ID())
+		assert.True(t, outOfDate.OutOfDate())
+		assert.Equal(t, "my-rg/nodes", outOfDate.NodeGroupName())
+		assert.True(t, outOfDate.MatchesProviderID(testProviderIDPrefix+"0"))
+		assert.True(t, outOfDate.MatchesProviderID("azure://"+testVMIDPrefix+"0"))
+		assert.False(t, outOfDate.MatchesProviderID(testProviderIDPrefix+"1"))
+	}
+
+	upToDate := instances[testProviderIDPrefix+"1"]
+	if assert.NotNil(t, upToDate) {
+		assert.False(t, upToDate.OutOfDate())
+	}
+
+	assert.Len(t, nodeGroups.ReadyInstances(), 1)
+	assert.Contains(t, nodeGroups.ReadyInstances(), testProviderIDPrefix+"0")
+	assert.Len(t, nodeGroups.NotReadyInstances(), 1)
+	assert.Contains(t, nodeGroups.NotReadyInstances(), testProviderIDPrefix+"1")
+}
+
+func TestDetachInstance(t *testing.T) {
+	service := newTestScaleSetService()
+	p := newTestProvider(service)
+
+	nodeGroups, err := p.GetNodeGroups([]string{"my-rg/nodes"})
+	assert.NoError(t, err)
+
+	alreadyDetaching, err := nodeGroups.DetachInstance(testProviderIDPrefix + "0")
+	assert.NoError(t, err)
+	assert.False(t, alreadyDetaching)
+
+	// The scale set is scaled out to create a replacement, and the VM is tagged as detached
+	assert.Equal(t, int64(3), service.Capacities["nodes"])
+	if assert.Len(t, service.UpdatedVMs, 1) {
+		assert.True(t, isDetached(service.UpdatedVMs[0]))
+	}
+
+	// Detached VMs are no longer instances of the node group
+	assert.Len(t, nodeGroups.Instances(), 1)
+	assert.NotContains(t, nodeGroups.Instances(), testProviderIDPrefix+"0")
+
+	// Detaching a second VM scales out again
+	_, err = nodeGroups.DetachInstance(testProviderIDPrefix + "1")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), service.Capacities["nodes"])
+
+	// Detaching again is reported as already detaching
+	alreadyDetaching, err = nodeGroups.DetachInstance(testProviderIDPrefix + "0")
+	assert.Error(t, err)
+	assert.True(t, alreadyDetaching)
+}
+
+func TestDetachInstance_TagFailed(t *testing.T) {
+	service := newTestScaleSetService()
+	service.UpdateScaleSetVMErr = &azcore.ResponseError{StatusCode: http.StatusInternalServerError}
+	p := newTestProvider(service)
+
+	nodeGroups, err := p.GetNodeGroups([]string{"my-rg/nodes"})
+	assert.NoError(t, err)
+
+	// The scale set isn't scaled out if the VM can't be tagged
+	alreadyDetaching, err := nodeGroups.DetachInstance(testProviderIDPrefix + "0")
+	assert.Error(t, err)
+	assert.False(t, alreadyDetaching)
+	assert.Empty(t, service.Capacities)
+	assert.Contains(t, nodeGroups.Instances(), testProviderIDPrefix+"0")
+
+	// Retrying scales out only once
+	service.UpdateScaleSetVMErr = nil
+	alreadyDetaching, err = nodeGroups.DetachInstance(testProviderIDPrefix + "0")
+	assert.NoError(t, err)
+	assert.False(t, alreadyDetaching)
+	assert.Equal(t, int64(3), service.Capacities["nodes"])
+}
+
+func TestDetachInstance_ScaleOutFailed(t *testing.T) {
+	service := newTestScaleSetService()
+	service.SetScaleSetCapacityErr = &azcore.ResponseError{StatusCode: http.StatusInternalServerError}
+	p := newTestProvider(service)
+
+	nodeGroups, err := p.GetNodeGroups([]string{"my-rg/nodes"})
+	assert.NoError(t, err)
+
+	// The detached tag is removed again if the scale set can't be scaled out
+	alreadyDetaching, err := nodeGroups.DetachInstance(testProviderIDPrefix + "0")
+	assert.Error(t, err)
+	assert.False(t, alreadyDetaching)
+	if assert.Len(t, service.UpdatedVMs, 2) {
+		assert.False(t, isDetached(service.UpdatedVMs[1]))
+	}
+	assert.Contains(t, nodeGroups.Instances(), testProviderIDPrefix+"0")
+
+	// Retrying scales out only once
+	service.SetScaleSetCapacityErr = nil
+	_, err = nodeGroups.DetachInstance(testProviderIDPrefix + "0")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), service.Capacities["nodes"])
+}
+
+func TestAttachInstance(t *testing.T) {
+	service := newTestScaleSetService()
+	service.ScaleSetVMs["nodes"][0].Tags = map[string]*string{detachedTag: strPtr(detachedTagValue)}
+	p := newTestProvider(service)
+
+	nodeGroups, err := p.GetNodeGroups([]string{"my-rg/nodes"})
+	assert.NoError(t, err)
+	assert.Len(t, nodeGroups.Instances(), 1)
+
+	alreadyAttached, err := nodeGroups.AttachInstance(testProviderIDPrefix+"0", "my-rg/nodes")
+	assert.NoError(t, err)
+	assert.False(t, alreadyAttached)
+	assert.Len(t, nodeGroups.Instances(), 2)
+
+	alreadyAttached, err = nodeGroups.AttachInstance(testProviderIDPrefix+"0", "my-rg/nodes")
+	assert.Error(t, err)
+	assert.True(t, alreadyAttached)
+
+	_, err = nodeGroups.AttachInstance(testProviderIDPrefix+"0", "my-rg/other")
+	assert.Error(t, err)
+}
+
+func TestInstancesExist(t *testing.T) {
+	service := newTestScaleSetService()
+	p := newTestProvider(service)
+
+	valid, err := p.InstancesExist([]string{testProviderIDPrefix + "0"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{testProviderIDPrefix + "0"}, valid)
+
+	service.GetScaleSetVMErr = &azcore.ResponseError{StatusCode: http.StatusNotFound}
+	valid, err = p.InstancesExist([]string{testProviderIDPrefix + "0"})
+	assert.NoError(t, err)
+	assert.Empty(t, valid)
+
+	service.GetScaleSetVMErr = &azcore.ResponseError{StatusCode: http.StatusForbidden}
+	_, err = p.InstancesExist([]string{testProviderIDPrefix + "0"})
+	assert.Error(t, err)
+}
+
+func TestTerminateInstance(t *testing.T) {
+	service := newTestScaleSetService()
+	p := newTestProvider(service)
+
+	assert.NoError(t, p.TerminateInstance(testProviderIDPrefix+"1"))
+	assert.Equal(t, []string{"1"}, service.DeletedVMs)
+}
@@ -0,0 +1,50 @@
+package azure
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute"
+	"github.com/atlassian-labs/cyclops/pkg/cloudprovider"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const subscriptionIDEnv = "AZURE_SUBSCRIPTION_ID"
+
+var log = logf.Log.WithName("azure")
+
+// NewCloudProvider returns a new Azure cloud provider
+func NewCloudProvider() (cloudprovider.CloudProvider, error) {
+	subscriptionID := os.Getenv(subscriptionIDEnv)
+	if subscriptionID == "" {
+		return nil, fmt.Errorf("%v must be set to the subscription the scale sets reside in", subscriptionIDEnv)
+	}
+
+	cred, err := azidentity.NewDefaultAzureCredential(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	scaleSetsClient, err := armcompute.NewVirtualMachineScaleSetsClient(subscriptionID, cred, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	scaleSetVMsClient, err := armcompute.NewVirtualMachineScaleSetVMsClient(subscriptionID, cred, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &provider{
+		scaleSetService: &scaleSetAPI{
+			scaleSets:   scaleSetsClient,
+			scaleSetVMs: scaleSetVMsClient,
+		},
+		logger: log,
+	}
+
+	log.Info(fmt.Sprintf("azure clients created successfully, using subscription %v", subscriptionID))
+
+	return p, nil
+}
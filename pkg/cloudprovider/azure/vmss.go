@@ -0,0 +1,77 @@
+package azure
+
+import (
+	"context"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute"
+)
+
+// scaleSetService is the subset of the Virtual Machine Scale Set API used by the provider. It allows the
+// API to be faked in tests.
+type scaleSetService interface {
+	GetScaleSet(resourceGroup, name string) (*armcompute.VirtualMachineScaleSet, error)
+	ListScaleSetVMs(resourceGroup, name string) ([]*armcompute.VirtualMachineScaleSetVM, error)
+	SetScaleSetCapacity(resourceGroup, name string, capacity int64) error
+	GetScaleSetVM(resourceGroup, name, instanceID string) (*armcompute.VirtualMachineScaleSetVM, error)
+	UpdateScaleSetVM(resourceGroup, name, instanceID string, vm *armcompute.VirtualMachineScaleSetVM) error
+	DeleteScaleSetVM(resourceGroup, name, instanceID string) error
+}
+
+// scaleSetAPI implements scaleSetService using the real Azure API. Long running operations are started
+// but not waited on, the same as the asynchronous scaling operations of the other providers.
+type scaleSetAPI struct {
+	scaleSets   *armcompute.VirtualMachineScaleSetsClient
+	scaleSetVMs *armcompute.VirtualMachineScaleSetVMsClient
+}
+
+// GetScaleSet gets a virtual machine scale set
+func (s *scaleSetAPI) GetScaleSet(resourceGroup, name string) (*armcompute.VirtualMachineScaleSet, error) {
+	resp, err := s.scaleSets.Get(context.TODO(), resourceGroup, name, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &resp.VirtualMachineScaleSet, nil
+}
+
+// ListScaleSetVMs lists all of the virtual machines in a scale set
+func (s *scaleSetAPI) ListScaleSetVMs(resourceGroup, name string) ([]*armcompute.VirtualMachineScaleSetVM, error) {
+	var vms []*armcompute.VirtualMachineScaleSetVM
+	pager := s.scaleSetVMs.NewListPager(resourceGroup, name, nil)
+	for pager.More() {
+		page, err := pager.NextPage(context.TODO())
+		if err != nil {
+			return nil, err
+		}
+		vms = append(vms, page.Value...)
+	}
+	return vms, nil
+}
+
+// SetScaleSetCapacity sets the number of virtual machines in the scale set
+func (s *scaleSetAPI) SetScaleSetCapacity(resourceGroup, name string, capacity int64) error {
+	_, err := s.scaleSets.BeginUpdate(context.TODO(), resourceGroup, name, armcompute.VirtualMachineScaleSetUpdate{
+		SKU: &armcompute.SKU{Capacity: &capacity},
+	}, nil)
+	return err
+}
+
+// GetScaleSetVM gets a virtual machine in a scale set
+func (s *scaleSetAPI) GetScaleSetVM(resourceGroup, name, instanceID string) (*armcompute.VirtualMachineScaleSetVM, error) {
+	resp, err := s.scaleSetVMs.Get(context.TODO(), resourceGroup, name, instanceID, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &resp.VirtualMachineScaleSetVM, nil
+}
+
+// UpdateScaleSetVM updates a virtual machine in a scale set
+func (s *scaleSetAPI) UpdateScaleSetVM(resourceGroup, name, instanceID string, vm *armcompute.VirtualMachineScaleSetVM) error {
+	_, err := s.scaleSetVMs.BeginUpdate(context.TODO(), resourceGroup, name, instanceID, *vm, nil)
+	return err
+}
+
+// DeleteScaleSetVM deletes a virtual machine in a scale set, which also decreases the capacity of the scale set
+func (s *scaleSetAPI) DeleteScaleSetVM(resourceGroup, name, instanceID string) error {
+	_, err := s.scaleSetVMs.BeginDelete(context.TODO(), resourceGroup, name, instanceID, nil)
+	return err
+}
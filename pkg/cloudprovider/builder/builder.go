@@ -5,6 +5,10 @@ import (
 
 	"github.com/atlassian-labs/cyclops/pkg/cloudprovider"
 	"github.com/atlassian-labs/cyclops/pkg/cloudprovider/aws"
+	"github.com/atlassian-labs/cyclops/pkg/cloudprovider/azure"
+	"github.com/atlassian-labs/cyclops/pkg/cloudprovider/clusterapi"
+	"github.com/atlassian-labs/cyclops/pkg/cloudprovider/fake"
+	"github.com/atlassian-labs/cyclops/pkg/cloudprovider/gcp"
 )
 
 type builderFunc func() (cloudprovider.CloudProvider, error)
@@ -12,7 +16,11 @@ type builderFunc func() (cloudprovider.CloudProvider, error)
 // BuildCloudProvider returns a cloud provider based on the provided name
 func BuildCloudProvider(name string) (cloudprovider.CloudProvider, error) {
 	buildFuncs := map[string]builderFunc{
-		aws.ProviderName: aws.NewCloudProvider,
+		aws.ProviderName:        aws.NewCloudProvider,
+		gcp.ProviderName:        gcp.NewCloudProvider,
+		azure.ProviderName:      azure.NewCloudProvider,
+		clusterapi.ProviderName: clusterapi.NewCloudProvider,
+		fake.ProviderName:       fake.NewCloudProvider,
 	}
 
 	builder, ok := buildFuncs[name]
@@ -0,0 +1,34 @@
+package clusterapi
+
+import (
+	"github.com/atlassian-labs/cyclops/pkg/cloudprovider"
+	"github.com/atlassian-labs/cyclops/pkg/k8s"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+var log = logf.Log.WithName("clusterapi")
+
+// NewCloudProvider returns a new Cluster API cloud provider. Machines are read from the same
+// API server that Cyclops is running against.
+func NewCloudProvider() (cloudprovider.CloudProvider, error) {
+	config, err := k8s.GetConfig("")
+	if err != nil {
+		return nil, err
+	}
+
+	// Cluster API objects are handled as unstructured objects, so the scheme doesn't need to know about them
+	c, err := client.New(config, client.Options{})
+	if err != nil {
+		return nil, err
+	}
+
+	p := &provider{
+		client: c,
+		logger: log,
+	}
+
+	log.Info("cluster api client created successfully", "apiVersion", apiVersion)
+
+	return p, nil
+}
@@ -0,0 +1,508 @@
+package clusterapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/atlassian-labs/cyclops/pkg/cloudprovider"
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// ProviderName is the name of the provider
+	ProviderName = "clusterapi"
+
+	apiVersion = "cluster.x-k8s.io/v1beta1"
+
+	machineKind           = "Machine"
+	machineSetKind        = "MachineSet"
+	machineDeploymentKind = "MachineDeployment"
+
+	// revisionAnnotation is set by Cluster API on MachineDeployments and their MachineSets to track rollouts
+	revisionAnnotation = "machinedeployment.clusters.x-k8s.io/revision"
+	// templateHashLabel is set by Cluster API on MachineSets and Machines created for a MachineDeployment template
+	templateHashLabel = "machine-template-hash"
+	// clusterNameLabel links a Machine to its Cluster, and is never removed when detaching
+	clusterNameLabel = "cluster.x-k8s.io/cluster-name"
+
+	// detachedAnnotation stores the selector labels removed from a detached Machine so they can be restored
+	detachedAnnotation = "cyclops.atlassian.com/detached-labels"
+
+	machinePhaseRunning = "Running"
+)
+
+// gvk returns the group version kind of a Cluster API kind
+func gvk(kind string) schema.GroupVersionKind {
+	return schema.FromAPIVersionAndKind(apiVersion, kind)
+}
+
+// newObject returns an empty unstructured object of a Cluster API kind
+func newObject(kind string) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(gvk(kind))
+	return u
+}
+
+// newList returns an empty unstructured list of a Cluster API kind
+func newList(kind string) *unstructured.UnstructuredList {
+	u := &unstructured.UnstructuredList{}
+	u.SetGroupVersionKind(gvk(kind + "List"))
+	return u
+}
+
+// parseNodeGroupName converts a node group name into the kind, namespace and name of the owner of the Machines.
+// Names are in the format [MachineDeployment|MachineSet/]<namespace>/<name>, and default to a MachineDeployment.
+func parseNodeGroupName(nodeGroupName string) (kind, namespace, name string, err error) {
+	parts := strings.Split(nodeGroupName, "/")
+	switch {
+	case len(parts) == 2:
+		kind, namespace, name = machineDeploymentKind, parts[0], parts[1]
+	case len(parts) == 3 && (parts[0] == machineDeploymentKind || parts[0] == machineSetKind):
+		kind, namespace, name = parts[0], parts[1], parts[2]
+	default:
+		return "", "", "", fmt.Errorf("node group name %q must be in the format [MachineDeployment|MachineSet/]<namespace>/<name>", nodeGroupName)
+	}
+	if namespace == "" || name == "" {
+		return "", "", "", fmt.Errorf("node group name %q must be in the format [MachineDeployment|MachineSet/]<namespace>/<name>", nodeGroupName)
+	}
+	return kind, namespace, name, nil
+}
+
+// machineProviderID returns the provider ID of a Machine, which is empty until the infrastructure is provisioned
+func machineProviderID(machine *unstructured.Unstructured) string {
+	providerID, _, _ := unstructured.NestedString(machine.Object, "spec", "providerID")
+	return providerID
+}
+
+// machineKey returns the key of a Machine in the instance maps. Machines which have not been provisioned yet
+// don't have a provider ID, so they are keyed by their namespaced name instead, which will never match a node.
+func machineKey(machine *unstructured.Unstructured) string {
+	if providerID := machineProviderID(machine); providerID != "" {
+		return providerID
+	}
+	return fmt.Sprintf("clusterapi://%s/%s", machine.GetNamespace(), machine.GetName())
+}
+
+// machineReady returns whether the Machine is running and not being deleted
+func machineReady(machine *unstructured.Unstructured) bool {
+	phase, _, _ := unstructured.NestedString(machine.Object, "status", "phase")
+	return phase == machinePhaseRunning && machine.GetDeletionTimestamp() == nil && machineProviderID(machine) != ""
+}
+
+// ownerSelector returns the label selector of a MachineDeployment or MachineSet
+func ownerSelector(owner *unstructured.Unstructured) (*metav1.LabelSelector, labels.Selector, error) {
+	rawSelector, found, err := unstructured.NestedMap(owner.Object, "spec", "selector")
+	if err != nil || !found {
+		return nil, nil, fmt.Errorf("failed to get selector of %v %v/%v: %v", owner.GetKind(), owner.GetNamespace(), owner.GetName(), err)
+	}
+
+	var labelSelector metav1.LabelSelector
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(rawSelector, &labelSelector); err != nil {
+		return nil, nil, err
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(&labelSelector)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &labelSelector, selector, nil
+}
+
+type provider struct {
+	client client.Client
+	logger logr.Logger
+}
+
+// machineGroup stores the MachineDeployment or MachineSet for a node group and the Machines it owns
+type machineGroup struct {
+	nodeGroup     string
+	owner         *unstructured.Unstructured
+	labelSelector *metav1.LabelSelector
+	selector      labels.Selector
+	// templateHash is the template hash of the current MachineSet, empty if it is unknown
+	templateHash string
+	// version is the Kubernetes version of a MachineSet template, only set for MachineSet node groups
+	version  string
+	machines []*unstructured.Unstructured
+}
+
+type machineGroups struct {
+	client client.Client
+	groups []*machineGroup
+	logger logr.Logger
+}
+
+type instance struct {
+	machine       *unstructured.Unstructured
+	nodeGroupName string
+	outOfDate     bool
+}
+
+// Name returns the name of the cloud provider
+func (p *provider) Name() string {
+	return ProviderName
+}
+
+// GetNodeGroups gets the MachineDeployments and MachineSets. Names must be in the format
+// [MachineDeployment|MachineSet/]<namespace>/<name>
+func (p *provider) GetNodeGroups(names []string) (cloudprovider.NodeGroups, error) {
+	var groups []*machineGroup
+	for _, name := range names {
+		group, err := p.getMachineGroup(name)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, group)
+	}
+
+	if len(groups) == 0 {
+		return nil, fmt.Errorf("machine groups not found: %v", names)
+	}
+
+	return &machineGroups{
+		client: p.client,
+		groups: groups,
+		logger: p.logger,
+	}, nil
+}
+
+// getMachineGroup gets the owner of the node group, its current template and the Machines it selects
+func (p *provider) getMachineGroup(nodeGroupName string) (*machineGroup, error) {
+	kind, namespace, name, err := parseNodeGroupName(nodeGroupName)
+	if err != nil {
+		return nil, err
+	}
+
+	owner := newObject(kind)
+	if err := p.client.Get(context.TODO(), client.ObjectKey{Namespace: namespace, Name: name}, owner); err != nil {
+		return nil, err
+	}
+
+	labelSelector, selector, err := ownerSelector(owner)
+	if err != nil {
+		return nil, err
+	}
+
+	group := &machineGroup{
+		nodeGroup:     nodeGroupName,
+		owner:         owner,
+		labelSelector: labelSelector,
+		selector:      selector,
+	}
+
+	switch kind {
+	case machineDeploymentKind:
+		// The current MachineSet of a MachineDeployment has the same revision as the MachineDeployment
+		machineSets := newList(machineSetKind)
+		if err := p.client.List(context.TODO(), machineSets, client.InNamespace(namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+			return nil, err
+		}
+		revision := owner.GetAnnotations()[revisionAnnotation]
+		for i := range machineSets.Items {
+			if revision != "" && machineSets.Items[i].GetAnnotations()[revisionAnnotation] == revision {
+				group.templateHash = machineSets.Items[i].GetLabels()[templateHashLabel]
+				break
+			}
+		}
+	case machineSetKind:
+		group.templateHash = owner.GetLabels()[templateHashLabel]
+		group.version, _, _ = unstructured.NestedString(owner.Object, "spec", "template", "spec", "version")
+	}
+
+	machines := newList(machineKind)
+	if err := p.client.List(context.TODO(), machines, client.InNamespace(namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, err
+	}
+	for i := range machines.Items {
+		group.machines = append(group.machines, &machines.Items[i])
+	}
+
+	return group, nil
+}
+
+// listMachines lists the Machines in all namespaces
+func (p *provider) listMachines() ([]unstructured.Unstructured, error) {
+	machines := newList(machineKind)
+	if err := p.client.List(context.TODO(), machines); err != nil {
+		return nil, err
+	}
+	return machines.Items, nil
+}
+
+// InstancesExist returns a list of the instances that exist
+func (p *provider) InstancesExist(providerIDs []string) (validProviderIDs []string, err error) {
+	machines, err := p.listMachines()
+	if err != nil {
+		return nil, err
+	}
+
+	existing := make(map[string]bool)
+	for i := range machines {
+		if providerID := machineProviderID(&machines[i]); providerID != "" {
+			existing[providerID] = true
+		}
+	}
+
+	for _, providerID := range providerIDs {
+		if existing[providerID] {
+			validProviderIDs = append(validProviderIDs, providerID)
+		}
+	}
+
+	return validProviderIDs, nil
+}
+
+// ReplacesTerminatedInstances returns true, as the MachineSet of a deleted Machine creates a new one to keep its
+// replicas
+func (p *provider) ReplacesTerminatedInstances() bool {
+	return true
+}
+
+// TerminateInstance deletes the Machine with the providerID. Cluster API deletes the infrastructure.
+func (p *provider) TerminateInstance(providerID string) error {
+	machines, err := p.listMachines()
+	if err != nil {
+		return err
+	}
+
+	for i := range machines {
+		if machineProviderID(&machines[i]) == providerID {
+			return p.client.Delete(context.TODO(), &machines[i])
+		}
+	}
+
+	return fmt.Errorf("failed to find machine for instance: %v", providerID)
+}
+
+// instances returns a map of the Machines in the groups that match the filter
+// with providerID as key and cloudprovider.Instance as value
+func (m *machineGroups) instances(filter func(*unstructured.Unstructured) bool) map[string]cloudprovider.Instance {
+	instances := make(map[string]cloudprovider.Instance)
+	for _, group := range m.groups {
+		for _, machine := range group.machines {
+			if !filter(machine) {
+				continue
+			}
+			instances[machineKey(machine)] = &instance{
+				machine:       machine,
+				nodeGroupName: group.nodeGroup,
+				outOfDate:     group.machineOutOfDate(machine),
+			}
+		}
+	}
+	return instances
+}
+
+// Instances returns a map of all Machines in the groups
+// with providerID as key and cloudprovider.Instance as value
+func (m *machineGroups) Instances() map[string]cloudprovider.Instance {
+	return m.instances(func(*unstructured.Unstructured) bool {
+		return true
+	})
+}
+
+// ReadyInstances returns a map of Machines which are running
+// with providerID as key and cloudprovider.Instance as value
+func (m *machineGroups) ReadyInstances() map[string]cloudprovider.Instance {
+	return m.instances(machineReady)
+}
+
+// NotReadyInstances returns a map of Machines which are not running
+// with providerID as key and cloudprovider.Instance as value
+func (m *machineGroups) NotReadyInstances() map[string]cloudprovider.Instance {
+	return m.instances(func(machine *unstructured.Unstructured) bool {
+		return !machineReady(machine)
+	})
+}
+
+// getGroupByMachine finds the group which owns the Machine with the providerID
+func (m *machineGroups) getGroupByMachine(providerID string) (*machineGroup, *unstructured.Unstructured, error) {
+	for _, group := range m.groups {
+		for _, machine := range group.machines {
+			if machineProviderID(machine) == providerID {
+				return group, machine, nil
+			}
+		}
+	}
+	return nil, nil, fmt.Errorf("failed to find target node group for instance: %v", providerID)
+}
+
+// getGroupByName finds the group with the node group name
+func (m *machineGroups) getGroupByName(nodeGroup string) (*machineGroup, error) {
+	for _, group := range m.groups {
+		if group.nodeGroup == nodeGroup {
+			return group, nil
+		}
+	}
+	return nil, fmt.Errorf("failed to find node group: %v", nodeGroup)
+}
+
+// DetachInstance orphans the Machine from its MachineSet by removing the selector labels and owner reference.
+// The MachineSet no longer counts the Machine as one of its replicas and creates a replacement, while the
+// orphaned Machine keeps running until it is deleted by TerminateInstance.
+func (m *machineGroups) DetachInstance(providerID string) (alreadyDetaching bool, err error) {
+	group, machine, err := m.getGroupByMachine(providerID)
+	if err != nil {
+		return false, err
+	}
+
+	if _, ok := machine.GetAnnotations()[detachedAnnotation]; ok {
+		return true, fmt.Errorf("machine %v is already detached from %v", machine.GetName(), group.nodeGroup)
+	}
+
+	machineLabels := machine.GetLabels()
+	removedLabels := make(map[string]string)
+	for key := range group.labelSelector.MatchLabels {
+		if key == clusterNameLabel {
+			continue
+		}
+		if value, ok := machineLabels[key]; ok {
+			removedLabels[key] = value
+			delete(machineLabels, key)
+		}
+	}
+	if len(removedLabels) == 0 {
+		return false, fmt.Errorf("machine %v can't be detached from %v: the selector has no labels which can be removed", machine.GetName(), group.nodeGroup)
+	}
+
+	rawRemovedLabels, err := json.Marshal(removedLabels)
+	if err != nil {
+		return false, err
+	}
+
+	annotations := machine.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[detachedAnnotation] = string(rawRemovedLabels)
+
+	var ownerReferences []metav1.OwnerReference
+	for _, ref := range machine.GetOwnerReferences() {
+		if ref.Kind != machineSetKind {
+			ownerReferences = append(ownerReferences, ref)
+		}
+	}
+
+	machine.SetLabels(machineLabels)
+	machine.SetAnnotations(annotations)
+	machine.SetOwnerReferences(ownerReferences)
+
+	return false, m.client.Update(context.TODO(), machine)
+}
+
+// AttachInstance restores the selector labels of a detached Machine so its MachineSet adopts it again, and
+// increases the replicas of the node group by one so the Machine isn't scaled down straight away, the same as
+// attaching an instance to an auto scaling group.
+func (m *machineGroups) AttachInstance(providerID, nodeGroup string) (alreadyAttached bool, err error) {
+	group, err := m.getGroupByName(nodeGroup)
+	if err != nil {
+		return false, err
+	}
+
+	machines := newList(machineKind)
+	if err := m.client.List(context.TODO(), machines, client.InNamespace(group.owner.GetNamespace())); err != nil {
+		return false, err
+	}
+
+	var machine *unstructured.Unstructured
+	for i := range machines.Items {
+		if machineProviderID(&machines.Items[i]) == providerID {
+			machine = &machines.Items[i]
+			break
+		}
+	}
+	if machine == nil {
+		return false, fmt.Errorf("failed to find machine for instance: %v", providerID)
+	}
+
+	annotations := machine.GetAnnotations()
+	rawRemovedLabels, ok := annotations[detachedAnnotation]
+	if !ok {
+		return true, fmt.Errorf("machine %v is already attached to %v", machine.GetName(), nodeGroup)
+	}
+
+	removedLabels := make(map[string]string)
+	if err := json.Unmarshal([]byte(rawRemovedLabels), &removedLabels); err != nil {
+		return false, err
+	}
+
+	machineLabels := machine.GetLabels()
+	if machineLabels == nil {
+		machineLabels = make(map[string]string)
+	}
+	for key, value := range removedLabels {
+		machineLabels[key] = value
+	}
+	delete(annotations, detachedAnnotation)
+
+	machine.SetLabels(machineLabels)
+	machine.SetAnnotations(annotations)
+	if err := m.client.Update(context.TODO(), machine); err != nil {
+		return false, err
+	}
+
+	// Fetch the latest version of the owner, as other Machines may have been attached in the meantime
+	owner := newObject(group.owner.GetKind())
+	if err := m.client.Get(context.TODO(), client.ObjectKeyFromObject(group.owner), owner); err != nil {
+		return false, err
+	}
+
+	replicas, _, err := unstructured.NestedInt64(owner.Object, "spec", "replicas")
+	if err != nil {
+		return false, err
+	}
+	if err := unstructured.SetNestedField(owner.Object, replicas+1, "spec", "replicas"); err != nil {
+		return false, err
+	}
+
+	return false, m.client.Update(context.TODO(), owner)
+}
+
+// machineOutOfDate returns whether the Machine was created from a different template to the current one
+func (g *machineGroup) machineOutOfDate(machine *unstructured.Unstructured) bool {
+	if g.templateHash != "" && machine.GetLabels()[templateHashLabel] != g.templateHash {
+		return true
+	}
+
+	if g.version != "" {
+		version, _, _ := unstructured.NestedString(machine.Object, "spec", "version")
+		return version != g.version
+	}
+
+	return false
+}
+
+// ID returns the name of the Machine
+func (i *instance) ID() string {
+	return i.machine.GetName()
+}
+
+// String returns the name of the Machine
+func (i *instance) String() string {
+	return i.ID()
+}
+
+// OutOfDate returns if the Machine is out of date from the current template of its node group
+func (i *instance) OutOfDate() bool {
+	return i.outOfDate
+}
+
+// MatchesProviderID returns if the Machine matches the providerID
+func (i *instance) MatchesProviderID(providerID string) bool {
+	id := machineProviderID(i.machine)
+	return id != "" && id == providerID
+}
+
+// NodeGroupName returns cloud provider node group name for the instance
+func (i *instance) NodeGroupName() string {
+	return i.nodeGroupName
+}
@@ -0,0 +1,245 @@
+package clusterapi
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	testNamespace  = "default"
+	deploymentName = "cluster.x-k8s.io/deployment-name"
+)
+
+func newTestMachineDeployment(name, revision string, replicas int64) *unstructured.Unstructured {
+	u := newObject(machineDeploymentKind)
+	u.SetNamespace(testNamespace)
+	u.SetName(name)
+	u.SetAnnotations(map[string]string{revisionAnnotation: revision})
+	u.Object["spec"] = map[string]interface{}{
+		"replicas": replicas,
+		"selector": map[string]interface{}{
+			"matchLabels": map[string]interface{}{
+				clusterNameLabel: "test",
+				deploymentName:   name,
+			},
+		},
+	}
+	return u
+}
+
+func newTestMachineSet(name, deployment, revision, hash string) *unstructured.Unstructured {
+	u := newObject(machineSetKind)
+	u.SetNamespace(testNamespace)
+	u.SetName(name)
+	u.SetLabels(map[string]string{clusterNameLabel: "test", deploymentName: deployment, templateHashLabel: hash})
+	u.SetAnnotations(map[string]string{revisionAnnotation: revision})
+	u.Object["spec"] = map[string]interface{}{
+		"selector": map[string]interface{}{
+			"matchLabels": map[string]interface{}{
+				clusterNameLabel:  "test",
+				deploymentName:    deployment,
+				templateHashLabel: hash,
+			},
+		},
+		"template": map[string]interface{}{
+			"spec": map[string]interface{}{
+				"version": "v1.22.6",
+			},
+		},
+	}
+	return u
+}
+
+func newTestMachine(name, deployment, machineSet, hash, providerID, phase string) *unstructured.Unstructured {
+	u := newObject(machineKind)
+	u.SetNamespace(testNamespace)
+	u.SetName(name)
+	u.SetLabels(map[string]string{clusterNameLabel: "test", deploymentName: deployment, templateHashLabel: hash})
+	u.SetOwnerReferences([]metav1.OwnerReference{{APIVersion: apiVersion, Kind: machineSetKind, Name: machineSet}})
+	u.Object["spec"] = map[string]interface{}{
+		"providerID": providerID,
+		"version":    "v1.22.6",
+	}
+	u.Object["status"] = map[string]interface{}{
+		"phase": phase,
+	}
+	return u
+}
+
+func newTestProvider(objects ...runtime.Object) *provider {
+	scheme := runtime.NewScheme()
+	for _, kind := range []string{machineKind, machineSetKind, machineDeploymentKind} {
+		scheme.AddKnownTypeWithName(gvk(kind), &unstructured.Unstructured{})
+		scheme.AddKnownTypeWithName(gvk(kind+"List"), &unstructured.UnstructuredList{})
+	}
+
+	return &provider{
+		client: fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(objects...).Build(),
+		logger: logf.Log.WithName("clusterapi-test"),
+	}
+}
+
+func newTestObjects() []runtime.Object {
+	return []runtime.Object{
+		newTestMachineDeployment("workers", "2", 3),
+		newTestMachineSet("workers-old", "workers", "1", "old"),
+		newTestMachineSet("workers-new", "workers", "2", "new"),
+		newTestMachine("workers-old-a", "workers", "workers-old", "old", "docker:////workers-old-a", machinePhaseRunning),
+		newTestMachine("workers-new-a", "workers", "workers-new", "new", "docker:////workers-new-a", machinePhaseRunning),
+		newTestMachine("workers-new-b", "workers", "workers-new", "new", "", "Provisioning"),
+		newTestMachine("other-a", "other", "other-new", "other", "docker:////other-a", machinePhaseRunning),
+	}
+}
+
+func Test_parseNodeGroupName(t *testing.T) {
+	tests := []struct {
+		name          string
+		nodeGroupName string
+		kind          string
+		namespace     string
+		ngName        string
+		wantErr       bool
+	}{
+		{"defaults to machine deployment", "default/workers", machineDeploymentKind, "default", "workers", false},
+		{"explicit machine deployment", "MachineDeployment/default/workers", machineDeploymentKind, "default", "workers", false},
+		{"machine set", "MachineSet/default/workers-abc", machineSetKind, "default", "workers-abc", false},
+		{"missing namespace", "workers", "", "", "", true},
+		{"unknown kind", "Machine/default/workers", "", "", "", true},
+		{"empty name", "default/", "", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kind, namespace, name, err := parseNodeGroupName(tt.nodeGroupName)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.kind, kind)
+			assert.Equal(t, tt.namespace, namespace)
+			assert.Equal(t, tt.ngName, name)
+		})
+	}
+}
+
+func TestGetNodeGroups_MachineDeployment(t *testing.T) {
+	p := newTestProvider(newTestObjects()...)
+
+	nodeGroups, err := p.GetNodeGroups([]string{"default/workers"})
+	assert.NoError(t, err)
+
+	instances := nodeGroups.Instances()
+	assert.Len(t, instances, 3)
+
+	old := instances["docker:////workers-old-a"]
+	if assert.NotNil(t, old) {
+		assert.Equal(t, "workers-old-a", old.ID())
+		assert.True(t, old.OutOfDate())
+		assert.Equal(t, "default/workers", old.NodeGroupName())
+		assert.True(t, old.MatchesProviderID("docker:////workers-old-a"))
+	}
+
+	current := instances["docker:////workers-new-a"]
+	if assert.NotNil(t, current) {
+		assert.False(t, current.OutOfDate())
+	}
+
+	// Machines which are still provisioning have no provider ID and are not ready
+	provisioning := instances["clusterapi://default/workers-new-b"]
+	if assert.NotNil(t, provisioning) {
+		assert.False(t, provisioning.MatchesProviderID(""))
+	}
+	assert.Len(t, nodeGroups.ReadyInstances(), 2)
+	assert.Len(t, nodeGroups.NotReadyInstances(), 1)
+	assert.Contains(t, nodeGroups.NotReadyInstances(), "clusterapi://default/workers-new-b")
+}
+
+func TestGetNodeGroups_MachineSet(t *testing.T) {
+	p := newTestProvider(newTestObjects()...)
+
+	nodeGroups, err := p.GetNodeGroups([]string{"MachineSet/default/workers-old"})
+	assert.NoError(t, err)
+
+	instances := nodeGroups.Instances()
+	assert.Len(t, instances, 1)
+	assert.False(t, instances["docker:////workers-old-a"].OutOfDate())
+}
+
+func TestDetachAndAttachInstance(t *testing.T) {
+	p := newTestProvider(newTestObjects()...)
+
+	nodeGroups, err := p.GetNodeGroups([]string{"default/workers"})
+	assert.NoError(t, err)
+
+	alreadyDetaching, err := nodeGroups.DetachInstance("docker:////workers-old-a")
+	assert.NoError(t, err)
+	assert.False(t, alreadyDetaching)
+
+	// The Machine is orphaned from the MachineSet, but keeps the cluster name label
+	machine := newObject(machineKind)
+	assert.NoError(t, p.client.Get(context.TODO(), client.ObjectKey{Namespace: testNamespace, Name: "workers-old-a"}, machine))
+	assert.NotContains(t, machine.GetLabels(), deploymentName)
+	assert.Equal(t, "test", machine.GetLabels()[clusterNameLabel])
+	assert.Empty(t, machine.GetOwnerReferences())
+
+	// The Machine is no longer part of the node group
+	nodeGroups, err = p.GetNodeGroups([]string{"default/workers"})
+	assert.NoError(t, err)
+	assert.Len(t, nodeGroups.Instances(), 2)
+
+	// Re-attaching restores the labels and scales the MachineDeployment up by one
+	alreadyAttached, err := nodeGroups.AttachInstance("docker:////workers-old-a", "default/workers")
+	assert.NoError(t, err)
+	assert.False(t, alreadyAttached)
+
+	assert.NoError(t, p.client.Get(context.TODO(), client.ObjectKey{Namespace: testNamespace, Name: "workers-old-a"}, machine))
+	assert.Equal(t, "workers", machine.GetLabels()[deploymentName])
+	assert.NotContains(t, machine.GetAnnotations(), detachedAnnotation)
+
+	deployment := newObject(machineDeploymentKind)
+	assert.NoError(t, p.client.Get(context.TODO(), client.ObjectKey{Namespace: testNamespace, Name: "workers"}, deployment))
+	replicas, _, _ := unstructured.NestedInt64(deployment.Object, "spec", "replicas")
+	assert.Equal(t, int64(4), replicas)
+
+	alreadyAttached, err = nodeGroups.AttachInstance("docker:////workers-old-a", "default/workers")
+	assert.Error(t, err)
+	assert.True(t, alreadyAttached)
+}
+
+func TestDetachInstance_AlreadyDetaching(t *testing.T) {
+	p := newTestProvider(newTestObjects()...)
+
+	nodeGroups, err := p.GetNodeGroups([]string{"default/workers"})
+	assert.NoError(t, err)
+
+	_, err = nodeGroups.DetachInstance("docker:////workers-new-a")
+	assert.NoError(t, err)
+
+	alreadyDetaching, err := nodeGroups.DetachInstance("docker:////workers-new-a")
+	assert.Error(t, err)
+	assert.True(t, alreadyDetaching)
+}
+
+func TestInstancesExistAndTerminateInstance(t *testing.T) {
+	p := newTestProvider(newTestObjects()...)
+
+	valid, err := p.InstancesExist([]string{"docker:////workers-old-a", "docker:////missing"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"docker:////workers-old-a"}, valid)
+
+	assert.NoError(t, p.TerminateInstance("docker:////workers-old-a"))
+	assert.Error(t, p.TerminateInstance("docker:////missing"))
+
+	valid, err = p.InstancesExist([]string{"docker:////workers-old-a"})
+	assert.NoError(t, err)
+	assert.Empty(t, valid)
+}
@@ -0,0 +1,55 @@
+package fake
+
+import (
+	"os"
+	"time"
+
+	"github.com/atlassian-labs/cyclops/pkg/cloudprovider"
+	"github.com/atlassian-labs/cyclops/pkg/k8s"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	namespaceEnv     = "FAKE_CLOUD_PROVIDER_NAMESPACE"
+	defaultNamespace = "kube-system"
+)
+
+var log = logf.Log.WithName("fake")
+
+// NewCloudProvider returns a new fake cloud provider, which stores its state in a ConfigMap in the
+// namespace given by FAKE_CLOUD_PROVIDER_NAMESPACE, defaulting to kube-system
+func NewCloudProvider() (cloudprovider.CloudProvider, error) {
+	config, err := k8s.GetConfig("")
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := client.New(config, client.Options{})
+	if err != nil {
+		return nil, err
+	}
+
+	namespace := os.Getenv(namespaceEnv)
+	if namespace == "" {
+		namespace = defaultNamespace
+	}
+
+	p := &provider{
+		client:    c,
+		namespace: namespace,
+		logger:    log,
+	}
+
+	// Replacement nodes have no kubelet, so keep them Ready for as long as Cyclops is running
+	go wait.Forever(func() {
+		if err := p.refreshHeartbeats(time.Now()); err != nil {
+			log.Error(err, "unable to refresh heartbeats of replacement nodes")
+		}
+	}, heartbeatInterval)
+
+	log.Info("fake cloud provider created successfully", "configmap", namespace+"/"+ConfigMapName)
+
+	return p, nil
+}
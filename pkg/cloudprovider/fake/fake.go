@@ -0,0 +1,553 @@
+package fake
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/atlassian-labs/cyclops/pkg/cloudprovider"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// ProviderName is the name of the provider
+	ProviderName = "fake"
+
+	// NodeGroupLabel is the label on nodes which places them in a fake node group
+	NodeGroupLabel = "cyclops.atlassian.com/fake-node-group"
+
+	// ConfigMapName is the name of the ConfigMap which stores the state of the fake cloud
+	ConfigMapName = "cyclops-fake-cloud-provider"
+
+	stateKey         = "state"
+	defaultTemplate  = "1"
+	providerIDPrefix = "fake://"
+
+	// heartbeatInterval is how often the Ready condition of replacement nodes is refreshed. It is well within the
+	// default 40s grace period of the node lifecycle controller.
+	heartbeatInterval = 10 * time.Second
+
+	// heartbeatReason is the reason of the Ready condition of nodes created by the fake cloud provider
+	heartbeatReason = "FakeCloudProvider"
+
+	// cycleNodeLabel is added to nodes being cycled and must not be copied to replacements
+	cycleNodeLabel = "cyclops.atlassian.com/terminate"
+)
+
+var invalidNodeNameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// state is the state of the fake cloud, stored as JSON in the ConfigMap
+type state struct {
+	NodeGroups map[string]*nodeGroupState `json:"nodeGroups"`
+	Instances  map[string]*instanceState  `json:"instances"`
+}
+
+// nodeGroupState is the state of a node group. Changing the template makes all instances
+// launched with a different template out of date.
+type nodeGroupState struct {
+	Template string `json:"template"`
+}
+
+// instanceState is the state of an instance, keyed by providerID
+type instanceState struct {
+	NodeGroup string `json:"nodeGroup"`
+	Template  string `json:"template"`
+	Detached  bool   `json:"detached"`
+}
+
+type provider struct {
+	client    client.Client
+	namespace string
+	logger    logr.Logger
+}
+
+type nodeGroups struct {
+	provider *provider
+	names    []string
+	nodes    map[string][]corev1.Node
+	state    *state
+}
+
+type instance struct {
+	name          string
+	providerID    string
+	nodeGroupName string
+	outOfDate     bool
+}
+
+// Name returns the name of the cloud provider
+func (p *provider) Name() string {
+	return ProviderName
+}
+
+// readState reads the state of the fake cloud from the ConfigMap, returning an empty state if it doesn't exist
+func (p *provider) readState() (*state, *corev1.ConfigMap, error) {
+	s := &state{
+		NodeGroups: make(map[string]*nodeGroupState),
+		Instances:  make(map[string]*instanceState),
+	}
+
+	cm := &corev1.ConfigMap{}
+	err := p.client.Get(context.TODO(), client.ObjectKey{Namespace: p.namespace, Name: ConfigMapName}, cm)
+	if errors.IsNotFound(err) {
+		return s, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if raw, ok := cm.Data[stateKey]; ok && raw != "" {
+		if err := json.Unmarshal([]byte(raw), s); err != nil {
+			return nil, nil, fmt.Errorf("failed to parse state in configmap %v/%v: %v", p.namespace, ConfigMapName, err)
+		}
+	}
+	if s.NodeGroups == nil {
+		s.NodeGroups = make(map[string]*nodeGroupState)
+	}
+	if s.Instances == nil {
+		s.Instances = make(map[string]*instanceState)
+	}
+
+	return s, cm, nil
+}
+
+// updateState reads the state, applies the update and writes it back to the ConfigMap, retrying on conflicts
+func (p *provider) updateState(update func(*state) error) (*state, error) {
+	var s *state
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var cm *corev1.ConfigMap
+		var err error
+		s, cm, err = p.readState()
+		if err != nil {
+			return err
+		}
+
+		if err := update(s); err != nil {
+			return err
+		}
+
+		raw, err := json.Marshal(s)
+		if err != nil {
+			return err
+		}
+
+		if cm == nil {
+			return p.client.Create(context.TODO(), &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Namespace: p.namespace, Name: ConfigMapName},
+				Data:       map[string]string{stateKey: string(raw)},
+			})
+		}
+
+		if cm.Data == nil {
+			cm.Data = make(map[string]string)
+		}
+		cm.Data[stateKey] = string(raw)
+		return p.client.Update(context.TODO(), cm)
+	})
+	return s, err
+}
+
+// listNodes lists the nodes with the node group label
+func (p *provider) listNodes() ([]corev1.Node, error) {
+	var nodeList corev1.NodeList
+	if err := p.client.List(context.TODO(), &nodeList, client.HasLabels{NodeGroupLabel}); err != nil {
+		return nil, err
+	}
+	return nodeList.Items, nil
+}
+
+// GetNodeGroups gets the node groups. The instances of a node group are the nodes labelled with its name,
+// and any that haven't been seen before are recorded in the state with the current template of the node group. The
+// state is only written when there is something new to record, so that reading the node groups doesn't update the
+// ConfigMap every time.
+func (p *provider) GetNodeGroups(names []string) (cloudprovider.NodeGroups, error) {
+	nodes, err := p.listNodes()
+	if err != nil {
+		return nil, err
+	}
+
+	groupNodes := make(map[string][]corev1.Node)
+	for _, name := range names {
+		groupNodes[name] = nil
+	}
+	for _, node := range nodes {
+		name := node.Labels[NodeGroupLabel]
+		if _, ok := groupNodes[name]; ok && node.Spec.ProviderID != "" {
+			groupNodes[name] = append(groupNodes[name], node)
+		}
+	}
+
+	record := func(s *state) (changed bool) {
+		for name, nodes := range groupNodes {
+			group, ok := s.NodeGroups[name]
+			if !ok {
+				group = &nodeGroupState{Template: defaultTemplate}
+				s.NodeGroups[name] = group
+				changed = true
+			}
+			for _, node := range nodes {
+				if _, ok := s.Instances[node.Spec.ProviderID]; !ok {
+					s.Instances[node.Spec.ProviderID] = &instanceState{NodeGroup: name, Template: group.Template}
+					changed = true
+				}
+			}
+		}
+		return changed
+	}
+
+	s, _, err := p.readState()
+	if err != nil {
+		return nil, err
+	}
+	if record(s) {
+		s, err = p.updateState(func(s *state) error {
+			record(s)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &nodeGroups{
+		provider: p,
+		names:    names,
+		nodes:    groupNodes,
+		state:    s,
+	}, nil
+}
+
+// InstancesExist returns a list of the instances that exist, either as a node or in the state of the fake cloud
+func (p *provider) InstancesExist(providerIDs []string) (validProviderIDs []string, err error) {
+	s, _, err := p.readState()
+	if err != nil {
+		return nil, err
+	}
+
+	nodes, err := p.listNodes()
+	if err != nil {
+		return nil, err
+	}
+
+	existing := make(map[string]bool)
+	for providerID := range s.Instances {
+		existing[providerID] = true
+	}
+	for _, node := range nodes {
+		existing[node.Spec.ProviderID] = true
+	}
+
+	for _, providerID := range providerIDs {
+		if existing[providerID] {
+			validProviderIDs = append(validProviderIDs, providerID)
+		}
+	}
+
+	return validProviderIDs, nil
+}
+
+// ReplacesTerminatedInstances returns true, as instances that weren't detached are replaced when terminated
+func (p *provider) ReplacesTerminatedInstances() bool {
+	return true
+}
+
+// TerminateInstance removes the instance from the state and deletes its node if it still exists. Instances created
+// by the fake cloud provider that were not detached are replaced, like a cloud provider keeping the node group at its
+// desired capacity. Other nodes are registered again by their kubelet.
+func (p *provider) TerminateInstance(providerID string) error {
+	nodes, err := p.listNodes()
+	if err != nil {
+		return err
+	}
+
+	var template *corev1.Node
+	for i := range nodes {
+		if nodes[i].Spec.ProviderID == providerID {
+			template = &nodes[i]
+			if err := p.client.Delete(context.TODO(), &nodes[i]); err != nil && !errors.IsNotFound(err) {
+				return err
+			}
+			break
+		}
+	}
+
+	var nodeGroup string
+	_, err = p.updateState(func(s *state) error {
+		if st, ok := s.Instances[providerID]; ok && !st.Detached && strings.HasPrefix(providerID, providerIDPrefix) {
+			nodeGroup = st.NodeGroup
+		}
+		delete(s.Instances, providerID)
+		return nil
+	})
+	if err != nil || nodeGroup == "" {
+		return err
+	}
+
+	// The node is usually deleted before the instance is terminated, so use another node in the group as the
+	// template for the replacement
+	for i := range nodes {
+		if template != nil {
+			break
+		}
+		if nodes[i].Labels[NodeGroupLabel] == nodeGroup {
+			template = &nodes[i]
+		}
+	}
+	if template == nil {
+		template = &corev1.Node{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{NodeGroupLabel: nodeGroup}}}
+	}
+
+	replacementName, replacementProviderID := newReplacementName(nodeGroup)
+	_, err = p.updateState(func(s *state) error {
+		s.Instances[replacementProviderID] = &instanceState{NodeGroup: nodeGroup, Template: s.NodeGroups[nodeGroup].Template}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return p.client.Create(context.TODO(), newReplacementNode(template, replacementName, replacementProviderID))
+}
+
+// instances returns a map of the instances in the node groups that have not been detached and match the filter
+// with providerID as key and cloudprovider.Instance as value
+func (n *nodeGroups) instances(filter func(*corev1.Node) bool) map[string]cloudprovider.Instance {
+	instances := make(map[string]cloudprovider.Instance)
+	for _, name := range n.names {
+		for i := range n.nodes[name] {
+			node := &n.nodes[name][i]
+			st, ok := n.state.Instances[node.Spec.ProviderID]
+			if !ok || st.Detached || !filter(node) {
+				continue
+			}
+			instances[node.Spec.ProviderID] = &instance{
+				name:          node.Name,
+				providerID:    node.Spec.ProviderID,
+				nodeGroupName: name,
+				outOfDate:     st.Template != n.state.NodeGroups[name].Template,
+			}
+		}
+	}
+	return instances
+}
+
+// Instances returns a map of all instances in the node groups
+// with providerID as key and cloudprovider.Instance as value
+func (n *nodeGroups) Instances() map[string]cloudprovider.Instance {
+	return n.instances(func(*corev1.Node) bool {
+		return true
+	})
+}
+
+// ReadyInstances returns a map of instances whose node is ready
+// with providerID as key and cloudprovider.Instance as value
+func (n *nodeGroups) ReadyInstances() map[string]cloudprovider.Instance {
+	return n.instances(nodeReady)
+}
+
+// NotReadyInstances returns a map of instances whose node is not ready
+// with providerID as key and cloudprovider.Instance as value
+func (n *nodeGroups) NotReadyInstances() map[string]cloudprovider.Instance {
+	return n.instances(func(node *corev1.Node) bool {
+		return !nodeReady(node)
+	})
+}
+
+// getNodeByProviderID finds the node and node group of an instance
+func (n *nodeGroups) getNodeByProviderID(providerID string) (string, *corev1.Node, error) {
+	for _, name := range n.names {
+		for i := range n.nodes[name] {
+			if n.nodes[name][i].Spec.ProviderID == providerID {
+				return name, &n.nodes[name][i], nil
+			}
+		}
+	}
+	return "", nil, fmt.Errorf("failed to find target node group for instance: %v", providerID)
+}
+
+// DetachInstance marks the instance as detached and launches a replacement by creating a new node in the node
+// group, copying the labels and capacity of the detached node
+func (n *nodeGroups) DetachInstance(providerID string) (alreadyDetaching bool, err error) {
+	nodeGroup, node, err := n.getNodeByProviderID(providerID)
+	if err != nil {
+		return false, err
+	}
+
+	replacementName, replacementProviderID := newReplacementName(nodeGroup)
+
+	s, err := n.provider.updateState(func(s *state) error {
+		st, ok := s.Instances[providerID]
+		if !ok {
+			return fmt.Errorf("instance %v does not exist", providerID)
+		}
+		if st.Detached {
+			alreadyDetaching = true
+			return fmt.Errorf("instance %v is already detached from %v", providerID, nodeGroup)
+		}
+		st.Detached = true
+		s.Instances[replacementProviderID] = &instanceState{NodeGroup: nodeGroup, Template: s.NodeGroups[nodeGroup].Template}
+		return nil
+	})
+	if err != nil {
+		return alreadyDetaching, err
+	}
+	n.state = s
+
+	return false, n.provider.client.Create(context.TODO(), newReplacementNode(node, replacementName, replacementProviderID))
+}
+
+// AttachInstance marks the instance as attached to its node group again
+func (n *nodeGroups) AttachInstance(providerID, nodeGroup string) (alreadyAttached bool, err error) {
+	s, err := n.provider.updateState(func(s *state) error {
+		st, ok := s.Instances[providerID]
+		if !ok {
+			return fmt.Errorf("instance %v does not exist", providerID)
+		}
+		if st.NodeGroup != nodeGroup {
+			return fmt.Errorf("instance %v is in %v and cannot be attached to %v", providerID, st.NodeGroup, nodeGroup)
+		}
+		if !st.Detached {
+			alreadyAttached = true
+			return fmt.Errorf("instance %v is already attached to %v", providerID, nodeGroup)
+		}
+		st.Detached = false
+		return nil
+	})
+	if err != nil {
+		return alreadyAttached, err
+	}
+	n.state = s
+
+	return false, nil
+}
+
+// newReplacementName generates the name and provider ID of a replacement node in the node group
+func newReplacementName(nodeGroup string) (name, providerID string) {
+	name = fmt.Sprintf("%s-%s", strings.Trim(invalidNodeNameChars.ReplaceAllString(strings.ToLower(nodeGroup), "-"), "-"), rand.String(5))
+	return name, providerIDPrefix + name
+}
+
+// newReplacementNode builds a ready node to replace the detached node
+func newReplacementNode(detached *corev1.Node, name, providerID string) *corev1.Node {
+	labels := make(map[string]string)
+	for key, value := range detached.Labels {
+		labels[key] = value
+	}
+	delete(labels, cycleNodeLabel)
+	labels[corev1.LabelHostname] = name
+
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: labels,
+		},
+		Spec: corev1.NodeSpec{
+			ProviderID: providerID,
+		},
+		Status: corev1.NodeStatus{
+			Capacity:    detached.Status.Capacity,
+			Allocatable: detached.Status.Allocatable,
+		},
+	}
+	setReplacementReady(node, time.Now())
+	return node
+}
+
+// refreshHeartbeats stands in for the kubelet of the replacement nodes created by the fake cloud provider. It
+// refreshes the heartbeat of their Ready condition, and makes them Ready again if the node lifecycle controller has
+// marked them otherwise, so that they aren't tainted and treated as unhealthy. Nodes with a real kubelet are left alone.
+func (p *provider) refreshHeartbeats(now time.Time) error {
+	nodes, err := p.listNodes()
+	if err != nil {
+		return err
+	}
+
+	for _, node := range nodes {
+		if !strings.HasPrefix(node.Spec.ProviderID, providerIDPrefix) {
+			continue
+		}
+
+		err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			var latest corev1.Node
+			if err := p.client.Get(context.TODO(), client.ObjectKey{Name: node.Name}, &latest); err != nil {
+				return err
+			}
+			setReplacementReady(&latest, now)
+			return p.client.Status().Update(context.TODO(), &latest)
+		})
+		if err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to refresh heartbeat of node %v: %v", node.Name, err)
+		}
+	}
+	return nil
+}
+
+// setReplacementReady sets the Ready condition of a replacement node with a heartbeat at now
+func setReplacementReady(node *corev1.Node, now time.Time) {
+	heartbeat := metav1.NewTime(now)
+	for i, cond := range node.Status.Conditions {
+		if cond.Type != corev1.NodeReady {
+			continue
+		}
+		if cond.Status != corev1.ConditionTrue {
+			node.Status.Conditions[i].Status = corev1.ConditionTrue
+			node.Status.Conditions[i].LastTransitionTime = heartbeat
+		}
+		node.Status.Conditions[i].Reason = heartbeatReason
+		node.Status.Conditions[i].Message = "node created by the fake cloud provider"
+		node.Status.Conditions[i].LastHeartbeatTime = heartbeat
+		return
+	}
+	node.Status.Conditions = append(node.Status.Conditions, corev1.NodeCondition{
+		Type:               corev1.NodeReady,
+		Status:             corev1.ConditionTrue,
+		Reason:             heartbeatReason,
+		Message:            "node created by the fake cloud provider",
+		LastHeartbeatTime:  heartbeat,
+		LastTransitionTime: heartbeat,
+	})
+}
+
+// nodeReady returns whether the node has the Ready condition
+func nodeReady(node *corev1.Node) bool {
+	for _, cond := range node.Status.Conditions {
+		if cond.Type == corev1.NodeReady && cond.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
+
+// ID returns the name of the node of the instance
+func (i *instance) ID() string {
+	return i.name
+}
+
+// String returns the name of the node of the instance
+func (i *instance) String() string {
+	return i.ID()
+}
+
+// OutOfDate returns if the instance was launched with a different template to the current node group template
+func (i *instance) OutOfDate() bool {
+	return i.outOfDate
+}
+
+// MatchesProviderID returns if the instance matches the providerID
+func (i *instance) MatchesProviderID(providerID string) bool {
+	return i.providerID == providerID
+}
+
+// NodeGroupName returns cloud provider node group name for the instance
+func (i *instance) NodeGroupName() string {
+	return i.nodeGroupName
+}
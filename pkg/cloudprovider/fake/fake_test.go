@@ -0,0 +1,261 @@
+package fake
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func newTestNode(name, nodeGroup string, ready bool) *corev1.Node {
+	status := corev1.ConditionTrue
+	if !ready {
+		status = corev1.ConditionFalse
+	}
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+			Labels: map[string]string{
+				NodeGroupLabel:       nodeGroup,
+				corev1.LabelHostname: name,
+				"role":               "worker",
+			},
+		},
+		Spec: corev1.NodeSpec{
+			ProviderID: "kind://docker/kind/" + name,
+		},
+		Status: corev1.NodeStatus{
+			Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: status}},
+		},
+	}
+}
+
+func newTestProvider(objects ...runtime.Object) *provider {
+	return &provider{
+		client:    fakeclient.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(objects...).Build(),
+		namespace: "kube-system",
+		logger:    logf.Log.WithName("fake-test"),
+	}
+}
+
+func TestGetNodeGroups(t *testing.T) {
+	p := newTestProvider(
+		newTestNode("worker-1", "workers", true),
+		newTestNode("worker-2", "workers", false),
+		newTestNode("other-1", "other", true),
+	)
+
+	nodeGroups, err := p.GetNodeGroups([]string{"workers"})
+	assert.NoError(t, err)
+
+	instances := nodeGroups.Instances()
+	assert.Len(t, instances, 2)
+	if instance := instances["kind://docker/kind/worker-1"]; assert.NotNil(t, instance) {
+		assert.Equal(t, "worker-1", instance.ID())
+		assert.Equal(t, "workers", instance.NodeGroupName())
+		assert.False(t, instance.OutOfDate())
+		assert.True(t, instance.MatchesProviderID("kind://docker/kind/worker-1"))
+	}
+	assert.Len(t, nodeGroups.ReadyInstances(), 1)
+	assert.Contains(t, nodeGroups.NotReadyInstances(), "kind://docker/kind/worker-2")
+
+	// The state is stored in the ConfigMap
+	s, cm, err := p.readState()
+	assert.NoError(t, err)
+	assert.NotNil(t, cm)
+	assert.Equal(t, defaultTemplate, s.NodeGroups["workers"].Template)
+	assert.Len(t, s.Instances, 2)
+}
+
+func TestGetNodeGroups_OnlyWritesNewInstances(t *testing.T) {
+	p := newTestProvider(newTestNode("worker-1", "workers", true))
+
+	_, err := p.GetNodeGroups([]string{"workers"})
+	assert.NoError(t, err)
+	var cm corev1.ConfigMap
+	assert.NoError(t, p.client.Get(context.TODO(), client.ObjectKey{Namespace: "kube-system", Name: ConfigMapName}, &cm))
+	resourceVersion := cm.ResourceVersion
+
+	// Nothing new was seen, so the state isn't written again
+	_, err = p.GetNodeGroups([]string{"workers"})
+	assert.NoError(t, err)
+	assert.NoError(t, p.client.Get(context.TODO(), client.ObjectKey{Namespace: "kube-system", Name: ConfigMapName}, &cm))
+	assert.Equal(t, resourceVersion, cm.ResourceVersion)
+
+	// A new node is recorded
+	assert.NoError(t, p.client.Create(context.TODO(), newTestNode("worker-2", "workers", true)))
+	nodeGroups, err := p.GetNodeGroups([]string{"workers"})
+	assert.NoError(t, err)
+	assert.Len(t, nodeGroups.Instances(), 2)
+	assert.NoError(t, p.client.Get(context.TODO(), client.ObjectKey{Namespace: "kube-system", Name: ConfigMapName}, &cm))
+	assert.NotEqual(t, resourceVersion, cm.ResourceVersion)
+	assert.Contains(t, cm.Data[stateKey], "kind://docker/kind/worker-2")
+}
+
+func TestOutOfDate(t *testing.T) {
+	p := newTestProvider(newTestNode("worker-1", "workers", true))
+
+	_, err := p.GetNodeGroups([]string{"workers"})
+	assert.NoError(t, err)
+
+	// Changing the template of the node group makes the existing instances out of date
+	_, err = p.updateState(func(s *state) error {
+		s.NodeGroups["workers"].Template = "2"
+		return nil
+	})
+	assert.NoError(t, err)
+
+	nodeGroups, err := p.GetNodeGroups([]string{"workers"})
+	assert.NoError(t, err)
+	assert.True(t, nodeGroups.Instances()["kind://docker/kind/worker-1"].OutOfDate())
+}
+
+func TestDetachAttachAndTerminate(t *testing.T) {
+	p := newTestProvider(newTestNode("worker-1", "workers", true))
+
+	nodeGroups, err := p.GetNodeGroups([]string{"workers"})
+	assert.NoError(t, err)
+
+	alreadyDetaching, err := nodeGroups.DetachInstance("kind://docker/kind/worker-1")
+	assert.NoError(t, err)
+	assert.False(t, alreadyDetaching)
+
+	alreadyDetaching, err = nodeGroups.DetachInstance("kind://docker/kind/worker-1")
+	assert.Error(t, err)
+	assert.True(t, alreadyDetaching)
+
+	// A ready replacement node was created in the node group, and the detached node is no longer an instance
+	var nodeList corev1.NodeList
+	assert.NoError(t, p.client.List(context.TODO(), &nodeList))
+	assert.Len(t, nodeList.Items, 2)
+
+	nodeGroups, err = p.GetNodeGroups([]string{"workers"})
+	assert.NoError(t, err)
+	instances := nodeGroups.ReadyInstances()
+	assert.Len(t, instances, 1)
+	for providerID, instance := range instances {
+		assert.Contains(t, providerID, providerIDPrefix+"workers-")
+		assert.False(t, instance.OutOfDate())
+
+		var replacement corev1.Node
+		assert.NoError(t, p.client.Get(context.TODO(), client.ObjectKey{Name: instance.ID()}, &replacement))
+		assert.Equal(t, "worker", replacement.Labels["role"])
+		assert.Equal(t, instance.ID(), replacement.Labels[corev1.LabelHostname])
+	}
+
+	// Re-attaching makes it an instance of the node group again
+	alreadyAttached, err := nodeGroups.AttachInstance("kind://docker/kind/worker-1", "workers")
+	assert.NoError(t, err)
+	assert.False(t, alreadyAttached)
+	assert.Len(t, nodeGroups.Instances(), 2)
+
+	alreadyAttached, err = nodeGroups.AttachInstance("kind://docker/kind/worker-1", "workers")
+	assert.Error(t, err)
+	assert.True(t, alreadyAttached)
+
+	// Terminating deletes the node and removes the instance
+	assert.NoError(t, p.TerminateInstance("kind://docker/kind/worker-1"))
+	valid, err := p.InstancesExist([]string{"kind://docker/kind/worker-1"})
+	assert.NoError(t, err)
+	assert.Empty(t, valid)
+
+	var node corev1.Node
+	err = p.client.Get(context.TODO(), client.ObjectKey{Name: "worker-1"}, &node)
+	assert.Error(t, err)
+}
+
+func TestTerminateInstance_NodeAlreadyDeleted(t *testing.T) {
+	p := newTestProvider(newTestNode("worker-1", "workers", true))
+
+	_, err := p.GetNodeGroups([]string{"workers"})
+	assert.NoError(t, err)
+
+	// Cyclops deletes the node before terminating the instance
+	assert.NoError(t, p.client.Delete(context.TODO(), newTestNode("worker-1", "workers", true)))
+
+	valid, err := p.InstancesExist([]string{"kind://docker/kind/worker-1"})
+	assert.NoError(t, err)
+	assert.Len(t, valid, 1)
+
+	assert.NoError(t, p.TerminateInstance("kind://docker/kind/worker-1"))
+
+	valid, err = p.InstancesExist([]string{"kind://docker/kind/worker-1"})
+	assert.NoError(t, err)
+	assert.Empty(t, valid)
+}
+
+func TestTerminateInstance_ReplacesAttachedInstance(t *testing.T) {
+	fakeNode := newTestNode("workers-abcde", "workers", true)
+	fakeNode.Spec.ProviderID = providerIDPrefix + "workers-abcde"
+	p := newTestProvider(fakeNode, newTestNode("worker-1", "workers", true))
+
+	_, err := p.GetNodeGroups([]string{"workers"})
+	assert.NoError(t, err)
+
+	// Cyclops deletes the node before terminating the instance, the replacement is based on another node in the group
+	assert.NoError(t, p.client.Delete(context.TODO(), fakeNode))
+	assert.NoError(t, p.TerminateInstance(providerIDPrefix+"workers-abcde"))
+
+	nodeGroups, err := p.GetNodeGroups([]string{"workers"})
+	assert.NoError(t, err)
+	instances := nodeGroups.ReadyInstances()
+	assert.Len(t, instances, 2)
+	assert.NotContains(t, instances, providerIDPrefix+"workers-abcde")
+	assert.Contains(t, instances, "kind://docker/kind/worker-1")
+
+	for providerID, instance := range instances {
+		if providerID == "kind://docker/kind/worker-1" {
+			continue
+		}
+		assert.Contains(t, providerID, providerIDPrefix+"workers-")
+
+		var replacement corev1.Node
+		assert.NoError(t, p.client.Get(context.TODO(), client.ObjectKey{Name: instance.ID()}, &replacement))
+		assert.Equal(t, "worker", replacement.Labels["role"])
+	}
+
+	// kind nodes are registered again by their kubelet instead
+	assert.NoError(t, p.TerminateInstance("kind://docker/kind/worker-1"))
+	nodeGroups, err = p.GetNodeGroups([]string{"workers"})
+	assert.NoError(t, err)
+	assert.Len(t, nodeGroups.Instances(), 1)
+}
+
+func TestRefreshHeartbeats(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	stale := metav1.NewTime(now.Add(-time.Hour))
+
+	// The node lifecycle controller has marked the replacement as Unknown after the grace period
+	fakeNode := newTestNode("workers-abcde", "workers", true)
+	fakeNode.Spec.ProviderID = providerIDPrefix + "workers-abcde"
+	fakeNode.Status.Conditions = []corev1.NodeCondition{
+		{Type: corev1.NodeReady, Status: corev1.ConditionUnknown, LastHeartbeatTime: stale, LastTransitionTime: stale},
+	}
+	kindNode := newTestNode("worker-1", "workers", false)
+	p := newTestProvider(fakeNode, kindNode)
+
+	assert.NoError(t, p.refreshHeartbeats(now))
+
+	var replacement corev1.Node
+	assert.NoError(t, p.client.Get(context.TODO(), client.ObjectKey{Name: "workers-abcde"}, &replacement))
+	if assert.Len(t, replacement.Status.Conditions, 1) {
+		cond := replacement.Status.Conditions[0]
+		assert.Equal(t, corev1.ConditionTrue, cond.Status)
+		assert.Equal(t, heartbeatReason, cond.Reason)
+		assert.True(t, cond.LastHeartbeatTime.Time.Equal(now))
+		assert.True(t, cond.LastTransitionTime.Time.Equal(now))
+	}
+
+	// Nodes with a kubelet are left alone
+	var kind corev1.Node
+	assert.NoError(t, p.client.Get(context.TODO(), client.ObjectKey{Name: "worker-1"}, &kind))
+	assert.False(t, nodeReady(&kind))
+}
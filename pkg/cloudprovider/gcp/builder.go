@@ -0,0 +1,29 @@
+package gcp
+
+import (
+	"context"
+
+	"github.com/atlassian-labs/cyclops/pkg/cloudprovider"
+	"google.golang.org/api/compute/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+var log = logf.Log.WithName("gcp")
+
+// NewCloudProvider returns a new GCP cloud provider
+func NewCloudProvider() (cloudprovider.CloudProvider, error) {
+	// Uses the application default credentials to authenticate against the Compute API
+	service, err := compute.NewService(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	p := &provider{
+		computeService: &computeAPI{service: service},
+		logger:         log,
+	}
+
+	log.Info("gcp compute service created successfully")
+
+	return p, nil
+}
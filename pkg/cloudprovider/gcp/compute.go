@@ -0,0 +1,75 @@
+package gcp
+
+import (
+	"context"
+
+	"google.golang.org/api/compute/v1"
+)
+
+// computeService is the subset of the Compute API used by the provider. It allows the
+// API to be faked in tests.
+type computeService interface {
+	GetInstanceGroupManager(project, zone, name string) (*compute.InstanceGroupManager, error)
+	ListManagedInstances(project, zone, name string) ([]*compute.ManagedInstance, error)
+	AbandonInstances(project, zone, name string, instanceURLs []string) error
+	ResizeInstanceGroupManager(project, zone, name string, size int64) error
+	GetInstance(project, zone, name string) (*compute.Instance, error)
+	SetInstanceLabels(project, zone, name string, labels map[string]string, fingerprint string) error
+	DeleteInstance(project, zone, name string) error
+}
+
+// computeAPI implements computeService using the real Compute API
+type computeAPI struct {
+	service *compute.Service
+}
+
+// GetInstanceGroupManager gets a zonal managed instance group
+func (c *computeAPI) GetInstanceGroupManager(project, zone, name string) (*compute.InstanceGroupManager, error) {
+	return c.service.InstanceGroupManagers.Get(project, zone, name).Context(context.TODO()).Do()
+}
+
+// ListManagedInstances lists all of the instances managed by a zonal managed instance group
+func (c *computeAPI) ListManagedInstances(project, zone, name string) ([]*compute.ManagedInstance, error) {
+	var instances []*compute.ManagedInstance
+	err := c.service.InstanceGroupManagers.ListManagedInstances(project, zone, name).Pages(context.TODO(),
+		func(page *compute.InstanceGroupManagersListManagedInstancesResponse) error {
+			instances = append(instances, page.ManagedInstances...)
+			return nil
+		})
+	return instances, err
+}
+
+// AbandonInstances removes the instances from the managed instance group without deleting them
+func (c *computeAPI) AbandonInstances(project, zone, name string, instanceURLs []string) error {
+	_, err := c.service.InstanceGroupManagers.AbandonInstances(project, zone, name,
+		&compute.InstanceGroupManagersAbandonInstancesRequest{
+			Instances: instanceURLs,
+		}).Context(context.TODO()).Do()
+	return err
+}
+
+// ResizeInstanceGroupManager sets the target size of the managed instance group
+func (c *computeAPI) ResizeInstanceGroupManager(project, zone, name string, size int64) error {
+	_, err := c.service.InstanceGroupManagers.Resize(project, zone, name, size).Context(context.TODO()).Do()
+	return err
+}
+
+// GetInstance gets an instance
+func (c *computeAPI) GetInstance(project, zone, name string) (*compute.Instance, error) {
+	return c.service.Instances.Get(project, zone, name).Context(context.TODO()).Do()
+}
+
+// SetInstanceLabels replaces the labels of an instance. The fingerprint must be the one of the current labels
+func (c *computeAPI) SetInstanceLabels(project, zone, name string, labels map[string]string, fingerprint string) error {
+	_, err := c.service.Instances.SetLabels(project, zone, name, &compute.InstancesSetLabelsRequest{
+		Labels:           labels,
+		LabelFingerprint: fingerprint,
+	}).Context(context.TODO()).Do()
+	return err
+}
+
+// DeleteInstance deletes an instance
+func (c *computeAPI) DeleteInstance(project, zone, name string) error {
+	_, err := c.service.Instances.Delete(project, zone, name).Context(context.TODO()).Do()
+	return err
+}
@@ -0,0 +1,434 @@
+package gcp
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/atlassian-labs/cyclops/pkg/cloudprovider"
+	"github.com/go-logr/logr"
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/googleapi"
+)
+
+const (
+	// ProviderName is the name of the provider
+	ProviderName = "gcp"
+
+	instanceStatusRunning = "RUNNING"
+	currentActionNone     = "NONE"
+	currentActionAbandon  = "ABANDONING"
+
+	// detachedFromLabel and detachTargetSizeLabel are set on an instance before it is abandoned, recording the
+	// managed instance group it was abandoned from and the target size to restore the group to
+	detachedFromLabel     = "cyclops-detached-from"
+	detachTargetSizeLabel = "cyclops-detach-target-size"
+)
+
+var (
+	providerIDRegex  = regexp.MustCompile(`^gce:\/\/([\w.:-]+)\/([\w-]+)\/([\w-]+)$`)
+	instanceURLRegex = regexp.MustCompile(`projects\/([\w.:-]+)\/zones\/([\w-]+)\/instances\/([\w-]+)$`)
+)
+
+// resourceRef identifies a zonal resource, either an instance or a managed instance group
+type resourceRef struct {
+	project string
+	zone    string
+	name    string
+}
+
+// providerID returns the Kubernetes provider ID for the instance referenced
+func (r resourceRef) providerID() string {
+	return fmt.Sprintf("gce://%s/%s/%s", r.project, r.zone, r.name)
+}
+
+// url returns the partial Compute API URL for the instance referenced
+func (r resourceRef) url() string {
+	return fmt.Sprintf("projects/%s/zones/%s/instances/%s", r.project, r.zone, r.name)
+}
+
+func providerIDToInstanceRef(providerID string) (resourceRef, error) {
+	res := providerIDRegex.FindStringSubmatch(providerID)
+	if len(res) != 4 {
+		return resourceRef{}, fmt.Errorf("unable to extract instance from provider ID")
+	}
+	return resourceRef{project: res[1], zone: res[2], name: res[3]}, nil
+}
+
+func instanceURLToInstanceRef(instanceURL string) (resourceRef, error) {
+	res := instanceURLRegex.FindStringSubmatch(instanceURL)
+	if len(res) != 4 {
+		return resourceRef{}, fmt.Errorf("unable to extract instance from instance url %q", instanceURL)
+	}
+	return resourceRef{project: res[1], zone: res[2], name: res[3]}, nil
+}
+
+// nodeGroupNameToRef converts a node group name in the format <project>/<zone>/<name> into a reference
+// to the zonal managed instance group
+func nodeGroupNameToRef(nodeGroupName string) (resourceRef, error) {
+	parts := strings.Split(nodeGroupName, "/")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return resourceRef{}, fmt.Errorf("node group name %q must be in the format <project>/<zone>/<name>", nodeGroupName)
+	}
+	return resourceRef{project: parts[0], zone: parts[1], name: parts[2]}, nil
+}
+
+// isNotFound returns true if the error returned by the Compute API is a 404
+func isNotFound(err error) bool {
+	if apiErr, ok := err.(*googleapi.Error); ok {
+		return apiErr.Code == http.StatusNotFound
+	}
+	return false
+}
+
+type provider struct {
+	computeService computeService
+	logger         logr.Logger
+}
+
+// instanceGroup stores a managed instance group and the instances it manages
+type instanceGroup struct {
+	ref       resourceRef
+	nodeGroup string
+	manager   *compute.InstanceGroupManager
+	instances []*compute.ManagedInstance
+}
+
+type managedInstanceGroups struct {
+	computeService computeService
+	groups         []*instanceGroup
+	logger         logr.Logger
+}
+
+type instance struct {
+	ref           resourceRef
+	instance      *compute.ManagedInstance
+	nodeGroupName string
+	outOfDate     bool
+}
+
+// Name returns the name of the cloud provider
+func (p *provider) Name() string {
+	return ProviderName
+}
+
+// GetNodeGroups gets managed instance groups. Names must be in the format <project>/<zone>/<name>
+func (p *provider) GetNodeGroups(names []string) (cloudprovider.NodeGroups, error) {
+	var groups []*instanceGroup
+	for _, name := range names {
+		ref, err := nodeGroupNameToRef(name)
+		if err != nil {
+			return nil, err
+		}
+
+		manager, err := p.computeService.GetInstanceGroupManager(ref.project, ref.zone, ref.name)
+		if err != nil {
+			return nil, err
+		}
+
+		instances, err := p.computeService.ListManagedInstances(ref.project, ref.zone, ref.name)
+		if err != nil {
+			return nil, err
+		}
+
+		groups = append(groups, &instanceGroup{
+			ref:       ref,
+			nodeGroup: name,
+			manager:   manager,
+			instances: instances,
+		})
+	}
+
+	if len(groups) == 0 {
+		return nil, fmt.Errorf("managed instance groups not found: %v", names)
+	}
+
+	return &managedInstanceGroups{
+		computeService: p.computeService,
+		groups:         groups,
+		logger:         p.logger,
+	}, nil
+}
+
+// InstancesExist returns a list of the instances that exist
+func (p *provider) InstancesExist(providerIDs []string) (validProviderIDs []string, err error) {
+	for _, providerID := range providerIDs {
+		ref, err := providerIDToInstanceRef(providerID)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := p.computeService.GetInstance(ref.project, ref.zone, ref.name); err != nil {
+			if isNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+
+		validProviderIDs = append(validProviderIDs, providerID)
+	}
+
+	return validProviderIDs, nil
+}
+
+// ReplacesTerminatedInstances returns true, as a managed instance group recreates deleted instances to keep its
+// target size
+func (p *provider) ReplacesTerminatedInstances() bool {
+	return true
+}
+
+// TerminateInstance deletes an instance
+func (p *provider) TerminateInstance(providerID string) error {
+	ref, err := providerIDToInstanceRef(providerID)
+	if err != nil {
+		return err
+	}
+
+	return p.computeService.DeleteInstance(ref.project, ref.zone, ref.name)
+}
+
+// instances returns a map of the instances in the managed instance groups that match the filter
+// with providerID as key and cloudprovider.Instance as value
+func (m *managedInstanceGroups) instances(filter func(*compute.ManagedInstance) bool) map[string]cloudprovider.Instance {
+	instances := make(map[string]cloudprovider.Instance)
+	for _, group := range m.groups {
+		for _, i := range group.instances {
+			if !filter(i) {
+				continue
+			}
+			ref, err := instanceURLToInstanceRef(i.Instance)
+			if err != nil {
+				m.logger.Info("skip instance which failed instance url to providerID conversion", "instance", i.Instance)
+				continue
+			}
+			instances[ref.providerID()] = &instance{
+				ref:           ref,
+				instance:      i,
+				outOfDate:     instanceOutOfDate(group.manager, i),
+				nodeGroupName: group.nodeGroup,
+			}
+		}
+	}
+	return instances
+}
+
+// Instances returns a map of all instances in the managed instance groups
+// with providerID as key and cloudprovider.Instance as value
+func (m *managedInstanceGroups) Instances() map[string]cloudprovider.Instance {
+	return m.instances(func(*compute.ManagedInstance) bool {
+		return true
+	})
+}
+
+// ReadyInstances returns a map of instances that are running and have no pending action
+// with providerID as key and cloudprovider.Instance as value
+func (m *managedInstanceGroups) ReadyInstances() map[string]cloudprovider.Instance {
+	return m.instances(instanceReady)
+}
+
+// NotReadyInstances returns a map of instances that are not running or have a pending action
+// with providerID as key and cloudprovider.Instance as value
+func (m *managedInstanceGroups) NotReadyInstances() map[string]cloudprovider.Instance {
+	return m.instances(func(i *compute.ManagedInstance) bool {
+		return !instanceReady(i)
+	})
+}
+
+// getInstanceGroupByInstance finds the managed instance group that manages the instance
+func (m *managedInstanceGroups) getInstanceGroupByInstance(ref resourceRef) (*instanceGroup, *compute.ManagedInstance, error) {
+	for _, group := range m.groups {
+		for _, i := range group.instances {
+			instanceRef, err := instanceURLToInstanceRef(i.Instance)
+			if err != nil {
+				continue
+			}
+			if instanceRef == ref {
+				return group, i, nil
+			}
+		}
+	}
+	return nil, nil, fmt.Errorf("failed to find target node group for instance: %v", ref.name)
+}
+
+// DetachInstance abandons the instance from the managed instance group. Abandoning an instance
+// decreases the target size of the group, so the group is resized back to its original target size
+// to trigger the creation of a replacement instance. The original target size is recorded on the instance
+// first, so that retrying after a failed resize restores it even though the instance has already left the group.
+func (m *managedInstanceGroups) DetachInstance(providerID string) (alreadyDetaching bool, err error) {
+	ref, err := providerIDToInstanceRef(providerID)
+	if err != nil {
+		return false, err
+	}
+
+	group, managedInstance, err := m.getInstanceGroupByInstance(ref)
+	if err != nil || managedInstance.CurrentAction == currentActionAbandon {
+		// The instance may have been abandoned by an earlier attempt that failed to resize the group back
+		abandonedFrom, targetSize, ok, getErr := m.abandonedInstanceGroup(ref)
+		if getErr != nil {
+			return false, getErr
+		}
+		if ok {
+			return false, m.restoreTargetSize(abandonedFrom, targetSize)
+		}
+		if err != nil {
+			return false, err
+		}
+		return true, fmt.Errorf("instance %v is already being abandoned from %v", ref.name, group.nodeGroup)
+	}
+
+	targetSize := group.manager.TargetSize
+	if err := m.recordDetach(ref, group, targetSize); err != nil {
+		return false, err
+	}
+
+	if err := m.computeService.AbandonInstances(group.ref.project, group.ref.zone, group.ref.name, []string{ref.url()}); err != nil {
+		return false, err
+	}
+
+	return false, m.computeService.ResizeInstanceGroupManager(group.ref.project, group.ref.zone, group.ref.name, targetSize)
+}
+
+// AttachInstance checks the instance is still part of the managed instance group. Managed instance groups
+// do not support adding existing instances, so an instance which has already been abandoned cannot be re-attached.
+// Instead the group is restored to its target size from before the instance was abandoned and
+// cloudprovider.ErrCannotReattach is returned, so that the caller drains and terminates the abandoned instance.
+func (m *managedInstanceGroups) AttachInstance(providerID, nodeGroup string) (alreadyAttached bool, err error) {
+	ref, err := providerIDToInstanceRef(providerID)
+	if err != nil {
+		return false, err
+	}
+
+	group, _, err := m.getInstanceGroupByInstance(ref)
+	if err == nil && group.nodeGroup == nodeGroup {
+		return true, nil
+	}
+
+	abandonedFrom, targetSize, ok, err := m.abandonedInstanceGroup(ref)
+	if err != nil {
+		return false, err
+	}
+	if !ok || abandonedFrom.nodeGroup != nodeGroup {
+		return false, fmt.Errorf("instance %v cannot be attached to %v: managed instance groups do not support attaching existing instances", ref.name, nodeGroup)
+	}
+
+	if err := m.restoreTargetSize(abandonedFrom, targetSize); err != nil {
+		return false, err
+	}
+	return false, fmt.Errorf("instance %v was abandoned from %v: %w", ref.name, nodeGroup, cloudprovider.ErrCannotReattach)
+}
+
+// recordDetach labels the instance with the managed instance group it is about to be abandoned from and the target
+// size to restore the group to. A target size recorded by an earlier attempt is kept.
+func (m *managedInstanceGroups) recordDetach(ref resourceRef, group *instanceGroup, targetSize int64) error {
+	i, err := m.computeService.GetInstance(ref.project, ref.zone, ref.name)
+	if err != nil {
+		return err
+	}
+
+	labels := map[string]string{}
+	var fingerprint string
+	if i != nil {
+		for key, value := range i.Labels {
+			labels[key] = value
+		}
+		fingerprint = i.LabelFingerprint
+	}
+	if labels[detachedFromLabel] == group.ref.name && labels[detachTargetSizeLabel] != "" {
+		return nil
+	}
+
+	labels[detachedFromLabel] = group.ref.name
+	labels[detachTargetSizeLabel] = strconv.FormatInt(targetSize, 10)
+	return m.computeService.SetInstanceLabels(ref.project, ref.zone, ref.name, labels, fingerprint)
+}
+
+// abandonedInstanceGroup returns the managed instance group an instance was abandoned from by DetachInstance and
+// the target size recorded for it. It returns false if the instance wasn't abandoned from one of the groups.
+func (m *managedInstanceGroups) abandonedInstanceGroup(ref resourceRef) (*instanceGroup, int64, bool, error) {
+	i, err := m.computeService.GetInstance(ref.project, ref.zone, ref.name)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, 0, false, nil
+		}
+		return nil, 0, false, err
+	}
+	if i == nil {
+		return nil, 0, false, nil
+	}
+
+	targetSize, err := strconv.ParseInt(i.Labels[detachTargetSizeLabel], 10, 64)
+	if err != nil {
+		return nil, 0, false, nil
+	}
+
+	for _, group := range m.groups {
+		if group.ref.project == ref.project && group.ref.zone == ref.zone && group.ref.name == i.Labels[detachedFromLabel] {
+			return group, targetSize, true, nil
+		}
+	}
+	return nil, 0, false, nil
+}
+
+// restoreTargetSize resizes the managed instance group back up to the target size if it is smaller
+func (m *managedInstanceGroups) restoreTargetSize(group *instanceGroup, targetSize int64) error {
+	if group.manager.TargetSize >= targetSize {
+		return nil
+	}
+	return m.computeService.ResizeInstanceGroupManager(group.ref.project, group.ref.zone, group.ref.name, targetSize)
+}
+
+// instanceReady returns whether the managed instance is running and has no pending action
+func instanceReady(i *compute.ManagedInstance) bool {
+	return i.InstanceStatus == instanceStatusRunning && i.CurrentAction == currentActionNone
+}
+
+// instanceOutOfDate returns whether the instance template of the managed instance is not one
+// of the templates the managed instance group is currently configured with
+func instanceOutOfDate(manager *compute.InstanceGroupManager, i *compute.ManagedInstance) bool {
+	if i.Version == nil {
+		return false
+	}
+
+	groupTemplates := map[string]bool{}
+	if manager.InstanceTemplate != "" {
+		groupTemplates[path.Base(manager.InstanceTemplate)] = true
+	}
+	for _, version := range manager.Versions {
+		if version.InstanceTemplate != "" {
+			groupTemplates[path.Base(version.InstanceTemplate)] = true
+		}
+	}
+
+	return !groupTemplates[path.Base(i.Version.InstanceTemplate)]
+}
+
+// ID returns the name of the instance
+func (i *instance) ID() string {
+	return i.ref.name
+}
+
+// String returns the name of the instance
+func (i *instance) String() string {
+	return i.ID()
+}
+
+// OutOfDate returns if the instance is out of date from it's managed instance group template
+func (i *instance) OutOfDate() bool {
+	return i.outOfDate
+}
+
+// MatchesProviderID returns if the instance matches the providerID
+func (i *instance) MatchesProviderID(providerID string) bool {
+	if ref, err := providerIDToInstanceRef(providerID); err == nil {
+		return i.ref == ref
+	}
+	return false
+}
+
+// NodeGroupName returns cloud provider node group name for the instance
+func (i *instance) NodeGroupName() string {
+	return i.nodeGroupName
+}
@@ -0,0 +1,332 @@
+package gcp
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/googleapi"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/atlassian-labs/cyclops/pkg/cloudprovider"
+	"github.com/atlassian-labs/cyclops/pkg/test"
+)
+
+const (
+	testInstanceURLPrefix = "https://www.googleapis.com/compute/v1/projects/my-project/zones/us-central1-a/instances/"
+	testTemplateURLPrefix = "https://www.googleapis.com/compute/v1/projects/my-project/global/instanceTemplates/"
+)
+
+func newTestProvider(service *test.MockComputeService) *provider {
+	return &provider{
+		computeService: service,
+		logger:         logf.Log.WithName("gcp-test"),
+	}
+}
+
+func newTestComputeService() *test.MockComputeService {
+	return &test.MockComputeService{
+		InstanceGroupManagers: map[string]*compute.InstanceGroupManager{
+			"nodes": {
+				Name:             "nodes",
+				InstanceTemplate: testTemplateURLPrefix + "nodes-v2",
+				TargetSize:       2,
+			},
+		},
+		ManagedInstances: map[string][]*compute.ManagedInstance{
+			"nodes": {
+				{
+					Instance:       testInstanceURLPrefix + "nodes-abcd",
+					InstanceStatus: instanceStatusRunning,
+					CurrentAction:  currentActionNone,
+					Version:        &compute.ManagedInstanceVersion{InstanceTemplate: testTemplateURLPrefix + "nodes-v1"},
+				},
+				{
+					Instance:       testInstanceURLPrefix + "nodes-efgh",
+					InstanceStatus: "PROVISIONING",
+					CurrentAction:  "CREATING",
+					Version:        &compute.ManagedInstanceVersion{InstanceTemplate: testTemplateURLPrefix + "nodes-v2"},
+				},
+			},
+		},
+	}
+}
+
+func Test_providerIDToInstanceRef(t *testing.T) {
+	tests := []struct {
+		name       string
+		providerID string
+		ref        resourceRef
+		wantErr    bool
+	}{
+		{
+			"expected format",
+			"gce://my-project/us-central1-a/nodes-abcd",
+			resourceRef{project: "my-project", zone: "us-central1-a", name: "nodes-abcd"},
+			false,
+		},
+		{
+			"domain scoped project",
+			"gce://example.com:my-project/us-central1-a/nodes-abcd",
+			resourceRef{project: "example.com:my-project", zone: "us-central1-a", name: "nodes-abcd"},
+			false,
+		},
+		{
+			"incorrect format. missing zone",
+			"gce://my-project/nodes-abcd",
+			resourceRef{},
+			true,
+		},
+		{
+			"incorrect format. wrong scheme",
+			"aws:///us-west-2b/i-0bdf741206dd9793c",
+			resourceRef{},
+			true,
+		},
+		{
+			"incorrect format. missing name",
+			"gce://my-project/us-central1-a/",
+			resourceRef{},
+			true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ref, err := providerIDToInstanceRef(tt.providerID)
+			if tt.wantErr {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.ref, ref)
+			assert.Equal(t, tt.providerID, ref.providerID())
+		})
+	}
+}
+
+func Test_nodeGroupNameToRef(t *testing.T) {
+	tests := []struct {
+		name          string
+		nodeGroupName string
+		ref           resourceRef
+		wantErr       bool
+	}{
+		{
+			"expected format",
+			"my-project/us-central1-a/nodes",
+			resourceRef{project: "my-project", zone: "us-central1-a", name: "nodes"},
+			false,
+		},
+		{
+			"missing project and zone",
+			"nodes",
+			resourceRef{},
+			true,
+		},
+		{
+			"empty zone",
+			"my-project//nodes",
+			resourceRef{},
+			true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ref, err := nodeGroupNameToRef(tt.nodeGroupName)
+			if tt.wantErr {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.ref, ref)
+		})
+	}
+}
+
+func TestGetNodeGroups(t *testing.T) {
+	p := newTestProvider(newTestComputeService())
+
+	nodeGroups, err := p.GetNodeGroups([]string{"my-project/us-central1-a/nodes"})
+	assert.NoError(t, err)
+
+	instances := nodeGroups.Instances()
+	assert.Len(t, instances, 2)
+
+	outOfDate := instances["gce://my-project/us-central1-a/nodes-abcd"]
+	if assert.NotNil(t, outOfDate) {
+		assert.Equal(t, "nodes-abcd", outOfDate.ID())
+		assert.True(t, outOfDate.OutOfDate())
+		assert.Equal(t, "my-project/us-central1-a/nodes", outOfDate.NodeGroupName())
+		assert.True(t, outOfDate.MatchesProviderID("gce://my-project/us-central1-a/nodes-abcd"))
+		assert.False(t, outOfDate.MatchesProviderID("gce://my-project/us-central1-b/nodes-abcd"))
+	}
+
+	upToDate := instances["gce://my-project/us-central1-a/nodes-efgh"]
+	if assert.NotNil(t, upToDate) {
+		assert.False(t, upToDate.OutOfDate())
+	}
+
+	assert.Len(t, nodeGroups.ReadyInstances(), 1)
+	assert.Contains(t, nodeGroups.ReadyInstances(), "gce://my-project/us-central1-a/nodes-abcd")
+	assert.Len(t, nodeGroups.NotReadyInstances(), 1)
+	assert.Contains(t, nodeGroups.NotReadyInstances(), "gce://my-project/us-central1-a/nodes-efgh")
+}
+
+func TestGetNodeGroups_InvalidName(t *testing.T) {
+	p := newTestProvider(newTestComputeService())
+
+	_, err := p.GetNodeGroups([]string{"nodes"})
+	assert.Error(t, err)
+}
+
+func Test_instanceOutOfDate_CanaryVersions(t *testing.T) {
+	manager := &compute.InstanceGroupManager{
+		InstanceTemplate: testTemplateURLPrefix + "nodes-v1",
+		Versions: []*compute.InstanceGroupManagerVersion{
+			{InstanceTemplate: testTemplateURLPrefix + "nodes-v1"},
+			{InstanceTemplate: testTemplateURLPrefix + "nodes-v2"},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		template  string
+		outOfDate bool
+	}{
+		{"primary version", "nodes-v1", false},
+		{"canary version", "nodes-v2", false},
+		{"old version", "nodes-v0", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			i := &compute.ManagedInstance{
+				Version: &compute.ManagedInstanceVersion{InstanceTemplate: testTemplateURLPrefix + tt.template},
+			}
+			assert.Equal(t, tt.outOfDate, instanceOutOfDate(manager, i))
+		})
+	}
+}
+
+func TestDetachInstance(t *testing.T) {
+	service := newTestComputeService()
+	p := newTestProvider(service)
+
+	nodeGroups, err := p.GetNodeGroups([]string{"my-project/us-central1-a/nodes"})
+	assert.NoError(t, err)
+
+	alreadyDetaching, err := nodeGroups.DetachInstance("gce://my-project/us-central1-a/nodes-abcd")
+	assert.NoError(t, err)
+	assert.False(t, alreadyDetaching)
+
+	// The instance is abandoned and the group resized back to its original target size
+	assert.Equal(t, []string{"projects/my-project/zones/us-central1-a/instances/nodes-abcd"}, service.AbandonedInstances)
+	assert.Equal(t, int64(2), service.ResizedTo["nodes"])
+	assert.Equal(t, map[string]string{detachedFromLabel: "nodes", detachTargetSizeLabel: "2"}, service.Instances["nodes-abcd"].Labels)
+}
+
+func TestDetachInstance_RetryAfterResizeFailed(t *testing.T) {
+	service := newTestComputeService()
+	service.ResizeInstanceGroupManagerErr = fmt.Errorf("quota exceeded")
+	p := newTestProvider(service)
+
+	nodeGroups, err := p.GetNodeGroups([]string{"my-project/us-central1-a/nodes"})
+	assert.NoError(t, err)
+	_, err = nodeGroups.DetachInstance("gce://my-project/us-central1-a/nodes-abcd")
+	assert.Error(t, err)
+
+	// The instance has left the group and the group has shrunk by the time it is retried
+	service.ResizeInstanceGroupManagerErr = nil
+	service.ManagedInstances["nodes"] = service.ManagedInstances["nodes"][1:]
+	service.InstanceGroupManagers["nodes"].TargetSize = 1
+
+	nodeGroups, err = p.GetNodeGroups([]string{"my-project/us-central1-a/nodes"})
+	assert.NoError(t, err)
+	alreadyDetaching, err := nodeGroups.DetachInstance("gce://my-project/us-central1-a/nodes-abcd")
+	assert.NoError(t, err)
+	assert.False(t, alreadyDetaching)
+	assert.Len(t, service.AbandonedInstances, 1)
+	assert.Equal(t, int64(2), service.ResizedTo["nodes"])
+}
+
+func TestDetachInstance_AlreadyAbandoning(t *testing.T) {
+	service := newTestComputeService()
+	service.ManagedInstances["nodes"][0].CurrentAction = currentActionAbandon
+	p := newTestProvider(service)
+
+	nodeGroups, err := p.GetNodeGroups([]string{"my-project/us-central1-a/nodes"})
+	assert.NoError(t, err)
+
+	alreadyDetaching, err := nodeGroups.DetachInstance("gce://my-project/us-central1-a/nodes-abcd")
+	assert.Error(t, err)
+	assert.True(t, alreadyDetaching)
+	assert.Empty(t, service.AbandonedInstances)
+}
+
+func TestAttachInstance(t *testing.T) {
+	p := newTestProvider(newTestComputeService())
+
+	nodeGroups, err := p.GetNodeGroups([]string{"my-project/us-central1-a/nodes"})
+	assert.NoError(t, err)
+
+	// Instances still in the group are already attached
+	alreadyAttached, err := nodeGroups.AttachInstance("gce://my-project/us-central1-a/nodes-abcd", "my-project/us-central1-a/nodes")
+	assert.NoError(t, err)
+	assert.True(t, alreadyAttached)
+
+	// Instances that weren't abandoned by a detach can't be added to the group
+	alreadyAttached, err = nodeGroups.AttachInstance("gce://my-project/us-central1-a/nodes-zzzz", "my-project/us-central1-a/nodes")
+	assert.Error(t, err)
+	assert.False(t, alreadyAttached)
+}
+
+func TestAttachInstance_Abandoned(t *testing.T) {
+	service := newTestComputeService()
+	service.InstanceGroupManagers["nodes"].TargetSize = 1
+	service.Instances = map[string]*compute.Instance{
+		"nodes-zzzz": {Name: "nodes-zzzz", Labels: map[string]string{detachedFromLabel: "nodes", detachTargetSizeLabel: "2"}},
+	}
+	p := newTestProvider(service)
+
+	nodeGroups, err := p.GetNodeGroups([]string{"my-project/us-central1-a/nodes"})
+	assert.NoError(t, err)
+
+	// The abandoned instance can't be added back, and the group is restored to its original target size
+	alreadyAttached, err := nodeGroups.AttachInstance("gce://my-project/us-central1-a/nodes-zzzz", "my-project/us-central1-a/nodes")
+	assert.True(t, errors.Is(err, cloudprovider.ErrCannotReattach))
+	assert.False(t, alreadyAttached)
+	assert.Equal(t, int64(2), service.ResizedTo["nodes"])
+	assert.Empty(t, service.DeletedInstances)
+}
+
+func TestInstancesExist(t *testing.T) {
+	service := newTestComputeService()
+	service.Instances = map[string]*compute.Instance{"nodes-abcd": {Name: "nodes-abcd"}}
+	p := newTestProvider(service)
+
+	valid, err := p.InstancesExist([]string{"gce://my-project/us-central1-a/nodes-abcd"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"gce://my-project/us-central1-a/nodes-abcd"}, valid)
+
+	service.GetInstanceErr = &googleapi.Error{Code: http.StatusNotFound}
+	valid, err = p.InstancesExist([]string{"gce://my-project/us-central1-a/nodes-abcd"})
+	assert.NoError(t, err)
+	assert.Empty(t, valid)
+
+	service.GetInstanceErr = &googleapi.Error{Code: http.StatusForbidden}
+	_, err = p.InstancesExist([]string{"gce://my-project/us-central1-a/nodes-abcd"})
+	assert.Error(t, err)
+}
+
+func TestTerminateInstance(t *testing.T) {
+	service := newTestComputeService()
+	p := newTestProvider(service)
+
+	assert.NoError(t, p.TerminateInstance("gce://my-project/us-central1-a/nodes-abcd"))
+	assert.Equal(t, []string{"nodes-abcd"}, service.DeletedInstances)
+}
@@ -1,11 +1,19 @@
 package cloudprovider
 
+import "errors"
+
+// ErrCannotReattach is returned by AttachInstance when the instance was detached from its node group and the cloud
+// provider has no way to add it back. The instance isn't in any node group anymore, so it has to be drained and
+// terminated instead of being put back into service.
+var ErrCannotReattach = errors.New("instance cannot be attached to its node group again")
+
 // CloudProvider provides an interface to interact with a cloud provider, e.g. AWS, GCP etc.
 type CloudProvider interface {
 	Name() string
 	InstancesExist([]string) ([]string, error)
 	GetNodeGroups([]string) (NodeGroups, error)
 	TerminateInstance(string) error
+	ReplacesTerminatedInstances() bool
 }
 
 // NodeGroups provides an interface to interact with a list of `node groups` in a cloud provider
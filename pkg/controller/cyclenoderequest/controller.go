@@ -197,6 +197,10 @@ func (r *Reconciler) Reconcile(ctx context.Context, request reconcile.Request) (
 			cycleNodeRequest.Spec.PreTerminationChecks[i].HealthCheck.ValidStatusCodes = []uint{200}
 		}
 
+		if preTerminationCheck.Cancel != nil && len(preTerminationCheck.Cancel.ValidStatusCodes) == 0 {
+			cycleNodeRequest.Spec.PreTerminationChecks[i].Cancel.ValidStatusCodes = []uint{200}
+		}
+
 		// Validate the tls certs before starting to cycle. The certs are optional.
 		if err := tlsCertsValid(preTerminationCheck.TLSConfig); err != nil {
 			return reconcile.Result{}, err
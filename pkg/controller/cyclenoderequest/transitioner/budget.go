@@ -0,0 +1,122 @@
+package transitioner
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "github.com/atlassian-labs/cyclops/pkg/apis/atlassian/v1"
+)
+
+// budgetWindow is the window over which the MaxNodesCycledPerHour budget is counted
+const budgetWindow = time.Hour
+
+// budgetEnabled returns true if either of the cluster-wide cycling budgets are set
+func (t *CycleNodeRequestTransitioner) budgetEnabled() bool {
+	return t.options.MaxNodesInFlight > 0 || t.options.MaxNodesCycledPerHour > 0
+}
+
+// cyclingBudget returns how many more nodes can be selected for cycling within the cluster-wide budgets, or -1 if
+// there is no budget. The CycleNodeRequests and CycleNodeStatuses in every namespace count towards the budgets, since
+// they all cycle the same nodes. When none are left, the message says which budget is used up.
+func (t *CycleNodeRequestTransitioner) cyclingBudget(now time.Time) (remaining int64, message string, err error) {
+	if !t.budgetEnabled() {
+		return -1, "", nil
+	}
+
+	var cnrList v1.CycleNodeRequestList
+	if err := t.rm.Client.List(context.TODO(), &cnrList); err != nil {
+		return 0, "", err
+	}
+
+	remaining = -1
+	if t.options.MaxNodesInFlight > 0 {
+		var cnsList v1.CycleNodeStatusList
+		if err := t.rm.Client.List(context.TODO(), &cnsList); err != nil {
+			return 0, "", err
+		}
+
+		inFlight := countNodesInFlight(cnrList.Items, cnsList.Items)
+		remaining = t.options.MaxNodesInFlight - inFlight
+		if remaining <= 0 {
+			remaining = 0
+			message = fmt.Sprintf("%d nodes are being cycled across the cluster, waiting for fewer than %d before selecting more nodes",
+				inFlight, t.options.MaxNodesInFlight)
+		}
+	}
+
+	if t.options.MaxNodesCycledPerHour > 0 {
+		selected := countNodesSelectedSince(cnrList.Items, now.Add(-budgetWindow))
+		left := t.options.MaxNodesCycledPerHour - selected
+		if left <= 0 {
+			left = 0
+			message = fmt.Sprintf("%d nodes have been selected for cycling across the cluster in the last hour, waiting for fewer than %d before selecting more nodes",
+				selected, t.options.MaxNodesCycledPerHour)
+		}
+		if remaining < 0 || left < remaining {
+			remaining = left
+		}
+	}
+
+	return remaining, message, nil
+}
+
+// countNodesInFlight counts the nodes being cycled across all of the CycleNodeRequests. This is the nodes that have
+// been selected but not handed off yet, and the nodes with a CycleNodeStatus that hasn't finished.
+func countNodesInFlight(cnrs []v1.CycleNodeRequest, cnss []v1.CycleNodeStatus) int64 {
+	nodes := make(map[string]bool)
+
+	for _, cnr := range cnrs {
+		switch cnr.Status.Phase {
+		case v1.CycleNodeRequestFailed, v1.CycleNodeRequestSuccessful, v1.CycleNodeRequestCancelled:
+			continue
+		}
+		for _, node := range cnr.Status.CurrentNodes {
+			nodes[node.Name] = true
+		}
+	}
+
+	for _, cns := range cnss {
+		switch cns.Status.Phase {
+		case v1.CycleNodeStatusFailed, v1.CycleNodeStatusSuccessful:
+			continue
+		}
+		nodes[cns.Spec.NodeName] = true
+	}
+
+	return int64(len(nodes))
+}
+
+// countNodesSelectedSince counts the nodes selected for cycling across all of the CycleNodeRequests since the time
+func countNodesSelectedSince(cnrs []v1.CycleNodeRequest, since time.Time) int64 {
+	var selected int64
+	for _, cnr := range cnrs {
+		for _, selectionTime := range cnr.Status.NodeSelectionTimes {
+			if selectionTime.After(since) {
+				selected++
+			}
+		}
+	}
+	return selected
+}
+
+// recordNodeSelections records the time the current nodes were selected, and forgets the selections that have
+// fallen outside of the budget window. It doesn't save the CycleNodeRequest.
+func (t *CycleNodeRequestTransitioner) recordNodeSelections(now time.Time) {
+	if t.options.MaxNodesCycledPerHour <= 0 {
+		return
+	}
+
+	var selectionTimes []metav1.Time
+	for _, selectionTime := range t.cycleNodeRequest.Status.NodeSelectionTimes {
+		if selectionTime.After(now.Add(-budgetWindow)) {
+			selectionTimes = append(selectionTimes, selectionTime)
+		}
+	}
+	for range t.cycleNodeRequest.Status.CurrentNodes {
+		selectionTimes = append(selectionTimes, metav1.NewTime(now))
+	}
+	t.cycleNodeRequest.Status.NodeSelectionTimes = selectionTimes
+}
@@ -0,0 +1,149 @@
+package transitioner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	v1 "github.com/atlassian-labs/cyclops/pkg/apis/atlassian/v1"
+)
+
+func TestCyclingBudget(t *testing.T) {
+	now := time.Now()
+
+	// Another cycleNodeRequest in a different namespace has selected two nodes, one of which is already draining, and
+	// selected two more an hour and a half ago
+	other := newTestCycleNodeRequest(v1.CycleNodeRequestCordoningNode)
+	other.Name = "other"
+	other.Namespace = "other-system"
+	other.Status.CurrentNodes = []v1.CycleNodeRequestNode{{Name: "node-a"}, {Name: "node-b"}}
+	other.Status.NodeSelectionTimes = []metav1.Time{
+		metav1.NewTime(now.Add(-90 * time.Minute)),
+		metav1.NewTime(now.Add(-90 * time.Minute)),
+		metav1.NewTime(now.Add(-time.Minute)),
+		metav1.NewTime(now.Add(-time.Minute)),
+	}
+	draining := &v1.CycleNodeStatus{
+		ObjectMeta: metav1.ObjectMeta{Name: "other-node-a", Namespace: "other-system"},
+		Spec:       v1.CycleNodeStatusSpec{NodeName: "node-a"},
+		Status:     v1.CycleNodeStatusStatus{Phase: v1.CycleNodeStatusDrainingPods},
+	}
+	finished := &v1.CycleNodeStatus{
+		ObjectMeta: metav1.ObjectMeta{Name: "other-node-c", Namespace: "other-system"},
+		Spec:       v1.CycleNodeStatusSpec{NodeName: "node-c"},
+		Status:     v1.CycleNodeStatusStatus{Phase: v1.CycleNodeStatusSuccessful},
+	}
+	// Finished cycleNodeRequests don't count towards the nodes in flight
+	failed := newTestCycleNodeRequest(v1.CycleNodeRequestFailed)
+	failed.Name = "failed"
+	failed.Status.CurrentNodes = []v1.CycleNodeRequestNode{{Name: "node-d"}}
+
+	tests := []struct {
+		name            string
+		options         Options
+		expectRemaining int64
+		expectMessage   bool
+	}{
+		{"no budget", Options{}, -1, false},
+		{"nodes in flight left", Options{MaxNodesInFlight: 5}, 3, false},
+		{"nodes in flight used up", Options{MaxNodesInFlight: 2}, 0, true},
+		{"nodes per hour left", Options{MaxNodesCycledPerHour: 3}, 1, false},
+		{"nodes per hour used up", Options{MaxNodesCycledPerHour: 2}, 0, true},
+		{"lowest budget is used", Options{MaxNodesInFlight: 5, MaxNodesCycledPerHour: 4}, 2, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transitioner := newTestTransitioner(t, newTestCycleNodeRequest(v1.CycleNodeRequestInitialised),
+				[]runtime.Object{other, failed, draining, finished}...)
+			transitioner.options = tt.options
+
+			remaining, message, err := transitioner.cyclingBudget(now)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectRemaining, remaining)
+			assert.Equal(t, tt.expectMessage, message != "")
+		})
+	}
+}
+
+func TestTransitionInitialised_WaitingForBudget(t *testing.T) {
+	tests := []struct {
+		name             string
+		maxNodesInFlight int64
+		expectPhase      v1.CycleNodeRequestPhase
+		expectCondition  metav1.ConditionStatus
+	}{
+		{"budget used up", 1, v1.CycleNodeRequestInitialised, metav1.ConditionTrue},
+		{"budget left", 2, v1.CycleNodeRequestScalingUp, metav1.ConditionFalse},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node1 := newTestZoneNode("node-1", "us-east-1a", nil)
+			node2 := newTestZoneNode("node-2", "us-east-1a", nil)
+			nodes := []v1.CycleNodeRequestNode{
+				{Name: "node-1", ProviderID: node1.Spec.ProviderID, NodeGroupName: "nodegroup"},
+				{Name: "node-2", ProviderID: node2.Spec.ProviderID, NodeGroupName: "nodegroup"},
+			}
+
+			// Another cycleNodeRequest in a different namespace is cycling a node
+			other := newTestCycleNodeRequest(v1.CycleNodeRequestCordoningNode)
+			other.Name = "other"
+			other.Namespace = "other-system"
+			other.Status.CurrentNodes = []v1.CycleNodeRequestNode{{Name: "node-a"}}
+
+			cnr := newTestCycleNodeRequest(v1.CycleNodeRequestInitialised)
+			cnr.Status.NodesToTerminate = nodes
+			cnr.Status.NodesAvailable = append([]v1.CycleNodeRequestNode(nil), nodes...)
+			cloudProvider := newTestCloudProvider("aws", node1.Spec.ProviderID, node2.Spec.ProviderID)
+			transitioner := newTestTransitioner(t, cnr, other, node1, node2)
+			transitioner.rm.CloudProvider = cloudProvider
+			transitioner.options.MaxNodesInFlight = tt.maxNodesInFlight
+
+			_, err := transitioner.Run()
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectPhase, cnr.Status.Phase)
+
+			condition := meta.FindStatusCondition(cnr.Status.Conditions, v1.CycleNodeRequestConditionWaitingForBudget)
+			if assert.NotNil(t, condition) {
+				assert.Equal(t, tt.expectCondition, condition.Status)
+			}
+
+			// No nodes are selected while the budget is used up
+			if tt.expectCondition == metav1.ConditionTrue {
+				assert.Empty(t, cnr.Status.CurrentNodes)
+				assert.Empty(t, cloudProvider.detached)
+				assert.Len(t, cnr.Status.NodesAvailable, 2)
+				return
+			}
+			assert.Len(t, cnr.Status.CurrentNodes, 1)
+			assert.Len(t, cloudProvider.detached, 1)
+		})
+	}
+}
+
+func TestRecordNodeSelections(t *testing.T) {
+	now := time.Now()
+	cnr := newTestCycleNodeRequest(v1.CycleNodeRequestInitialised)
+	cnr.Status.CurrentNodes = []v1.CycleNodeRequestNode{{Name: "node-a"}, {Name: "node-b"}}
+	cnr.Status.NodeSelectionTimes = []metav1.Time{
+		metav1.NewTime(now.Add(-2 * time.Hour)),
+		metav1.NewTime(now.Add(-time.Minute)),
+	}
+
+	// Selections are only recorded when there is an hourly budget
+	transitioner := newTestTransitioner(t, cnr.DeepCopy())
+	transitioner.recordNodeSelections(now)
+	assert.Len(t, transitioner.cycleNodeRequest.Status.NodeSelectionTimes, 2)
+
+	// Selections older than an hour are forgotten
+	transitioner = newTestTransitioner(t, cnr.DeepCopy())
+	transitioner.options.MaxNodesCycledPerHour = 10
+	transitioner.recordNodeSelections(now)
+	assert.Equal(t, []metav1.Time{cnr.Status.NodeSelectionTimes[1], metav1.NewTime(now), metav1.NewTime(now)},
+		transitioner.cycleNodeRequest.Status.NodeSelectionTimes)
+}
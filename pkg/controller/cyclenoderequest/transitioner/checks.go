@@ -1,17 +1,26 @@
 package transitioner
 
 import (
+	"bytes"
+	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"fmt"
 	"html/template"
+	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"os"
 	"regexp"
 	"strings"
 
 	v1 "github.com/atlassian-labs/cyclops/pkg/apis/atlassian/v1"
+	"github.com/atlassian-labs/cyclops/pkg/checks"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -69,25 +78,26 @@ func buildHealthCheckEndpoint(node v1.CycleNodeRequestNode, endpoint string) (st
 	return renderedEndpoint.String(), nil
 }
 
-// Build a http client which contains the root CA and certs configured as environment
-// variables. The environment variables have already been validated, need to check
-// again in here.
-func (t *CycleNodeRequestTransitioner) buildHttpClient(tlsConfig v1.TLSConfig) (*http.Client, error) {
+// Build a tls config which contains the root CA and certs configured as environment
+// variables, or in the Secret of the tls config. The environment variables have already
+// been validated, need to check again in here.
+func (t *CycleNodeRequestTransitioner) buildTLSConfig(tlsConfig v1.TLSConfig) (*tls.Config, error) {
 	config := &tls.Config{}
 
-	rootCA, ok := os.LookupEnv(tlsConfig.RootCA)
-	if ok {
+	rootCA, certificate, key, err := t.lookupTLSMaterial(tlsConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	if rootCA != nil {
 		caCertPool := x509.NewCertPool()
-		caCertPool.AppendCertsFromPEM([]byte(rootCA))
+		caCertPool.AppendCertsFromPEM(rootCA)
 		config.RootCAs = caCertPool
 	}
 
 	// Both will be either configured or missing
-	certificate, ok := os.LookupEnv(tlsConfig.Certificate)
-	key, _ := os.LookupEnv(tlsConfig.Key)
-
-	if ok {
-		cert, err := tls.X509KeyPair([]byte(certificate), []byte(key))
+	if certificate != nil {
+		cert, err := tls.X509KeyPair(certificate, key)
 		if err != nil {
 			return nil, fmt.Errorf("failed to load certs for client: %v", err)
 		}
@@ -95,6 +105,40 @@ func (t *CycleNodeRequestTransitioner) buildHttpClient(tlsConfig v1.TLSConfig) (
 		config.Certificates = []tls.Certificate{cert}
 	}
 
+	return config, nil
+}
+
+// lookupTLSMaterial returns the root CA, certificate and key of the tls config from its Secret if it has one,
+// otherwise from the environment variables it names. Any of them that aren't configured are nil.
+func (t *CycleNodeRequestTransitioner) lookupTLSMaterial(tlsConfig v1.TLSConfig) (rootCA, certificate, key []byte, err error) {
+	if tlsConfig.SecretName != "" {
+		secret, err := t.getSecret(tlsConfig.SecretName)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		return secret.Data[tlsSecretRootCAKey], secret.Data[corev1.TLSCertKey], secret.Data[corev1.TLSPrivateKeyKey], nil
+	}
+
+	if value, ok := os.LookupEnv(tlsConfig.RootCA); ok {
+		rootCA = []byte(value)
+	}
+	if value, ok := os.LookupEnv(tlsConfig.Certificate); ok {
+		certificate = []byte(value)
+	}
+	if value, ok := os.LookupEnv(tlsConfig.Key); ok {
+		key = []byte(value)
+	}
+	return rootCA, certificate, key, nil
+}
+
+// Build a http client which contains the root CA and certs configured as environment
+// variables or in a Secret.
+func (t *CycleNodeRequestTransitioner) buildHttpClient(tlsConfig v1.TLSConfig) (*http.Client, error) {
+	config, err := t.buildTLSConfig(tlsConfig)
+	if err != nil {
+		return nil, err
+	}
+
 	// Return the configured client and add the timeout from the "default" client
 	return &http.Client{
 		Timeout: t.rm.HttpClient.Timeout,
@@ -105,8 +149,8 @@ func (t *CycleNodeRequestTransitioner) buildHttpClient(tlsConfig v1.TLSConfig) (
 }
 
 // healthCheckPassed checks if the statusCode returned matches the set of valid status code for the health check
-// as well as if the body matches regex provided
-func healthCheckPassed(healthCheck v1.HealthCheck, statusCode uint, body []byte) error {
+// as well as if the body matches regex provided, the headers match and the body assertions pass
+func healthCheckPassed(healthCheck v1.HealthCheck, statusCode uint, header http.Header, body []byte) error {
 	// If there is not regex match string specified, don't check the body of the response
 	r, err := regexp.Compile(healthCheck.RegexMatch)
 	if err != nil {
@@ -117,36 +161,114 @@ func healthCheckPassed(healthCheck v1.HealthCheck, statusCode uint, body []byte)
 		return fmt.Errorf("regex %s did not match body %s", healthCheck.RegexMatch, string(body))
 	}
 
+	var statusCodeFound bool
 	for _, validStatusCode := range healthCheck.ValidStatusCodes {
 		if statusCode == validStatusCode {
-			return nil
+			statusCodeFound = true
+			break
 		}
 	}
 
-	return fmt.Errorf("status code %d returned, did not match expected %v", statusCode, healthCheck.ValidStatusCodes)
+	if !statusCodeFound {
+		return fmt.Errorf("status code %d returned, did not match expected %v", statusCode, healthCheck.ValidStatusCodes)
+	}
+
+	if err := checks.MatchHeaders(&healthCheck, header); err != nil {
+		return err
+	}
+
+	return checks.AssertBody(&healthCheck, body)
 }
 
 // makeRequest makes the health check request to the endpoint specified, reads the body and returns
-// the status code/body to determinate weather it passed
-func (t *CycleNodeRequestTransitioner) makeRequest(httpMethod string, httpClient *http.Client, endpoint string) (uint, []byte, error) {
-	httpReq, err := http.NewRequest(httpMethod, endpoint, nil)
+// the status code/headers/body to determinate weather it passed
+func (t *CycleNodeRequestTransitioner) makeRequest(httpMethod string, httpClient *http.Client, endpoint string, header http.Header, requestBody []byte) (uint, http.Header, []byte, error) {
+	var reader io.Reader
+	if requestBody != nil {
+		reader = bytes.NewReader(requestBody)
+	}
+
+	httpReq, err := http.NewRequest(httpMethod, endpoint, reader)
 	if err != nil {
-		return 0, nil, err
+		return 0, nil, nil, err
+	}
+	for name, values := range header {
+		httpReq.Header[name] = values
 	}
 
 	resp, err := httpClient.Do(httpReq)
 	if err != nil {
-		return 0, nil, err
+		return 0, nil, nil, err
 	}
 
 	defer resp.Body.Close()
 
-	bytes, err := ioutil.ReadAll(resp.Body)
+	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return 0, nil, err
+		return 0, nil, nil, err
 	}
 
-	return uint(resp.StatusCode), bytes, nil
+	return uint(resp.StatusCode), resp.Header, body, nil
+}
+
+// makeTCPConnection opens a tcp connection to the endpoint and closes it again. It returns an error if the connection
+// can't be made within the health check timeout.
+func (t *CycleNodeRequestTransitioner) makeTCPConnection(endpoint string) error {
+	conn, err := net.DialTimeout("tcp", endpoint, t.rm.HttpClient.Timeout)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// makeGRPCHealthCheck calls the grpc.health.v1 Health service at the endpoint and returns an error unless it reports
+// SERVING. TLS is used when the health check has a root CA or certificate configured.
+func (t *CycleNodeRequestTransitioner) makeGRPCHealthCheck(endpoint string, healthCheck v1.HealthCheck) error {
+	config, err := t.buildTLSConfig(healthCheck.TLSConfig)
+	if err != nil {
+		return err
+	}
+
+	header, err := t.buildHeaders(healthCheck.Headers)
+	if err != nil {
+		return err
+	}
+
+	transportCredentials := grpc.WithInsecure()
+	if config.RootCAs != nil || len(config.Certificates) > 0 {
+		transportCredentials = grpc.WithTransportCredentials(credentials.NewTLS(config))
+	}
+
+	ctx := context.Background()
+	if timeout := t.rm.HttpClient.Timeout; timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	conn, err := grpc.DialContext(ctx, endpoint, transportCredentials)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// The headers are sent as metadata, which uses lower case keys
+	md := metadata.MD{}
+	for name, values := range header {
+		md.Append(name, values...)
+	}
+	ctx = metadata.NewOutgoingContext(ctx, md)
+
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{Service: healthCheck.GRPCService})
+	if err != nil {
+		return err
+	}
+
+	if resp.Status != healthpb.HealthCheckResponse_SERVING {
+		return fmt.Errorf("service %q is %s", healthCheck.GRPCService, resp.Status)
+	}
+
+	return nil
 }
 
 // performHealthCheck builds the endpoint, checks that the waiting period han't been exceeded and then makes the
@@ -157,29 +279,67 @@ func (t *CycleNodeRequestTransitioner) performHealthCheck(node v1.CycleNodeReque
 		return false, fmt.Errorf("failed to build health check endpoint: %v", err)
 	}
 
+	errorAllowed, err := t.checkEndpoint(endpoint, healthCheck)
+
 	// If the wait period has been exceeded, the health check is considered to have failed
-	// Only perform this check if the anchor time is supplied
-	if anchorTime != nil && anchorTime.Add(healthCheck.WaitPeriod.Duration).Before(metav1.Now().Time) {
-		return false, fmt.Errorf("health check %s failed: didn't become healthy in time", endpoint)
+	// Only perform this check if the anchor time is supplied. Include the reason it is still failing.
+	if err != nil && anchorTime != nil && anchorTime.Add(healthCheck.WaitPeriod.Duration).Before(metav1.Now().Time) {
+		return false, fmt.Errorf("health check %s failed: didn't become healthy in time: %v", endpoint, err)
 	}
 
+	return errorAllowed, err
+}
+
+// checkEndpoint performs the health check on the endpoint with the protocol of the health check. Like
+// performHealthCheck, it returns whether an error is allowed because the check still has time to pass.
+func (t *CycleNodeRequestTransitioner) checkEndpoint(endpoint string, healthCheck v1.HealthCheck) (bool, error) {
+	var err error
+
+	// The tcp and grpc protocols don't make a http request
+	switch healthCheck.Protocol {
+	case v1.HealthCheckProtocolTCP:
+		err = t.makeTCPConnection(endpoint)
+	case v1.HealthCheckProtocolGRPC:
+		err = t.makeGRPCHealthCheck(endpoint, healthCheck)
+	default:
+		return t.performHttpHealthCheck(endpoint, healthCheck)
+	}
+
+	// Log any error but don't fail the cycle, the workload is allowed time to start up
+	if err != nil {
+		t.rm.Logger.Error(err, "Health check failed", "endpoint", endpoint, "protocol", healthCheck.Protocol)
+		return true, fmt.Errorf("%s health check did not pass for the endpoint %s: %v", healthCheck.Protocol, endpoint, err)
+	}
+
+	t.rm.Logger.Info("Health check passed", "endpoint", endpoint, "protocol", healthCheck.Protocol)
+	return true, nil
+}
+
+// performHttpHealthCheck makes the http request for the health check and checks the response. Like performHealthCheck,
+// it returns whether an error is allowed because the check still has time to pass.
+func (t *CycleNodeRequestTransitioner) performHttpHealthCheck(endpoint string, healthCheck v1.HealthCheck) (bool, error) {
 	httpClient, err := t.buildHttpClient(healthCheck.TLSConfig)
 	if err != nil {
 		return false, fmt.Errorf("failed to build http client: %v", err)
 	}
 
+	requestHeader, err := t.buildHeaders(healthCheck.Headers)
+	if err != nil {
+		return false, fmt.Errorf("failed to build http headers: %v", err)
+	}
+
 	// Perform the health check and log any error but don't fail the cycle
 	// If a workload which is being checked has not started up yet, it should be allowed time to do so
 	// as configured in the Nodegroup spec
-	statusCode, body, err := t.makeRequest(http.MethodGet, httpClient, endpoint)
+	statusCode, header, body, err := t.makeRequest(http.MethodGet, httpClient, endpoint, requestHeader, nil)
 	if err != nil {
 		t.rm.Logger.Error(err, "Health check failed", "endpoint", endpoint, "error", err)
 		return true, fmt.Errorf("health check failed: %v", err)
 	}
 
 	// Still within the waiting period here, must trigger requeueing this phase
-	if err := healthCheckPassed(healthCheck, statusCode, body); err != nil {
-		return true, fmt.Errorf("health check did not pass for the endpoint %s, got: (%d) %s", endpoint, statusCode, string(body))
+	if err := healthCheckPassed(healthCheck, statusCode, header, body); err != nil {
+		return true, fmt.Errorf("health check did not pass for the endpoint %s: %v", endpoint, err)
 	}
 
 	t.rm.Logger.Info("Health check passed", "endpoint", endpoint)
@@ -233,6 +393,12 @@ func (t *CycleNodeRequestTransitioner) performInitialHealthChecks(kubeNodes []co
 				return fmt.Errorf("initial: %v", err)
 			}
 		}
+
+		for _, healthCheck := range t.cycleNodeRequest.Spec.KubernetesHealthChecks {
+			if _, err := t.performKubernetesHealthCheck(node, healthCheck, nil); err != nil {
+				return fmt.Errorf("initial: %v", err)
+			}
+		}
 	}
 
 	return nil
@@ -242,6 +408,7 @@ func (t *CycleNodeRequestTransitioner) performInitialHealthChecks(kubeNodes []co
 // until all health checks pass for the new instance before terminating the old one
 func (t *CycleNodeRequestTransitioner) performCyclingHealthChecks(kubeNodes []corev1.Node) (bool, error) {
 	var allHealthChecksPassed bool = true
+	var waitingMessage string
 
 	// Find new instsances attached to the nodegroup and perform health checks on them
 	// before terminating the old ones they are replacing
@@ -258,7 +425,8 @@ func (t *CycleNodeRequestTransitioner) performCyclingHealthChecks(kubeNodes []co
 		// Do not add set Skip=true or else they will be skipped as part of the health checks below
 		if !ok {
 			healthChecksStatus = v1.HealthCheckStatus{
-				Checks: make([]bool, len(t.cycleNodeRequest.Spec.HealthChecks)),
+				Checks:           make([]bool, len(t.cycleNodeRequest.Spec.HealthChecks)),
+				KubernetesChecks: make([]bool, len(t.cycleNodeRequest.Spec.KubernetesHealthChecks)),
 			}
 
 			t.cycleNodeRequest.Status.HealthChecks[nodeHash] = healthChecksStatus
@@ -289,11 +457,15 @@ func (t *CycleNodeRequestTransitioner) performCyclingHealthChecks(kubeNodes []co
 
 			// If the error is not allowed then the cycling should fail
 			if !errorAllowed && err != nil {
+				t.rm.LogWarningEvent(t.cycleNodeRequest, "HealthCheckFailed", "Health check failed on node %s: %v", node.Name, err)
 				return false, fmt.Errorf("cycling: %v", err)
 			}
 
 			// If the error is allowed, log out the error and continue to the next health check
 			if err != nil {
+				if allHealthChecksPassed {
+					waitingMessage = fmt.Sprintf("Waiting for health checks on node %s: %v", node.Name, err)
+				}
 				allHealthChecksPassed = false
 				continue
 			}
@@ -302,11 +474,73 @@ func (t *CycleNodeRequestTransitioner) performCyclingHealthChecks(kubeNodes []co
 			healthChecksStatus.Checks[i] = true
 			t.cycleNodeRequest.Status.HealthChecks[nodeHash] = healthChecksStatus
 		}
+
+		for i, healthCheck := range t.cycleNodeRequest.Spec.KubernetesHealthChecks {
+			// If the health check has already passed, skip it
+			if healthChecksStatus.KubernetesChecks[i] {
+				continue
+			}
+
+			errorAllowed, err := t.performKubernetesHealthCheck(node, healthCheck, healthChecksStatus.NodeReady)
+
+			// If the error is not allowed then the cycling should fail
+			if !errorAllowed && err != nil {
+				t.rm.LogWarningEvent(t.cycleNodeRequest, "HealthCheckFailed", "Health check failed on node %s: %v", node.Name, err)
+				return false, fmt.Errorf("cycling: %v", err)
+			}
+
+			// If the error is allowed, log out the error and continue to the next health check
+			if err != nil {
+				if allHealthChecksPassed {
+					waitingMessage = fmt.Sprintf("Waiting for health checks on node %s: %v", node.Name, err)
+				}
+				allHealthChecksPassed = false
+				continue
+			}
+
+			// Update after each check passes in case the next one returns an error
+			healthChecksStatus.KubernetesChecks[i] = true
+			t.cycleNodeRequest.Status.HealthChecks[nodeHash] = healthChecksStatus
+		}
+	}
+
+	if allHealthChecksPassed {
+		t.setCondition(v1.CycleNodeRequestConditionHealthChecksPassing, metav1.ConditionTrue, "HealthChecksPassed", "Health checks on the new nodes have passed")
+	} else {
+		t.setCondition(v1.CycleNodeRequestConditionHealthChecksPassing, metav1.ConditionFalse, "WaitingHealthChecks", waitingMessage)
 	}
 
 	return allHealthChecksPassed, nil
 }
 
+// performBakingHealthChecks repeats the health checks on the new nodes while a batch of the rollout is baking.
+// The new nodes have already passed them once, so there is no wait period and any failure is returned.
+func (t *CycleNodeRequestTransitioner) performBakingHealthChecks(kubeNodes []corev1.Node) error {
+	for _, kubeNode := range kubeNodes {
+		node := getCycleRequestNode(kubeNode)
+
+		// Only check the new nodes, which have a status without Skip from the ScalingUp phase
+		healthChecksStatus, ok := t.cycleNodeRequest.Status.HealthChecks[getNodeHash(node)]
+		if !ok || healthChecksStatus.Skip {
+			continue
+		}
+
+		for _, healthCheck := range t.cycleNodeRequest.Spec.HealthChecks {
+			if _, err := t.performHealthCheck(node, healthCheck, nil); err != nil {
+				return fmt.Errorf("baking: %v", err)
+			}
+		}
+
+		for _, healthCheck := range t.cycleNodeRequest.Spec.KubernetesHealthChecks {
+			if _, err := t.performKubernetesHealthCheck(node, healthCheck, nil); err != nil {
+				return fmt.Errorf("baking: %v", err)
+			}
+		}
+	}
+
+	return nil
+}
+
 // sendPreTerminationTrigger sends a http request as a trigger. When this is done, the upstream host
 // will know that the associated node is going to be terminated and so it should begin it's own
 // shutdown process before that begins. This can be thought of as a http sigterm.
@@ -335,40 +569,106 @@ func (t *CycleNodeRequestTransitioner) sendPreTerminationTrigger(node v1.CycleNo
 			continue
 		}
 
-		endpoint, err := buildHealthCheckEndpoint(node, preTerminationCheck.Endpoint)
+		err := t.sendPreTerminationRequest(node, preTerminationCheck.TLSConfig, preTerminationCheck.Method,
+			preTerminationCheck.Endpoint, preTerminationCheck.Headers, preTerminationCheck.Body, preTerminationCheck.ValidStatusCodes)
 		if err != nil {
-			return fmt.Errorf("failed to build health check endpoint: %v", err)
+			return fmt.Errorf("sending trigger failed: %v", err)
 		}
 
-		httpClient, err := t.buildHttpClient(preTerminationCheck.TLSConfig)
-		if err != nil {
-			return fmt.Errorf("failed to build http client: %v", err)
-		}
+		now := metav1.Now()
+		status.Checks[i].Trigger = &now
+		t.cycleNodeRequest.Status.PreTerminationChecks[nodeHash] = status
+	}
 
-		// Send the trigger, disregard the response body
-		statusCode, _, err := t.makeRequest(http.MethodPost, httpClient, endpoint)
-		if err != nil {
-			return fmt.Errorf("sending trigger failed: %v", err)
+	return nil
+}
+
+// sendPreTerminationCancels sends the cancel request of each pre-termination check to the nodes the trigger has been
+// sent to, so that the upstream host can undo the action it started. It is used when the CycleNodeRequest stops
+// before the nodes are terminated. Failures are recorded as warning events so that they don't stop the rollback.
+func (t *CycleNodeRequestTransitioner) sendPreTerminationCancels(nodes []v1.CycleNodeRequestNode) {
+	for _, node := range nodes {
+		nodeHash := getNodeHash(node)
+
+		status, ok := t.cycleNodeRequest.Status.PreTerminationChecks[nodeHash]
+		if !ok {
+			continue
 		}
 
-		var statusCodeFound bool
+		for i, preTerminationCheck := range t.cycleNodeRequest.Spec.PreTerminationChecks {
+			cancel := preTerminationCheck.Cancel
+			if cancel == nil || i >= len(status.Checks) || status.Checks[i].Trigger == nil || status.Checks[i].Cancel != nil {
+				continue
+			}
 
-		for _, validStatusCode := range preTerminationCheck.ValidStatusCodes {
-			if statusCode == validStatusCode {
-				statusCodeFound = true
+			err := t.sendPreTerminationRequest(node, preTerminationCheck.TLSConfig, cancel.Method,
+				cancel.Endpoint, cancel.Headers, cancel.Body, cancel.ValidStatusCodes)
+			if err != nil {
+				t.rm.LogWarningEvent(t.cycleNodeRequest, "PreTerminationCancelFailed",
+					"Failed to send pre-termination cancel for node %s: %v", node.Name, err)
+				continue
 			}
-		}
 
-		if !statusCodeFound {
-			return fmt.Errorf("got unexpected status code after sending trigger: %d", statusCode)
+			t.rm.LogEvent(t.cycleNodeRequest, "PreTerminationCancelled", "Sent pre-termination cancel for node %s", node.Name)
+			now := metav1.Now()
+			status.Checks[i].Cancel = &now
 		}
 
-		now := metav1.Now()
-		status.Checks[i].Trigger = &now
 		t.cycleNodeRequest.Status.PreTerminationChecks[nodeHash] = status
 	}
+}
 
-	return nil
+// sendPreTerminationRequest sends a pre-termination trigger or cancel request for the node, with the body template
+// rendered for the node, and returns an error unless the response has one of the valid status codes.
+func (t *CycleNodeRequestTransitioner) sendPreTerminationRequest(node v1.CycleNodeRequestNode, tlsConfig v1.TLSConfig,
+	method, endpoint string, headers []v1.HTTPHeader, body string, validStatusCodes []uint) error {
+	endpoint, err := buildHealthCheckEndpoint(node, endpoint)
+	if err != nil {
+		return fmt.Errorf("failed to build health check endpoint: %v", err)
+	}
+
+	httpClient, err := t.buildHttpClient(tlsConfig)
+	if err != nil {
+		return fmt.Errorf("failed to build http client: %v", err)
+	}
+
+	header, err := t.buildHeaders(headers)
+	if err != nil {
+		return fmt.Errorf("failed to build http headers: %v", err)
+	}
+
+	requestBody, err := checks.RenderRequestBody(body, checks.PreTerminationRequestData{
+		NodeName:         node.Name,
+		NodeIP:           node.PrivateIP,
+		ProviderID:       node.ProviderID,
+		NodeGroup:        node.NodeGroupName,
+		CycleNodeRequest: t.cycleNodeRequest.Name,
+	})
+	if err != nil {
+		return err
+	}
+
+	if requestBody != nil && header.Get("Content-Type") == "" {
+		header.Set("Content-Type", "application/json")
+	}
+
+	if method == "" {
+		method = http.MethodPost
+	}
+
+	// Send the request, disregard the response body
+	statusCode, _, _, err := t.makeRequest(method, httpClient, endpoint, header, requestBody)
+	if err != nil {
+		return err
+	}
+
+	for _, validStatusCode := range validStatusCodes {
+		if statusCode == validStatusCode {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("got unexpected status code: %d", statusCode)
 }
 
 // performPreTerminationHealthChecks is a health check performed on the upstream server after the trigger has been sent.
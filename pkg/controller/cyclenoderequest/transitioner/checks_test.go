@@ -0,0 +1,190 @@
+package transitioner
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+
+	v1 "github.com/atlassian-labs/cyclops/pkg/apis/atlassian/v1"
+)
+
+func TestPerformHealthCheck_Protocols(t *testing.T) {
+	// A tcp listener that accepts connections, and an address that nothing is listening on
+	tcpListener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer tcpListener.Close()
+
+	closedListener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	closedAddress := closedListener.Addr().String()
+	closedListener.Close()
+
+	// A grpc server with the standard health service
+	grpcListener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("serving", healthpb.HealthCheckResponse_SERVING)
+	healthServer.SetServingStatus("not-serving", healthpb.HealthCheckResponse_NOT_SERVING)
+	grpcServer := grpc.NewServer()
+	healthpb.RegisterHealthServer(grpcServer, healthServer)
+	go func() { _ = grpcServer.Serve(grpcListener) }()
+	defer grpcServer.Stop()
+
+	tests := []struct {
+		name         string
+		healthCheck  v1.HealthCheck
+		expectPassed bool
+	}{
+		{"tcp connects", v1.HealthCheck{Protocol: v1.HealthCheckProtocolTCP, Endpoint: tcpListener.Addr().String()}, true},
+		{"tcp refused", v1.HealthCheck{Protocol: v1.HealthCheckProtocolTCP, Endpoint: closedAddress}, false},
+		{"grpc server serving", v1.HealthCheck{Protocol: v1.HealthCheckProtocolGRPC, Endpoint: grpcListener.Addr().String()}, true},
+		{"grpc service serving", v1.HealthCheck{Protocol: v1.HealthCheckProtocolGRPC, Endpoint: grpcListener.Addr().String(), GRPCService: "serving"}, true},
+		{"grpc service not serving", v1.HealthCheck{Protocol: v1.HealthCheckProtocolGRPC, Endpoint: grpcListener.Addr().String(), GRPCService: "not-serving"}, false},
+		{"grpc service unknown", v1.HealthCheck{Protocol: v1.HealthCheckProtocolGRPC, Endpoint: grpcListener.Addr().String(), GRPCService: "unknown"}, false},
+		{"grpc unavailable", v1.HealthCheck{Protocol: v1.HealthCheckProtocolGRPC, Endpoint: closedAddress}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cnr := newTestCycleNodeRequest(v1.CycleNodeRequestScalingUp)
+			transitioner := newTestTransitioner(t, cnr)
+			transitioner.rm.HttpClient = &http.Client{Timeout: 5 * time.Second}
+
+			tt.healthCheck.WaitPeriod = &metav1.Duration{Duration: time.Minute}
+			anchorTime := metav1.Now()
+			errorAllowed, err := transitioner.performHealthCheck(v1.CycleNodeRequestNode{Name: "new-node"}, tt.healthCheck, &anchorTime)
+			assert.True(t, errorAllowed)
+			assert.Equal(t, tt.expectPassed, err == nil, "error: %v", err)
+		})
+	}
+}
+
+func TestPerformHealthCheck_Assertions(t *testing.T) {
+	status := "starting"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Node-Status", status)
+		fmt.Fprintf(w, `{"status": %q, "peers": ["a", "b", "c"]}`, status)
+	}))
+	defer server.Close()
+
+	healthCheck := v1.HealthCheck{
+		Endpoint:         server.URL,
+		WaitPeriod:       &metav1.Duration{Duration: time.Minute},
+		ValidStatusCodes: []uint{http.StatusOK},
+		BodyAssertions:   []string{`.peers | length >= 3`, `.status == "ok"`},
+		HeaderMatch:      map[string]string{"X-Node-Status": ".+"},
+	}
+
+	tests := []struct {
+		name               string
+		status             string
+		anchorTime         time.Duration
+		expectErrorAllowed bool
+		expectError        string
+	}{
+		{"assertion fails within the wait period", "starting", 0, true, "assertion `.status == \"ok\"` failed: got \"starting\""},
+		{"assertion fails after the wait period", "starting", -time.Hour, false, "didn't become healthy in time: health check did not pass for the endpoint " + server.URL + ": assertion `.status == \"ok\"` failed"},
+		{"header fails", "", 0, true, "header X-Node-Status"},
+		{"passes", "ok", 0, true, ""},
+		{"passes at the end of the wait period", "ok", -time.Hour, true, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status = tt.status
+			cnr := newTestCycleNodeRequest(v1.CycleNodeRequestScalingUp)
+			transitioner := newTestTransitioner(t, cnr)
+			transitioner.rm.HttpClient = server.Client()
+
+			anchorTime := metav1.NewTime(time.Now().Add(tt.anchorTime))
+			errorAllowed, err := transitioner.performHealthCheck(v1.CycleNodeRequestNode{Name: "new-node"}, healthCheck, &anchorTime)
+			assert.Equal(t, tt.expectErrorAllowed, errorAllowed)
+			if tt.expectError == "" {
+				assert.NoError(t, err)
+			} else if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), tt.expectError)
+			}
+		})
+	}
+}
+
+func TestPreTerminationTriggerAndCancel(t *testing.T) {
+	type request struct {
+		method      string
+		path        string
+		contentType string
+		body        string
+	}
+	var requests []request
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		requests = append(requests, request{r.Method, r.URL.Path, r.Header.Get("Content-Type"), string(body)})
+	}))
+	defer server.Close()
+
+	node1 := v1.CycleNodeRequestNode{Name: "node-1", ProviderID: "aws:///us-east-1a/i-1", PrivateIP: "10.0.0.1", NodeGroupName: "nodegroup"}
+	node2 := v1.CycleNodeRequestNode{Name: "node-2", ProviderID: "aws:///us-east-1a/i-2", PrivateIP: "10.0.0.2", NodeGroupName: "nodegroup"}
+
+	cnr := newTestCycleNodeRequest(v1.CycleNodeRequestHealing)
+	cnr.Status.CurrentNodes = []v1.CycleNodeRequestNode{node1, node2}
+	cnr.Spec.PreTerminationChecks = []v1.PreTerminationCheck{{
+		Endpoint:         server.URL + "/trigger",
+		Method:           http.MethodPut,
+		Body:             `{"node": {{ json .NodeName }}, "ip": "{{ .NodeIP }}", "group": {{ json .NodeGroup }}, "cnr": {{ json .CycleNodeRequest }}}`,
+		ValidStatusCodes: []uint{http.StatusOK},
+		Cancel: &v1.PreTerminationCancel{
+			Endpoint:         server.URL + "/cancel",
+			Method:           http.MethodDelete,
+			Body:             `{"providerID": {{ json .ProviderID }}}`,
+			ValidStatusCodes: []uint{http.StatusOK},
+		},
+	}}
+
+	// node-2 has already been handed off to be terminated
+	draining := &v1.CycleNodeStatus{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-node-2", Namespace: "kube-system", Labels: map[string]string{"name": "test"}},
+		Spec:       v1.CycleNodeStatusSpec{NodeName: "node-2"},
+	}
+	transitioner := newTestTransitioner(t, cnr, draining)
+	transitioner.rm.HttpClient = server.Client()
+	transitioner.rm.CloudProvider = &testCloudProvider{attached: map[string]string{}}
+	transitioner.rm.RawClient = fakeclientset.NewSimpleClientset(
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}},
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-2"}},
+	)
+
+	// The trigger is only sent once
+	assert.NoError(t, transitioner.sendPreTerminationTrigger(node1))
+	assert.NoError(t, transitioner.sendPreTerminationTrigger(node1))
+	assert.NoError(t, transitioner.sendPreTerminationTrigger(node2))
+	assert.Len(t, requests, 2)
+	assert.Equal(t, http.MethodPut, requests[0].method)
+	assert.Equal(t, "/trigger", requests[0].path)
+	assert.Equal(t, "application/json", requests[0].contentType)
+	assert.JSONEq(t, `{"node": "node-1", "ip": "10.0.0.1", "group": "nodegroup", "cnr": "test"}`, requests[0].body)
+
+	// Healing cancels the trigger of the node that won't be terminated
+	_, err := transitioner.transitionHealing()
+	assert.NoError(t, err)
+	assert.Len(t, requests, 3)
+	assert.Equal(t, request{http.MethodDelete, "/cancel", "application/json", `{"providerID": "aws:///us-east-1a/i-1"}`}, requests[2])
+	assert.NotNil(t, cnr.Status.PreTerminationChecks[getNodeHash(node1)].Checks[0].Cancel)
+	assert.Nil(t, cnr.Status.PreTerminationChecks[getNodeHash(node2)].Checks[0].Cancel)
+
+	// The cancel is only sent once
+	transitioner.sendPreTerminationCancels([]v1.CycleNodeRequestNode{node1})
+	assert.Len(t, requests, 3)
+}
@@ -0,0 +1,151 @@
+package transitioner
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	v1 "github.com/atlassian-labs/cyclops/pkg/apis/atlassian/v1"
+	"github.com/atlassian-labs/cyclops/pkg/k8s"
+)
+
+// hasHealthChecks returns true if the CycleNodeRequest has any http or Kubernetes health checks on its nodes
+func (t *CycleNodeRequestTransitioner) hasHealthChecks() bool {
+	return len(t.cycleNodeRequest.Spec.HealthChecks) > 0 || len(t.cycleNodeRequest.Spec.KubernetesHealthChecks) > 0
+}
+
+// performKubernetesHealthCheck checks that the waiting period hasn't been exceeded and then evaluates the Kubernetes
+// health check for the node against the API server. Like performHealthCheck, it returns whether an error is allowed
+// because the check still has time to pass.
+func (t *CycleNodeRequestTransitioner) performKubernetesHealthCheck(node v1.CycleNodeRequestNode, healthCheck v1.KubernetesHealthCheck, anchorTime *metav1.Time) (bool, error) {
+	// If the wait period has been exceeded, the health check is considered to have failed
+	// Only perform this check if the anchor time is supplied
+	if anchorTime != nil && healthCheck.WaitPeriod != nil && anchorTime.Add(healthCheck.WaitPeriod.Duration).Before(metav1.Now().Time) {
+		return false, fmt.Errorf("health check %s failed: didn't become healthy in time", healthCheck.String())
+	}
+
+	var err error
+	switch healthCheck.Type {
+	case v1.KubernetesHealthCheckDaemonSetPodsReady:
+		err = t.checkDaemonSetPodsReady(node, healthCheck)
+	case v1.KubernetesHealthCheckServiceReadyEndpoints:
+		err = t.checkServiceReadyEndpoints(healthCheck)
+	case v1.KubernetesHealthCheckDeploymentAvailable:
+		err = t.checkDeploymentAvailable(healthCheck)
+	default:
+		return false, fmt.Errorf("unknown kubernetes health check type %q", healthCheck.Type)
+	}
+
+	// Still within the waiting period here, must trigger requeueing this phase
+	if err != nil {
+		return true, fmt.Errorf("health check %s did not pass for node %s: %v", healthCheck.String(), node.Name, err)
+	}
+
+	t.rm.Logger.Info("Health check passed", "check", healthCheck.String(), "node", node.Name)
+	return true, nil
+}
+
+// checkDaemonSetPodsReady returns an error if any of the DaemonSet pods on the node are not Ready. If the check names a
+// DaemonSet, only its pods are checked and one of them must be on the node.
+func (t *CycleNodeRequestTransitioner) checkDaemonSetPodsReady(node v1.CycleNodeRequestNode, healthCheck v1.KubernetesHealthCheck) error {
+	pods, err := t.rm.GetPodsOnNode(node.Name)
+	if err != nil {
+		return err
+	}
+
+	var found bool
+	for _, pod := range pods {
+		if pod.Spec.NodeName != node.Name || !k8s.PodIsDaemonSet(&pod) {
+			continue
+		}
+
+		if healthCheck.Namespace != "" && pod.Namespace != healthCheck.Namespace {
+			continue
+		}
+
+		if healthCheck.Name != "" && !podOwnedBy(pod, "DaemonSet", healthCheck.Name) {
+			continue
+		}
+
+		found = true
+		if !podReady(pod) {
+			return fmt.Errorf("pod %s/%s is not ready", pod.Namespace, pod.Name)
+		}
+	}
+
+	if !found && healthCheck.Name != "" {
+		return fmt.Errorf("no pods of DaemonSet %s/%s are on the node", healthCheck.Namespace, healthCheck.Name)
+	}
+
+	return nil
+}
+
+// checkServiceReadyEndpoints returns an error if the Service has fewer than the minimum number of ready endpoints
+func (t *CycleNodeRequestTransitioner) checkServiceReadyEndpoints(healthCheck v1.KubernetesHealthCheck) error {
+	var endpoints corev1.Endpoints
+	key := types.NamespacedName{Namespace: healthCheck.Namespace, Name: healthCheck.Name}
+	if err := t.rm.Client.Get(context.TODO(), key, &endpoints); err != nil {
+		return err
+	}
+
+	var ready int32
+	for _, subset := range endpoints.Subsets {
+		ready += int32(len(subset.Addresses))
+	}
+
+	minReady := healthCheck.MinReadyEndpoints
+	if minReady <= 0 {
+		minReady = 1
+	}
+
+	if ready < minReady {
+		return fmt.Errorf("%d ready endpoints, expected at least %d", ready, minReady)
+	}
+
+	return nil
+}
+
+// checkDeploymentAvailable returns an error if the Deployment has fewer available replicas than it desires
+func (t *CycleNodeRequestTransitioner) checkDeploymentAvailable(healthCheck v1.KubernetesHealthCheck) error {
+	var deployment appsv1.Deployment
+	key := types.NamespacedName{Namespace: healthCheck.Namespace, Name: healthCheck.Name}
+	if err := t.rm.Client.Get(context.TODO(), key, &deployment); err != nil {
+		return err
+	}
+
+	// Replicas defaults to 1 when it isn't set
+	var desired int32 = 1
+	if deployment.Spec.Replicas != nil {
+		desired = *deployment.Spec.Replicas
+	}
+
+	if deployment.Status.AvailableReplicas < desired {
+		return fmt.Errorf("%d available replicas, expected %d", deployment.Status.AvailableReplicas, desired)
+	}
+
+	return nil
+}
+
+// podOwnedBy returns true if the pod is owned by an object of the given kind and name
+func podOwnedBy(pod corev1.Pod, kind, name string) bool {
+	for _, ownerReference := range pod.OwnerReferences {
+		if ownerReference.Kind == kind && ownerReference.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// podReady returns true if the pod has the Ready condition
+func podReady(pod corev1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
@@ -0,0 +1,140 @@
+package transitioner
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+
+	v1 "github.com/atlassian-labs/cyclops/pkg/apis/atlassian/v1"
+)
+
+func newTestDaemonSetPod(name, nodeName, daemonSet string, ready bool) *corev1.Pod {
+	status := corev1.ConditionFalse
+	if ready {
+		status = corev1.ConditionTrue
+	}
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			Namespace:       "kube-system",
+			OwnerReferences: []metav1.OwnerReference{{Kind: "DaemonSet", Name: daemonSet}},
+		},
+		Spec: corev1.PodSpec{NodeName: nodeName},
+		Status: corev1.PodStatus{
+			Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: status}},
+		},
+	}
+}
+
+func TestPerformKubernetesHealthCheck(t *testing.T) {
+	replicas := int32(3)
+	waitPeriod := &metav1.Duration{Duration: 10 * time.Minute}
+
+	objects := []runtime.Object{
+		newTestDaemonSetPod("agent-new", "new-node", "agent", true),
+		newTestDaemonSetPod("logs-new", "new-node", "logs", false),
+		newTestDaemonSetPod("logs-other", "other-node", "logs", true),
+		&corev1.Endpoints{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+			Subsets: []corev1.EndpointSubset{
+				{Addresses: []corev1.EndpointAddress{{IP: "10.0.0.1"}, {IP: "10.0.0.2"}}},
+				{Addresses: []corev1.EndpointAddress{{IP: "10.0.0.3"}}, NotReadyAddresses: []corev1.EndpointAddress{{IP: "10.0.0.4"}}},
+			},
+		},
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "available", Namespace: "default"},
+			Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+			Status:     appsv1.DeploymentStatus{AvailableReplicas: 3},
+		},
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "unavailable", Namespace: "default"},
+			Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+			Status:     appsv1.DeploymentStatus{AvailableReplicas: 2},
+		},
+	}
+
+	tests := []struct {
+		name         string
+		node         string
+		check        v1.KubernetesHealthCheck
+		expectPassed bool
+	}{
+		{"all daemonset pods ready", "new-node", v1.KubernetesHealthCheck{Type: v1.KubernetesHealthCheckDaemonSetPodsReady}, false},
+		{"named daemonset ready", "new-node", v1.KubernetesHealthCheck{Type: v1.KubernetesHealthCheckDaemonSetPodsReady, Namespace: "kube-system", Name: "agent"}, true},
+		{"named daemonset not ready", "new-node", v1.KubernetesHealthCheck{Type: v1.KubernetesHealthCheckDaemonSetPodsReady, Namespace: "kube-system", Name: "logs"}, false},
+		{"named daemonset missing from node", "other-node", v1.KubernetesHealthCheck{Type: v1.KubernetesHealthCheckDaemonSetPodsReady, Namespace: "kube-system", Name: "agent"}, false},
+		{"service ready endpoints default", "new-node", v1.KubernetesHealthCheck{Type: v1.KubernetesHealthCheckServiceReadyEndpoints, Namespace: "default", Name: "web"}, true},
+		{"service enough ready endpoints", "new-node", v1.KubernetesHealthCheck{Type: v1.KubernetesHealthCheckServiceReadyEndpoints, Namespace: "default", Name: "web", MinReadyEndpoints: 3}, true},
+		{"service not enough ready endpoints", "new-node", v1.KubernetesHealthCheck{Type: v1.KubernetesHealthCheckServiceReadyEndpoints, Namespace: "default", Name: "web", MinReadyEndpoints: 4}, false},
+		{"service missing", "new-node", v1.KubernetesHealthCheck{Type: v1.KubernetesHealthCheckServiceReadyEndpoints, Namespace: "default", Name: "missing"}, false},
+		{"deployment available", "new-node", v1.KubernetesHealthCheck{Type: v1.KubernetesHealthCheckDeploymentAvailable, Namespace: "default", Name: "available"}, true},
+		{"deployment unavailable", "new-node", v1.KubernetesHealthCheck{Type: v1.KubernetesHealthCheckDeploymentAvailable, Namespace: "default", Name: "unavailable"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cnr := newTestCycleNodeRequest(v1.CycleNodeRequestScalingUp)
+			transitioner := newTestTransitioner(t, cnr, objects...)
+
+			tt.check.WaitPeriod = waitPeriod
+			anchorTime := metav1.Now()
+			errorAllowed, err := transitioner.performKubernetesHealthCheck(v1.CycleNodeRequestNode{Name: tt.node}, tt.check, &anchorTime)
+			assert.True(t, errorAllowed)
+			assert.Equal(t, tt.expectPassed, err == nil)
+
+			// Once the wait period has passed the error is no longer allowed
+			if !tt.expectPassed {
+				anchorTime = metav1.NewTime(time.Now().Add(-time.Hour))
+				errorAllowed, err = transitioner.performKubernetesHealthCheck(v1.CycleNodeRequestNode{Name: tt.node}, tt.check, &anchorTime)
+				assert.False(t, errorAllowed)
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestPerformCyclingHealthChecks_Kubernetes(t *testing.T) {
+	newNode := newTestZoneNode("new-node", "zone-a", nil)
+	pod := newTestDaemonSetPod("agent-new", "new-node", "agent", false)
+
+	cnr := newTestCycleNodeRequest(v1.CycleNodeRequestScalingUp)
+	cnr.Spec.KubernetesHealthChecks = []v1.KubernetesHealthCheck{{
+		Type:       v1.KubernetesHealthCheckDaemonSetPodsReady,
+		WaitPeriod: &metav1.Duration{Duration: 10 * time.Minute},
+	}}
+	cnr.Status.HealthChecks = map[string]v1.HealthCheckStatus{}
+
+	transitioner := newTestTransitioner(t, cnr, pod)
+
+	passed, err := transitioner.performCyclingHealthChecks([]corev1.Node{*newNode})
+	assert.NoError(t, err)
+	assert.False(t, passed)
+
+	status := cnr.Status.HealthChecks[getNodeHash(getCycleRequestNode(*newNode))]
+	assert.Equal(t, []bool{false}, status.KubernetesChecks)
+	assert.NotNil(t, status.NodeReady)
+
+	// Waiting is only reported in the condition, rather than with an event each time the checks are performed
+	condition := meta.FindStatusCondition(cnr.Status.Conditions, v1.CycleNodeRequestConditionHealthChecksPassing)
+	if assert.NotNil(t, condition) {
+		assert.Equal(t, "WaitingHealthChecks", condition.Reason)
+	}
+	assert.Empty(t, transitioner.rm.Recorder.(*record.FakeRecorder).Events)
+
+	// The check passes once the pod becomes ready, and is recorded in the status
+	pod.Status.Conditions[0].Status = corev1.ConditionTrue
+	assert.NoError(t, transitioner.rm.Client.Update(context.TODO(), pod))
+
+	passed, err = transitioner.performCyclingHealthChecks([]corev1.Node{*newNode})
+	assert.NoError(t, err)
+	assert.True(t, passed)
+	assert.Equal(t, []bool{true}, cnr.Status.HealthChecks[getNodeHash(getCycleRequestNode(*newNode))].KubernetesChecks)
+}
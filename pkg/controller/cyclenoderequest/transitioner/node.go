@@ -54,38 +54,61 @@ func (t *CycleNodeRequestTransitioner) getNodesToTerminate(numNodes int64) (node
 		return nil, 0, err
 	}
 
+	kubeNodesByName := make(map[string]*corev1.Node, len(kubeNodes))
+	for i, kubeNode := range kubeNodes {
+		kubeNodesByName[kubeNode.Name] = &kubeNodes[i]
+	}
+
+	// Find the nodes that need to be terminated but have not yet been actioned
+	var candidates []*corev1.Node
+	inProgressZones := make(map[string]int)
 	for _, nodeToTerminate := range t.cycleNodeRequest.Status.NodesToTerminate {
-		for _, kubeNode := range kubeNodes {
-			// Skip nodes that are already being worked on so we don't duplicate our work
-			if value, ok := kubeNode.Labels[cycleNodeLabel]; ok && value == t.cycleNodeRequest.Name {
-				numNodesInProgress++
-				break
-			}
+		kubeNode, ok := kubeNodesByName[nodeToTerminate.Name]
+		if !ok || kubeNode.Spec.ProviderID != nodeToTerminate.ProviderID {
+			continue
+		}
 
-			// Add nodes that need to be terminated but have not yet been actioned
-			if kubeNode.Name == nodeToTerminate.Name && kubeNode.Spec.ProviderID == nodeToTerminate.ProviderID {
-				nodes = append(nodes, &kubeNode)
+		// Skip nodes that are already being worked on so we don't duplicate our work
+		if value, ok := kubeNode.Labels[cycleNodeLabel]; ok && value == t.cycleNodeRequest.Name {
+			numNodesInProgress++
+			inProgressZones[nodeZone(kubeNode)]++
+			continue
+		}
 
-				for i := 0; i < len(t.cycleNodeRequest.Status.NodesAvailable); i++ {
-					if kubeNode.Name == t.cycleNodeRequest.Status.NodesAvailable[i].Name {
-						// Remove nodes from available if they are also scheduled for termination
-						// Slice syntax removes this node at `i` from the array
-						t.cycleNodeRequest.Status.NodesAvailable = append(
-							t.cycleNodeRequest.Status.NodesAvailable[:i],
-							t.cycleNodeRequest.Status.NodesAvailable[i+1:]...,
-						)
+		candidates = append(candidates, kubeNode)
+	}
 
-						break
-					}
-				}
+	// Put the nodes in the order they should be cycled in, then select up to the desired amount following the
+	// zone strategy
+	if err := t.orderNodes(candidates); err != nil {
+		return nil, 0, err
+	}
+	nodes = selectNodesByZone(t.cycleNodeRequest.Spec.CycleSettings.ZoneStrategy, candidates, inProgressZones, numNodes)
 
-				break
-			}
+	// Record the nodes left to cycle after these, so that it can be seen what is coming next
+	selected := make(map[string]bool, len(nodes))
+	for _, node := range nodes {
+		selected[node.Name] = true
+	}
+	t.cycleNodeRequest.Status.PlannedOrder = nil
+	for _, node := range candidates {
+		if !selected[node.Name] {
+			t.cycleNodeRequest.Status.PlannedOrder = append(t.cycleNodeRequest.Status.PlannedOrder, node.Name)
 		}
+	}
 
-		// Stop finding nodes once we reach the desired amount
-		if int64(len(nodes)) >= numNodes {
-			break
+	// Remove the selected nodes from available as they are now scheduled for termination
+	for _, node := range nodes {
+		for i := 0; i < len(t.cycleNodeRequest.Status.NodesAvailable); i++ {
+			if node.Name == t.cycleNodeRequest.Status.NodesAvailable[i].Name {
+				// Slice syntax removes this node at `i` from the array
+				t.cycleNodeRequest.Status.NodesAvailable = append(
+					t.cycleNodeRequest.Status.NodesAvailable[:i],
+					t.cycleNodeRequest.Status.NodesAvailable[i+1:]...,
+				)
+
+				break
+			}
 		}
 	}
 
@@ -140,5 +163,6 @@ func newCycleNodeRequestNode(kubeNode *corev1.Node, nodeGroupName string) v1.Cyc
 		ProviderID:    kubeNode.Spec.ProviderID,
 		NodeGroupName: nodeGroupName,
 		PrivateIP:     privateIP,
+		Zone:          nodeZone(kubeNode),
 	}
 }
@@ -0,0 +1,136 @@
+package transitioner
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "github.com/atlassian-labs/cyclops/pkg/apis/atlassian/v1"
+	"github.com/atlassian-labs/cyclops/pkg/checks"
+)
+
+const (
+	// prometheusCheckTimeout limits how long each Prometheus query can take
+	prometheusCheckTimeout = 10 * time.Second
+
+	// prometheusCheckStep is the resolution of the range queries for Prometheus checks with a window
+	prometheusCheckStep = 30 * time.Second
+)
+
+// performPrometheusChecks evaluates the Prometheus checks of the CycleNodeRequest and sets the PrometheusChecksPassing
+// condition. It returns a message describing the first check that did not pass, or an error if a check could not be
+// evaluated. The time the checks started failing to be evaluated is recorded for prometheusChecksErrorTimedOut. It
+// doesn't save the CycleNodeRequest.
+func (t *CycleNodeRequestTransitioner) performPrometheusChecks(now time.Time) (passed bool, message string, err error) {
+	if len(t.cycleNodeRequest.Spec.PrometheusChecks) == 0 {
+		return true, "", nil
+	}
+
+	defer func() {
+		if err == nil {
+			t.cycleNodeRequest.Status.PrometheusChecksErroringSince = nil
+		} else if t.cycleNodeRequest.Status.PrometheusChecksErroringSince == nil {
+			erroringSince := metav1.NewTime(now)
+			t.cycleNodeRequest.Status.PrometheusChecksErroringSince = &erroringSince
+		}
+
+		switch {
+		case err != nil:
+			t.setCondition(v1.CycleNodeRequestConditionPrometheusChecksPassing, metav1.ConditionFalse, "PrometheusCheckError", err.Error())
+		case !passed:
+			t.setCondition(v1.CycleNodeRequestConditionPrometheusChecksPassing, metav1.ConditionFalse, "PrometheusChecksFailing", message)
+		default:
+			t.setCondition(v1.CycleNodeRequestConditionPrometheusChecksPassing, metav1.ConditionTrue, "PrometheusChecksPassed", "Prometheus checks have passed")
+		}
+	}()
+
+	if t.options.PrometheusAPI == nil {
+		return false, "", fmt.Errorf("prometheus checks require the controller to be given the address of Prometheus")
+	}
+
+	for _, check := range t.cycleNodeRequest.Spec.PrometheusChecks {
+		values, err := t.queryPrometheusCheck(t.options.PrometheusAPI, check, now)
+		if err != nil {
+			return false, "", fmt.Errorf("prometheus check %s failed to query: %v", check.Name, err)
+		}
+
+		for _, value := range values {
+			ok, err := checks.ThresholdPassed(&check, value)
+			if err != nil {
+				return false, "", fmt.Errorf("prometheus check %s: %v", check.Name, err)
+			}
+			if !ok {
+				return false, fmt.Sprintf("Prometheus check %s did not pass: got %v, expected %s", check.Name, value, check.Threshold), nil
+			}
+		}
+
+		t.rm.Logger.Info("Prometheus check passed", "name", check.Name)
+	}
+
+	return true, "", nil
+}
+
+// prometheusChecksErrorTimedOut returns true if the Prometheus checks have failed to be evaluated for longer than the
+// PrometheusCheckErrorTimeout, so the CycleNodeRequest should stop waiting for them.
+func (t *CycleNodeRequestTransitioner) prometheusChecksErrorTimedOut(now time.Time) bool {
+	erroringSince := t.cycleNodeRequest.Status.PrometheusChecksErroringSince
+	if erroringSince == nil || t.options.PrometheusCheckErrorTimeout <= 0 {
+		return false
+	}
+	return now.Sub(erroringSince.Time) > t.options.PrometheusCheckErrorTimeout
+}
+
+// queryPrometheusCheck evaluates the query of the Prometheus check and returns the values of all of the samples. The
+// query is evaluated over the window of the check, or only at the given time if it doesn't have one.
+func (t *CycleNodeRequestTransitioner) queryPrometheusCheck(promAPI promv1.API, check v1.PrometheusCheck, now time.Time) ([]float64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), prometheusCheckTimeout)
+	defer cancel()
+
+	var result model.Value
+	var warnings promv1.Warnings
+	var err error
+
+	if check.Window == nil || check.Window.Duration <= 0 {
+		result, warnings, err = promAPI.Query(ctx, check.Query, now)
+	} else {
+		step := prometheusCheckStep
+		if check.Window.Duration < step {
+			step = check.Window.Duration
+		}
+		result, warnings, err = promAPI.QueryRange(ctx, check.Query, promv1.Range{
+			Start: now.Add(-check.Window.Duration),
+			End:   now,
+			Step:  step,
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(warnings) > 0 {
+		t.rm.Logger.Info("Warnings from Prometheus", "name", check.Name, "warnings", warnings)
+	}
+
+	var values []float64
+	switch typed := result.(type) {
+	case model.Vector:
+		for _, sample := range typed {
+			values = append(values, float64(sample.Value))
+		}
+	case model.Matrix:
+		for _, stream := range typed {
+			for _, pair := range stream.Values {
+				values = append(values, float64(pair.Value))
+			}
+		}
+	case *model.Scalar:
+		values = append(values, float64(typed.Value))
+	default:
+		return nil, fmt.Errorf("unsupported result type %s", result.Type())
+	}
+
+	return values, nil
+}
@@ -0,0 +1,242 @@
+package transitioner
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/api"
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "github.com/atlassian-labs/cyclops/pkg/apis/atlassian/v1"
+)
+
+// newTestPrometheusServer creates a fake Prometheus that answers instant queries with a vector and range queries
+// with a matrix of the given values
+func newTestPrometheusServer(t *testing.T, values ...string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, r.ParseForm())
+		if r.Form.Get("query") == "error" {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"status":"error","errorType":"bad_data","error":"parse error"}`)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/api/v1/query":
+			result := ""
+			for i, value := range values {
+				if i > 0 {
+					result += ","
+				}
+				result += fmt.Sprintf(`{"metric":{"instance":"%d"},"value":[1600000000,"%s"]}`, i, value)
+			}
+			fmt.Fprintf(w, `{"status":"success","data":{"resultType":"vector","result":[%s]}}`, result)
+		case "/api/v1/query_range":
+			result := ""
+			for i, value := range values {
+				if i > 0 {
+					result += ","
+				}
+				result += fmt.Sprintf(`[%d,"%s"]`, 1600000000+i*30, value)
+			}
+			if result != "" {
+				result = fmt.Sprintf(`{"metric":{},"values":[%s]}`, result)
+			}
+			fmt.Fprintf(w, `{"status":"success","data":{"resultType":"matrix","result":[%s]}}`, result)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+// newTestPrometheusAPI creates a client for the fake Prometheus
+func newTestPrometheusAPI(t *testing.T, server *httptest.Server) promv1.API {
+	client, err := api.NewClient(api.Config{Address: server.URL})
+	assert.NoError(t, err)
+	return promv1.NewAPI(client)
+}
+
+func TestPerformPrometheusChecks(t *testing.T) {
+	tests := []struct {
+		name          string
+		values        []string
+		check         v1.PrometheusCheck
+		expectPassed  bool
+		expectError   bool
+		expectMessage bool
+	}{
+		{"passes", []string{"0.001"}, v1.PrometheusCheck{Name: "errors", Query: "error_rate", Threshold: "< 0.01"}, true, false, false},
+		{"no samples passes", nil, v1.PrometheusCheck{Name: "alerts", Query: "ALERTS", Threshold: "== 0"}, true, false, false},
+		{"one sample fails", []string{"0", "1"}, v1.PrometheusCheck{Name: "alerts", Query: "ALERTS", Threshold: "== 0"}, false, false, true},
+		{
+			"window passes",
+			[]string{"0.001", "0.002"},
+			v1.PrometheusCheck{Name: "errors", Query: "error_rate", Threshold: "< 0.01", Window: &metav1.Duration{Duration: 5 * time.Minute}},
+			true, false, false,
+		},
+		{
+			"window fails on an earlier sample",
+			[]string{"0.5", "0.002"},
+			v1.PrometheusCheck{Name: "errors", Query: "error_rate", Threshold: "< 0.01", Window: &metav1.Duration{Duration: 5 * time.Minute}},
+			false, false, true,
+		},
+		{"query error", nil, v1.PrometheusCheck{Name: "broken", Query: "error", Threshold: "< 1"}, false, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestPrometheusServer(t, tt.values...)
+			defer server.Close()
+
+			cnr := newTestCycleNodeRequest(v1.CycleNodeRequestInitialised)
+			cnr.Spec.PrometheusChecks = []v1.PrometheusCheck{tt.check}
+			transitioner := newTestTransitioner(t, cnr)
+			transitioner.options.PrometheusAPI = newTestPrometheusAPI(t, server)
+
+			passed, message, err := transitioner.performPrometheusChecks(time.Now())
+			assert.Equal(t, tt.expectPassed, passed)
+			assert.Equal(t, tt.expectError, err != nil)
+			assert.Equal(t, tt.expectMessage, message != "")
+			assert.Equal(t, tt.expectError, cnr.Status.PrometheusChecksErroringSince != nil)
+
+			condition := meta.FindStatusCondition(cnr.Status.Conditions, v1.CycleNodeRequestConditionPrometheusChecksPassing)
+			if assert.NotNil(t, condition) {
+				assert.Equal(t, tt.expectPassed, condition.Status == metav1.ConditionTrue)
+			}
+		})
+	}
+}
+
+func TestPerformPrometheusChecks_ErroringSince(t *testing.T) {
+	server := newTestPrometheusServer(t, "0")
+	defer server.Close()
+
+	cnr := newTestCycleNodeRequest(v1.CycleNodeRequestInitialised)
+	cnr.Spec.PrometheusChecks = []v1.PrometheusCheck{{Name: "broken", Query: "error", Threshold: "== 0"}}
+	transitioner := newTestTransitioner(t, cnr)
+	transitioner.options.PrometheusAPI = newTestPrometheusAPI(t, server)
+	transitioner.options.PrometheusCheckErrorTimeout = 10 * time.Minute
+
+	// The time the checks first failed to be evaluated is kept while they keep failing
+	now := time.Now()
+	_, _, err := transitioner.performPrometheusChecks(now.Add(-time.Hour))
+	assert.Error(t, err)
+	_, _, err = transitioner.performPrometheusChecks(now)
+	assert.Error(t, err)
+	if assert.NotNil(t, cnr.Status.PrometheusChecksErroringSince) {
+		assert.Equal(t, now.Add(-time.Hour).Unix(), cnr.Status.PrometheusChecksErroringSince.Unix())
+	}
+	assert.True(t, transitioner.prometheusChecksErrorTimedOut(now))
+
+	// Without a timeout the checks are waited for indefinitely
+	transitioner.options.PrometheusCheckErrorTimeout = 0
+	assert.False(t, transitioner.prometheusChecksErrorTimedOut(now))
+
+	// It is cleared once the checks can be evaluated again
+	cnr.Spec.PrometheusChecks[0].Query = "error_rate"
+	passed, _, err := transitioner.performPrometheusChecks(now)
+	assert.True(t, passed)
+	assert.NoError(t, err)
+	assert.Nil(t, cnr.Status.PrometheusChecksErroringSince)
+}
+
+func TestTransitionInitialised_PrometheusCheckErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		erroringFor time.Duration
+		expectPhase v1.CycleNodeRequestPhase
+	}{
+		{"checks that can't be evaluated are waited for", time.Minute, v1.CycleNodeRequestInitialised},
+		{"checks that can't be evaluated for too long heal", time.Hour, v1.CycleNodeRequestHealing},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestPrometheusServer(t, "0")
+			defer server.Close()
+
+			node := newTestZoneNode("node-1", "us-east-1a", nil)
+			nodes := []v1.CycleNodeRequestNode{{Name: "node-1", ProviderID: node.Spec.ProviderID, NodeGroupName: "nodegroup"}}
+			erroringSince := metav1.NewTime(time.Now().Add(-tt.erroringFor))
+
+			cnr := newTestCycleNodeRequest(v1.CycleNodeRequestInitialised)
+			cnr.Spec.PrometheusChecks = []v1.PrometheusCheck{{Name: "broken", Query: "error", Threshold: "== 0"}}
+			cnr.Status.NodesToTerminate = nodes
+			cnr.Status.NodesAvailable = append([]v1.CycleNodeRequestNode(nil), nodes...)
+			cnr.Status.PrometheusChecksErroringSince = &erroringSince
+
+			transitioner := newTestTransitioner(t, cnr, node)
+			transitioner.options.PrometheusAPI = newTestPrometheusAPI(t, server)
+			transitioner.options.PrometheusCheckErrorTimeout = 30 * time.Minute
+
+			_, _ = transitioner.transitionInitialised()
+			assert.Equal(t, tt.expectPhase, cnr.Status.Phase)
+			assert.Empty(t, cnr.Status.CurrentNodes)
+		})
+	}
+}
+
+func TestPerformPrometheusChecks_NoAddress(t *testing.T) {
+	cnr := newTestCycleNodeRequest(v1.CycleNodeRequestInitialised)
+	transitioner := newTestTransitioner(t, cnr)
+
+	// Without any checks the address isn't needed
+	passed, _, err := transitioner.performPrometheusChecks(time.Now())
+	assert.True(t, passed)
+	assert.NoError(t, err)
+	assert.Empty(t, cnr.Status.Conditions)
+
+	cnr.Spec.PrometheusChecks = []v1.PrometheusCheck{{Name: "errors", Query: "error_rate", Threshold: "< 0.01"}}
+	passed, _, err = transitioner.performPrometheusChecks(time.Now())
+	assert.False(t, passed)
+	assert.Error(t, err)
+}
+
+func TestTransitionBaking_PrometheusChecks(t *testing.T) {
+	tests := []struct {
+		name        string
+		query       string
+		erroringFor time.Duration
+		expectPhase v1.CycleNodeRequestPhase
+	}{
+		{"passing checks finish baking", "error_rate", 0, v1.CycleNodeRequestInitialised},
+		{"failing checks heal", "failing", 0, v1.CycleNodeRequestHealing},
+		{"checks that can't be evaluated keep baking", "error", 0, v1.CycleNodeRequestBaking},
+		{"checks that can't be evaluated for too long heal", "error", time.Hour, v1.CycleNodeRequestHealing},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value := "0"
+			if tt.query == "failing" {
+				value = "1"
+			}
+			server := newTestPrometheusServer(t, value)
+			defer server.Close()
+
+			bakeStarted := metav1.NewTime(time.Now().Add(-time.Hour))
+			cnr := newTestCycleNodeRequest(v1.CycleNodeRequestBaking)
+			cnr.Spec.CycleSettings.Rollout = &v1.CycleRollout{BakeDuration: &metav1.Duration{Duration: 10 * time.Minute}}
+			cnr.Spec.PrometheusChecks = []v1.PrometheusCheck{{Name: "errors", Query: tt.query, Threshold: "== 0"}}
+			cnr.Status.RolloutBatch = 1
+			cnr.Status.BakeStarted = &bakeStarted
+			if tt.erroringFor > 0 {
+				erroringSince := metav1.NewTime(time.Now().Add(-tt.erroringFor))
+				cnr.Status.PrometheusChecksErroringSince = &erroringSince
+			}
+
+			transitioner := newTestTransitioner(t, cnr)
+			transitioner.options.PrometheusAPI = newTestPrometheusAPI(t, server)
+			transitioner.options.PrometheusCheckErrorTimeout = 30 * time.Minute
+
+			_, _ = transitioner.transitionBaking()
+			assert.Equal(t, tt.expectPhase, cnr.Status.Phase)
+		})
+	}
+}
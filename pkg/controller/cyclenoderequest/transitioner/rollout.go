@@ -0,0 +1,80 @@
+package transitioner
+
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	v1 "github.com/atlassian-labs/cyclops/pkg/apis/atlassian/v1"
+)
+
+// batchSize returns the number of nodes to work on in parallel for the next batch. While the rollout is ramping up
+// it is the canary batch size, and then each of the steps in turn, bounded by the concurrency.
+func (t *CycleNodeRequestTransitioner) batchSize() int64 {
+	concurrency := t.concurrency()
+	rollout := t.cycleNodeRequest.Spec.CycleSettings.Rollout
+	batch := t.cycleNodeRequest.Status.RolloutBatch
+
+	if rollout == nil || batch > len(rollout.Steps) {
+		return concurrency
+	}
+
+	size := rollout.CanaryBatchSize
+	if batch == 0 {
+		if size <= 0 {
+			size = 1
+		}
+	} else {
+		step, err := intstr.GetScaledValueFromIntOrPercent(&rollout.Steps[batch-1], int(concurrency), true)
+		if err != nil {
+			t.rm.Logger.Error(err, "Invalid rollout step, using the concurrency", "step", rollout.Steps[batch-1].String())
+			return concurrency
+		}
+		size = int64(step)
+	}
+
+	if size > concurrency {
+		size = concurrency
+	}
+	if size < 1 {
+		size = 1
+	}
+	return size
+}
+
+// recordRolloutBatch counts a batch of nodes being selected for the rollout. It doesn't save the CycleNodeRequest.
+func (t *CycleNodeRequestTransitioner) recordRolloutBatch() {
+	if t.cycleNodeRequest.Spec.CycleSettings.Rollout != nil {
+		t.cycleNodeRequest.Status.RolloutBatch++
+	}
+}
+
+// rolloutInProgress returns true if the last batch of nodes selected was part of the ramp-up of the rollout, so it
+// must finish and bake before the next batch is selected. The canary batch and each of the steps are baked.
+func (t *CycleNodeRequestTransitioner) rolloutInProgress() bool {
+	rollout := t.cycleNodeRequest.Spec.CycleSettings.Rollout
+	if rollout == nil {
+		return false
+	}
+	return t.cycleNodeRequest.Status.RolloutBatch <= len(rollout.Steps)+1
+}
+
+// transitionToBaking starts baking the batch of nodes that has just finished
+func (t *CycleNodeRequestTransitioner) transitionToBaking() (reconcile.Result, error) {
+	now := metav1.Now()
+	t.cycleNodeRequest.Status.BakeStarted = &now
+	t.rm.LogEvent(t.cycleNodeRequest, "Baking", "Baking batch %d of the rollout for %v before selecting more nodes",
+		t.cycleNodeRequest.Status.RolloutBatch, t.bakeDuration())
+	return t.transitionObject(v1.CycleNodeRequestBaking)
+}
+
+// bakeDuration returns how long to bake each batch of the rollout for
+func (t *CycleNodeRequestTransitioner) bakeDuration() time.Duration {
+	rollout := t.cycleNodeRequest.Spec.CycleSettings.Rollout
+	if rollout == nil || rollout.BakeDuration == nil {
+		return 0
+	}
+	return rollout.BakeDuration.Duration
+}
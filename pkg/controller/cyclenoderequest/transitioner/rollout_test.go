@@ -0,0 +1,106 @@
+package transitioner
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	v1 "github.com/atlassian-labs/cyclops/pkg/apis/atlassian/v1"
+)
+
+func TestBatchSize(t *testing.T) {
+	rollout := &v1.CycleRollout{
+		CanaryBatchSize: 2,
+		Steps:           []intstr.IntOrString{intstr.FromString("25%"), intstr.FromInt(20)},
+	}
+
+	tests := []struct {
+		name         string
+		rollout      *v1.CycleRollout
+		rolloutBatch int
+		expectSize   int64
+		expectBaking bool
+	}{
+		{"no rollout", nil, 0, 10, false},
+		{"canary batch defaults to 1", &v1.CycleRollout{}, 0, 1, true},
+		{"canary batch", rollout, 0, 2, true},
+		{"percentage step rounds up", rollout, 1, 3, true},
+		{"number step is bounded by the concurrency", rollout, 2, 10, true},
+		{"after the steps", rollout, 3, 10, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cnr := newTestCycleNodeRequest(v1.CycleNodeRequestInitialised)
+			cnr.Spec.CycleSettings.Rollout = tt.rollout
+			cnr.Status.Concurrency = 10
+			cnr.Status.RolloutBatch = tt.rolloutBatch
+			transitioner := newTestTransitioner(t, cnr)
+
+			assert.Equal(t, tt.expectSize, transitioner.batchSize())
+
+			// The batch that is selected next needs to be baked if it is part of the ramp-up
+			transitioner.recordRolloutBatch()
+			assert.Equal(t, tt.expectBaking, transitioner.rolloutInProgress())
+		})
+	}
+}
+
+func TestTransitionBaking(t *testing.T) {
+	healthy := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !healthy {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	newNode := newTestZoneNode("new-node", "zone-a", nil)
+	newNode.Status.Addresses = []corev1.NodeAddress{{Type: corev1.NodeInternalIP, Address: "10.0.0.1"}}
+
+	tests := []struct {
+		name        string
+		bakeStarted time.Duration
+		healthy     bool
+		expectPhase v1.CycleNodeRequestPhase
+		expectError bool
+	}{
+		{"still baking", -time.Minute, true, v1.CycleNodeRequestBaking, false},
+		{"finished baking", -time.Hour, true, v1.CycleNodeRequestInitialised, false},
+		{"health check fails while baking", -time.Minute, false, v1.CycleNodeRequestHealing, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			healthy = tt.healthy
+			bakeStarted := metav1.NewTime(time.Now().Add(tt.bakeStarted))
+
+			cnr := newTestCycleNodeRequest(v1.CycleNodeRequestBaking)
+			cnr.Spec.Selector = metav1.LabelSelector{MatchLabels: map[string]string{"role": "test"}}
+			cnr.Spec.CycleSettings.Rollout = &v1.CycleRollout{BakeDuration: &metav1.Duration{Duration: 10 * time.Minute}}
+			cnr.Spec.HealthChecks = []v1.HealthCheck{{
+				Endpoint:         server.URL,
+				WaitPeriod:       &metav1.Duration{Duration: time.Minute},
+				ValidStatusCodes: []uint{http.StatusOK},
+			}}
+			cnr.Status.RolloutBatch = 1
+			cnr.Status.BakeStarted = &bakeStarted
+			cnr.Status.HealthChecks = map[string]v1.HealthCheckStatus{
+				getNodeHash(getCycleRequestNode(*newNode)): {Checks: []bool{true}},
+			}
+
+			transitioner := newTestTransitioner(t, cnr, newNode.DeepCopy())
+			transitioner.rm.HttpClient = server.Client()
+
+			_, err := transitioner.transitionBaking()
+			assert.Equal(t, tt.expectError, err != nil)
+			assert.Equal(t, tt.expectPhase, cnr.Status.Phase)
+		})
+	}
+}
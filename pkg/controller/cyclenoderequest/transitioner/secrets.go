@@ -0,0 +1,99 @@
+package transitioner
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "github.com/atlassian-labs/cyclops/pkg/apis/atlassian/v1"
+)
+
+// tlsSecretRootCAKey is the key of the root CA in a Secret referenced by a TLSConfig. The certificate and key use
+// the standard keys of a kubernetes.io/tls Secret.
+const tlsSecretRootCAKey = "ca.crt"
+
+// getSecret gets the Secret with the given name from the namespace of the CycleNodeRequest. The raw client is used so
+// that Secrets aren't cached by the controller. Secrets can only be referenced by CycleNodeRequests in the namespace of
+// the controller, which is the only namespace it is given permission to get Secrets in.
+func (t *CycleNodeRequestTransitioner) getSecret(name string) (*corev1.Secret, error) {
+	if t.cycleNodeRequest.Namespace != t.options.Namespace {
+		return nil, fmt.Errorf("secret %s can't be referenced: secrets can only be referenced by CycleNodeRequests in the %s namespace", name, t.options.Namespace)
+	}
+
+	secret, err := t.rm.RawClient.CoreV1().Secrets(t.cycleNodeRequest.Namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get secret %s/%s: %v", t.cycleNodeRequest.Namespace, name, err)
+	}
+	return secret, nil
+}
+
+// getSecretKey returns the value of the key of the Secret the reference selects
+func (t *CycleNodeRequestTransitioner) getSecretKey(ref v1.SecretKeyReference) (string, error) {
+	secret, err := t.getSecret(ref.Name)
+	if err != nil {
+		return "", err
+	}
+
+	value, ok := secret.Data[ref.Key]
+	if !ok {
+		return "", fmt.Errorf("key %s not found in secret %s/%s", ref.Key, t.cycleNodeRequest.Namespace, ref.Name)
+	}
+	return string(value), nil
+}
+
+// buildHeaders resolves the values of the headers, reading them from their Secrets where they have one
+func (t *CycleNodeRequestTransitioner) buildHeaders(headers []v1.HTTPHeader) (http.Header, error) {
+	header := http.Header{}
+
+	for _, h := range headers {
+		value := h.Value
+		if h.SecretKeyRef != nil {
+			var err error
+			if value, err = t.getSecretKey(*h.SecretKeyRef); err != nil {
+				return nil, err
+			}
+		}
+		header.Add(h.Name, value)
+	}
+
+	return header, nil
+}
+
+// validateSecretReferences returns an error if any of the Secrets, or keys of Secrets, referenced by the health checks
+// and pre-termination checks don't exist, or if the CycleNodeRequest isn't in the namespace of the controller.
+func (t *CycleNodeRequestTransitioner) validateSecretReferences() error {
+	validate := func(tlsConfig v1.TLSConfig, headers []v1.HTTPHeader) error {
+		if tlsConfig.SecretName != "" {
+			if _, err := t.getSecret(tlsConfig.SecretName); err != nil {
+				return err
+			}
+		}
+		_, err := t.buildHeaders(headers)
+		return err
+	}
+
+	for _, healthCheck := range t.cycleNodeRequest.Spec.HealthChecks {
+		if err := validate(healthCheck.TLSConfig, healthCheck.Headers); err != nil {
+			return fmt.Errorf("invalid health check %s: %v", healthCheck.Endpoint, err)
+		}
+	}
+
+	for _, preTerminationCheck := range t.cycleNodeRequest.Spec.PreTerminationChecks {
+		if err := validate(preTerminationCheck.TLSConfig, preTerminationCheck.Headers); err != nil {
+			return fmt.Errorf("invalid pre-termination check %s: %v", preTerminationCheck.Endpoint, err)
+		}
+		if preTerminationCheck.Cancel != nil {
+			if _, err := t.buildHeaders(preTerminationCheck.Cancel.Headers); err != nil {
+				return fmt.Errorf("invalid pre-termination cancel %s: %v", preTerminationCheck.Cancel.Endpoint, err)
+			}
+		}
+		if err := validate(preTerminationCheck.HealthCheck.TLSConfig, preTerminationCheck.HealthCheck.Headers); err != nil {
+			return fmt.Errorf("invalid pre-termination health check %s: %v", preTerminationCheck.HealthCheck.Endpoint, err)
+		}
+	}
+
+	return nil
+}
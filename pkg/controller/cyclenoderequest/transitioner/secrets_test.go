@@ -0,0 +1,197 @@
+package transitioner
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+
+	v1 "github.com/atlassian-labs/cyclops/pkg/apis/atlassian/v1"
+)
+
+func TestPerformHealthCheck_SecretReferences(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	rootCA := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+
+	tests := []struct {
+		name         string
+		healthCheck  v1.HealthCheck
+		expectPassed bool
+	}{
+		{
+			"tls and token from secrets",
+			v1.HealthCheck{
+				TLSConfig: v1.TLSConfig{SecretName: "health-check-tls"},
+				Headers: []v1.HTTPHeader{
+					{Name: "Authorization", SecretKeyRef: &v1.SecretKeyReference{Name: "health-check-token", Key: "header"}},
+				},
+			},
+			true,
+		},
+		{
+			"inline header",
+			v1.HealthCheck{
+				TLSConfig: v1.TLSConfig{SecretName: "health-check-tls"},
+				Headers:   []v1.HTTPHeader{{Name: "Authorization", Value: "Bearer secret-token"}},
+			},
+			true,
+		},
+		{
+			"no token",
+			v1.HealthCheck{
+				TLSConfig: v1.TLSConfig{SecretName: "health-check-tls"},
+			},
+			false,
+		},
+		{
+			"no root CA",
+			v1.HealthCheck{
+				Headers: []v1.HTTPHeader{{Name: "Authorization", Value: "Bearer secret-token"}},
+			},
+			false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cnr := newTestCycleNodeRequest(v1.CycleNodeRequestScalingUp)
+			transitioner := newTestTransitioner(t, cnr)
+			transitioner.rm.HttpClient = &http.Client{Timeout: 5 * time.Second}
+			transitioner.rm.RawClient = fakeclientset.NewSimpleClientset(
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: "health-check-tls", Namespace: "kube-system"},
+					Data:       map[string][]byte{"ca.crt": rootCA},
+				},
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: "health-check-token", Namespace: "kube-system"},
+					Data:       map[string][]byte{"header": []byte("Bearer secret-token")},
+				},
+			)
+
+			tt.healthCheck.Endpoint = server.URL
+			tt.healthCheck.WaitPeriod = &metav1.Duration{Duration: time.Minute}
+			tt.healthCheck.ValidStatusCodes = []uint{http.StatusOK}
+
+			errorAllowed, err := transitioner.performHealthCheck(v1.CycleNodeRequestNode{Name: "new-node"}, tt.healthCheck, nil)
+			assert.True(t, errorAllowed)
+			assert.Equal(t, tt.expectPassed, err == nil, "error: %v", err)
+		})
+	}
+}
+
+func TestValidateSecretReferences(t *testing.T) {
+	tests := []struct {
+		name                 string
+		healthChecks         []v1.HealthCheck
+		preTerminationChecks []v1.PreTerminationCheck
+		namespace            string
+		expectError          bool
+	}{
+		{
+			"no references",
+			[]v1.HealthCheck{{Endpoint: "http://{{ .NodeIP }}/health"}},
+			nil,
+			"kube-system",
+			false,
+		},
+		{
+			"existing secrets",
+			[]v1.HealthCheck{{
+				TLSConfig: v1.TLSConfig{SecretName: "tls"},
+				Headers:   []v1.HTTPHeader{{Name: "Authorization", SecretKeyRef: &v1.SecretKeyReference{Name: "token", Key: "header"}}},
+			}},
+			[]v1.PreTerminationCheck{{
+				TLSConfig:   v1.TLSConfig{SecretName: "tls"},
+				HealthCheck: v1.HealthCheck{TLSConfig: v1.TLSConfig{SecretName: "tls"}},
+			}},
+			"kube-system",
+			false,
+		},
+		{
+			"missing tls secret",
+			[]v1.HealthCheck{{TLSConfig: v1.TLSConfig{SecretName: "missing"}}},
+			nil,
+			"kube-system",
+			true,
+		},
+		{
+			"missing key",
+			[]v1.HealthCheck{{
+				Headers: []v1.HTTPHeader{{Name: "Authorization", SecretKeyRef: &v1.SecretKeyReference{Name: "token", Key: "missing"}}},
+			}},
+			nil,
+			"kube-system",
+			true,
+		},
+		{
+			"missing pre-termination trigger secret",
+			nil,
+			[]v1.PreTerminationCheck{{
+				Headers: []v1.HTTPHeader{{Name: "Authorization", SecretKeyRef: &v1.SecretKeyReference{Name: "missing", Key: "header"}}},
+			}},
+			"kube-system",
+			true,
+		},
+		{
+			"missing pre-termination health check secret",
+			nil,
+			[]v1.PreTerminationCheck{{
+				HealthCheck: v1.HealthCheck{TLSConfig: v1.TLSConfig{SecretName: "missing"}},
+			}},
+			"kube-system",
+			true,
+		},
+		{
+			"no references outside the controller namespace",
+			[]v1.HealthCheck{{Endpoint: "http://{{ .NodeIP }}/health"}},
+			nil,
+			"default",
+			false,
+		},
+		{
+			"secret referenced outside the controller namespace",
+			[]v1.HealthCheck{{
+				Headers: []v1.HTTPHeader{{Name: "Authorization", SecretKeyRef: &v1.SecretKeyReference{Name: "token", Key: "header"}}},
+			}},
+			nil,
+			"default",
+			true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cnr := newTestCycleNodeRequest(v1.CycleNodeRequestUndefined)
+			cnr.Namespace = tt.namespace
+			cnr.Spec.HealthChecks = tt.healthChecks
+			cnr.Spec.PreTerminationChecks = tt.preTerminationChecks
+
+			transitioner := newTestTransitioner(t, cnr)
+			transitioner.rm.RawClient = fakeclientset.NewSimpleClientset(
+				&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "tls", Namespace: "kube-system"}},
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: "token", Namespace: "kube-system"},
+					Data:       map[string][]byte{"header": []byte("Bearer token")},
+				},
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: "token", Namespace: "default"},
+					Data:       map[string][]byte{"header": []byte("Bearer token")},
+				},
+			)
+
+			assert.Equal(t, tt.expectError, transitioner.validateSecretReferences() != nil)
+		})
+	}
+}
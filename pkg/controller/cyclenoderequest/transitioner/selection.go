@@ -0,0 +1,108 @@
+package transitioner
+
+import (
+	"context"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+
+	v1 "github.com/atlassian-labs/cyclops/pkg/apis/atlassian/v1"
+	"github.com/atlassian-labs/cyclops/pkg/k8s"
+)
+
+// orderNodes sorts the nodes into the order they should be cycled in, following the selection strategy. Nodes that
+// are equal under the strategy keep their existing order.
+func (t *CycleNodeRequestTransitioner) orderNodes(nodes []*corev1.Node) error {
+	switch t.cycleNodeRequest.Spec.CycleSettings.SelectionStrategy {
+	case v1.CycleNodeRequestSelectionStrategyOldestFirst:
+		sort.SliceStable(nodes, func(i, j int) bool {
+			return nodes[i].CreationTimestamp.Before(&nodes[j].CreationTimestamp)
+		})
+
+	case v1.CycleNodeRequestSelectionStrategyFewestPodsFirst:
+		podsByNode, err := t.listPodsByNode()
+		if err != nil {
+			return err
+		}
+		podCounts := make(map[string]int, len(nodes))
+		for _, node := range nodes {
+			for i := range podsByNode[node.Name] {
+				pod := &podsByNode[node.Name][i]
+				if !k8s.PodIsDaemonSet(pod) && !k8s.PodIsStatic(pod) {
+					podCounts[node.Name]++
+				}
+			}
+		}
+		sort.SliceStable(nodes, func(i, j int) bool {
+			return podCounts[nodes[i].Name] < podCounts[nodes[j].Name]
+		})
+
+	case v1.CycleNodeRequestSelectionStrategyLeastRequestedFirst:
+		podsByNode, err := t.listPodsByNode()
+		if err != nil {
+			return err
+		}
+		requested := make(map[string]float64, len(nodes))
+		for _, node := range nodes {
+			requested[node.Name] = requestedFraction(node, podsByNode[node.Name])
+		}
+		sort.SliceStable(nodes, func(i, j int) bool {
+			return requested[nodes[i].Name] < requested[nodes[j].Name]
+		})
+
+	case v1.CycleNodeRequestSelectionStrategyNodeNamesOrder:
+		position := make(map[string]int, len(t.cycleNodeRequest.Spec.NodeNames))
+		for i, name := range t.cycleNodeRequest.Spec.NodeNames {
+			position[name] = i
+		}
+		// Nodes that aren't named go last
+		positionOf := func(name string) int {
+			if i, ok := position[name]; ok {
+				return i
+			}
+			return len(position)
+		}
+		sort.SliceStable(nodes, func(i, j int) bool {
+			return positionOf(nodes[i].Name) < positionOf(nodes[j].Name)
+		})
+	}
+
+	return nil
+}
+
+// listPodsByNode lists the pods that have not finished in the cluster, grouped by the name of the node they are on
+func (t *CycleNodeRequestTransitioner) listPodsByNode() (map[string][]corev1.Pod, error) {
+	var podList corev1.PodList
+	if err := t.rm.Client.List(context.TODO(), &podList); err != nil {
+		return nil, err
+	}
+
+	podsByNode := make(map[string][]corev1.Pod)
+	for _, pod := range podList.Items {
+		if pod.Spec.NodeName == "" || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		podsByNode[pod.Spec.NodeName] = append(podsByNode[pod.Spec.NodeName], pod)
+	}
+	return podsByNode, nil
+}
+
+// requestedFraction returns the average share of the node's allocatable cpu and memory that is requested by the pods
+func requestedFraction(node *corev1.Node, pods []corev1.Pod) float64 {
+	var cpu, memory int64
+	for _, pod := range pods {
+		for _, container := range pod.Spec.Containers {
+			cpu += container.Resources.Requests.Cpu().MilliValue()
+			memory += container.Resources.Requests.Memory().Value()
+		}
+	}
+
+	var fraction float64
+	if allocatable := node.Status.Allocatable.Cpu().MilliValue(); allocatable > 0 {
+		fraction += float64(cpu) / float64(allocatable)
+	}
+	if allocatable := node.Status.Allocatable.Memory().Value(); allocatable > 0 {
+		fraction += float64(memory) / float64(allocatable)
+	}
+	return fraction / 2
+}
@@ -0,0 +1,108 @@
+package transitioner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	v1 "github.com/atlassian-labs/cyclops/pkg/apis/atlassian/v1"
+)
+
+func newTestSelectionPod(name, nodeName, cpu string, owner string) *corev1.Pod {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec: corev1.PodSpec{
+			NodeName: nodeName,
+			Containers: []corev1.Container{{
+				Name: "app",
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(cpu)},
+				},
+			}},
+		},
+		Status: corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+	if owner != "" {
+		pod.OwnerReferences = []metav1.OwnerReference{{Kind: owner, Name: owner}}
+	}
+	return pod
+}
+
+func TestOrderNodes(t *testing.T) {
+	now := time.Now()
+	newNode := func(name string, age time.Duration, cpu string) *corev1.Node {
+		node := newTestZoneNode(name, "zone-a", nil)
+		node.CreationTimestamp = metav1.NewTime(now.Add(-age))
+		node.Status.Allocatable = corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(cpu)}
+		return node
+	}
+
+	pods := []runtime.Object{
+		// node-a has two pods requesting half of its cpu
+		newTestSelectionPod("a-1", "node-a", "500m", ""),
+		newTestSelectionPod("a-2", "node-a", "500m", ""),
+		// node-b has one pod requesting all of its cpu, and a DaemonSet pod which is not counted
+		newTestSelectionPod("b-1", "node-b", "1", ""),
+		newTestSelectionPod("b-ds", "node-b", "0", "DaemonSet"),
+		// node-c has three pods requesting a small amount of its cpu
+		newTestSelectionPod("c-1", "node-c", "100m", ""),
+		newTestSelectionPod("c-2", "node-c", "100m", ""),
+		newTestSelectionPod("c-3", "node-c", "100m", ""),
+	}
+
+	tests := []struct {
+		name      string
+		strategy  v1.CycleNodeRequestSelectionStrategy
+		nodeNames []string
+		expect    []string
+	}{
+		{"no strategy keeps the order", "", nil, []string{"node-a", "node-b", "node-c"}},
+		{"oldest first", v1.CycleNodeRequestSelectionStrategyOldestFirst, nil, []string{"node-c", "node-a", "node-b"}},
+		{"fewest pods first", v1.CycleNodeRequestSelectionStrategyFewestPodsFirst, nil, []string{"node-b", "node-a", "node-c"}},
+		{"least requested first", v1.CycleNodeRequestSelectionStrategyLeastRequestedFirst, nil, []string{"node-c", "node-a", "node-b"}},
+		{"node names order", v1.CycleNodeRequestSelectionStrategyNodeNamesOrder, []string{"node-c", "node-a"}, []string{"node-c", "node-a", "node-b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nodes := []*corev1.Node{
+				newNode("node-a", 2*time.Hour, "2"),
+				newNode("node-b", time.Hour, "1"),
+				newNode("node-c", 3*time.Hour, "4"),
+			}
+
+			cnr := newTestCycleNodeRequest(v1.CycleNodeRequestInitialised)
+			cnr.Spec.CycleSettings.SelectionStrategy = tt.strategy
+			cnr.Spec.NodeNames = tt.nodeNames
+
+			assert.NoError(t, newTestTransitioner(t, cnr, pods...).orderNodes(nodes))
+			assert.Equal(t, tt.expect, nodeNames(nodes))
+		})
+	}
+}
+
+func TestGetNodesToTerminate_PlannedOrder(t *testing.T) {
+	now := time.Now()
+	cnr := newTestCycleNodeRequest(v1.CycleNodeRequestInitialised)
+	cnr.Spec.Selector = metav1.LabelSelector{MatchLabels: map[string]string{"role": "test"}}
+	cnr.Spec.CycleSettings.SelectionStrategy = v1.CycleNodeRequestSelectionStrategyOldestFirst
+
+	var objects []runtime.Object
+	for i, name := range []string{"node-a", "node-b", "node-c"} {
+		node := newTestZoneNode(name, "zone-a", nil)
+		node.CreationTimestamp = metav1.NewTime(now.Add(time.Duration(-i) * time.Hour))
+		cnr.Status.NodesToTerminate = append(cnr.Status.NodesToTerminate, newCycleNodeRequestNode(node, "nodegroup"))
+		objects = append(objects, node)
+	}
+
+	transitioner := newTestTransitioner(t, cnr, objects...)
+	selected, _, err := transitioner.getNodesToTerminate(1)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"node-c"}, nodeNames(selected))
+	assert.Equal(t, []string{"node-b", "node-a"}, transitioner.cycleNodeRequest.Status.PlannedOrder)
+}
@@ -6,6 +6,7 @@ import (
 
 	v1 "github.com/atlassian-labs/cyclops/pkg/apis/atlassian/v1"
 	"github.com/atlassian-labs/cyclops/pkg/controller"
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
@@ -42,6 +43,28 @@ type Options struct {
 
 	// HealthCheckTimeout controls the duration of the timeout period for health checks performed on nodes
 	HealthCheckTimeout time.Duration
+
+	// MaxRetries controls how many times a failed CycleNodeRequest can be retried
+	MaxRetries int
+
+	// MaxNodesInFlight limits the number of nodes being cycled at once across all CycleNodeRequests. 0 means no limit
+	MaxNodesInFlight int64
+
+	// MaxNodesCycledPerHour limits the number of nodes selected for cycling within an hour across all
+	// CycleNodeRequests. 0 means no limit
+	MaxNodesCycledPerHour int64
+
+	// PrometheusAPI is the client of the Prometheus used to evaluate the Prometheus checks of CycleNodeRequests. It is
+	// shared by all of the CycleNodeRequests, and is nil if the controller wasn't given the address of Prometheus
+	PrometheusAPI promv1.API
+
+	// PrometheusCheckErrorTimeout controls how long the Prometheus checks of a CycleNodeRequest can fail to be
+	// evaluated before it is sent to Healing. 0 means it waits for them indefinitely
+	PrometheusCheckErrorTimeout time.Duration
+
+	// Namespace is the namespace the controller watches for CycleNodeRequests. Secrets referenced by health checks and
+	// pre-termination checks are only read from this namespace, which is the only one the controller can get them in
+	Namespace string
 }
 
 // NewCycleNodeRequestTransitioner returns a new cycleNodeRequest transitioner
@@ -61,6 +84,18 @@ func NewCycleNodeRequestTransitioner(
 func (t *CycleNodeRequestTransitioner) Run() (reconcile.Result, error) {
 	t.rm.Logger.Info("Transitioning cycleNodeRequest")
 
+	// A cancelled cycleNodeRequest stops whatever it is doing and rolls back the nodes it was working on
+	if t.cycleNodeRequest.Spec.Cancel && t.cancellable() {
+		t.rm.LogEvent(t.cycleNodeRequest, "Cancelling", "Cancel requested in phase %s", t.cycleNodeRequest.Status.Phase)
+		return t.transitionObject(v1.CycleNodeRequestCancelling)
+	}
+
+	// A failed cycleNodeRequest picks up from where it failed when a retry is requested
+	if _, ok := t.cycleNodeRequest.Annotations[v1.CycleNodeRequestRetryAnnotation]; ok &&
+		t.cycleNodeRequest.Status.Phase == v1.CycleNodeRequestFailed {
+		return t.retry()
+	}
+
 	// Locate the transition func for the phase
 	transitionFuncs := t.transitionFuncs()
 	tFunc, ok := transitionFuncs[t.cycleNodeRequest.Status.Phase]
@@ -70,6 +105,12 @@ func (t *CycleNodeRequestTransitioner) Run() (reconcile.Result, error) {
 		return reconcile.Result{}, err
 	}
 
+	// Reflect whether the cycleNodeRequest is paused in its conditions before transitioning it
+	if err := t.updatePausedCondition(); err != nil {
+		t.rm.Logger.Error(err, "Unable to update paused condition of cycleNodeRequest")
+		return reconcile.Result{}, err
+	}
+
 	// Transition the cycleNodeRequest
 	result, err := tFunc()
 	if err != nil {
@@ -89,8 +130,12 @@ func (t *CycleNodeRequestTransitioner) transitionFuncs() map[v1.CycleNodeRequest
 		v1.CycleNodeRequestScalingUp:          t.transitionScalingUp,
 		v1.CycleNodeRequestCordoningNode:      t.transitionCordoning,
 		v1.CycleNodeRequestWaitingTermination: t.transitionWaitingTermination,
+		v1.CycleNodeRequestWaitingReplacement: t.transitionWaitingReplacement,
+		v1.CycleNodeRequestBaking:             t.transitionBaking,
 		v1.CycleNodeRequestFailed:             t.transitionFailed,
 		v1.CycleNodeRequestSuccessful:         t.transitionSuccessful,
 		v1.CycleNodeRequestHealing:            t.transitionHealing,
+		v1.CycleNodeRequestCancelling:         t.transitionCancelling,
+		v1.CycleNodeRequestCancelled:          t.transitionCancelled,
 	}
 }
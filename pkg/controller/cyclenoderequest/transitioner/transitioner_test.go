@@ -0,0 +1,607 @@
+package transitioner
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/atlassian-labs/cyclops/pkg/apis"
+	v1 "github.com/atlassian-labs/cyclops/pkg/apis/atlassian/v1"
+	"github.com/atlassian-labs/cyclops/pkg/cloudprovider"
+	"github.com/atlassian-labs/cyclops/pkg/controller"
+)
+
+// testCloudProvider has the ready instances of its node groups, and records the instances attached to and detached
+// from them. Instances in cannotReattach can't be attached again, and terminated instances aren't replaced if
+// noReplacements is set. Other methods are not implemented.
+type testCloudProvider struct {
+	cloudprovider.CloudProvider
+	cloudprovider.NodeGroups
+	name           string
+	instances      map[string]cloudprovider.Instance
+	attached       map[string]string
+	detached       []string
+	cannotReattach map[string]bool
+	noReplacements bool
+}
+
+func (p *testCloudProvider) Name() string {
+	return p.name
+}
+
+func (p *testCloudProvider) ReplacesTerminatedInstances() bool {
+	return !p.noReplacements
+}
+
+func (p *testCloudProvider) GetNodeGroups([]string) (cloudprovider.NodeGroups, error) {
+	return p, nil
+}
+
+func (p *testCloudProvider) Instances() map[string]cloudprovider.Instance {
+	return p.instances
+}
+
+func (p *testCloudProvider) ReadyInstances() map[string]cloudprovider.Instance {
+	return p.instances
+}
+
+func (p *testCloudProvider) NotReadyInstances() map[string]cloudprovider.Instance {
+	return map[string]cloudprovider.Instance{}
+}
+
+func (p *testCloudProvider) AttachInstance(providerID, nodeGroup string) (bool, error) {
+	if p.cannotReattach[providerID] {
+		return false, cloudprovider.ErrCannotReattach
+	}
+	p.attached[providerID] = nodeGroup
+	return false, nil
+}
+
+func (p *testCloudProvider) DetachInstance(providerID string) (bool, error) {
+	p.detached = append(p.detached, providerID)
+	return false, nil
+}
+
+// newTestCloudProvider creates a testCloudProvider with a ready instance in nodegroup for each of the providerIDs
+func newTestCloudProvider(name string, providerIDs ...string) *testCloudProvider {
+	instances := make(map[string]cloudprovider.Instance, len(providerIDs))
+	for _, providerID := range providerIDs {
+		instances[providerID] = &dummyInstance{providerID: providerID, nodeGroup: "nodegroup"}
+	}
+	return &testCloudProvider{name: name, instances: instances, attached: map[string]string{}}
+}
+
+// newTestTransitioner creates a transitioner for the cycleNodeRequest backed by a fake client containing the
+// cycleNodeRequest and the objects
+func newTestTransitioner(t *testing.T, cnr *v1.CycleNodeRequest, objects ...runtime.Object) *CycleNodeRequestTransitioner {
+	testScheme := runtime.NewScheme()
+	assert.NoError(t, scheme.AddToScheme(testScheme))
+	assert.NoError(t, apis.AddToScheme(testScheme))
+
+	rm := &controller.ResourceManager{
+		Client:   fake.NewClientBuilder().WithScheme(testScheme).WithRuntimeObjects(append(objects, cnr)...).Build(),
+		Recorder: record.NewFakeRecorder(10),
+		Logger:   logf.Log.WithName("transitioner-test"),
+	}
+	return NewCycleNodeRequestTransitioner(cnr, rm, Options{Namespace: "kube-system"})
+}
+
+func newTestCycleNodeRequest(phase v1.CycleNodeRequestPhase) *v1.CycleNodeRequest {
+	return &v1.CycleNodeRequest{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "kube-system",
+		},
+		Spec: v1.CycleNodeRequestSpec{
+			NodeGroupName: "nodegroup",
+			CycleSettings: v1.CycleSettings{
+				Method:      v1.CycleNodeRequestMethodDrain,
+				Concurrency: intstr.FromInt(1),
+			},
+		},
+		Status: v1.CycleNodeRequestStatus{
+			Phase: phase,
+		},
+	}
+}
+
+func TestUpdatePausedCondition(t *testing.T) {
+	paused := metav1.Condition{Type: v1.CycleNodeRequestConditionPaused, Status: metav1.ConditionTrue, Reason: "Paused"}
+
+	tests := []struct {
+		name       string
+		phase      v1.CycleNodeRequestPhase
+		paused     bool
+		conditions []metav1.Condition
+		expect     metav1.ConditionStatus
+	}{
+		{"never paused", v1.CycleNodeRequestInitialised, false, nil, ""},
+		{"paused", v1.CycleNodeRequestScalingUp, true, nil, metav1.ConditionTrue},
+		{"still paused", v1.CycleNodeRequestInitialised, true, []metav1.Condition{paused}, metav1.ConditionTrue},
+		{"resumed", v1.CycleNodeRequestInitialised, false, []metav1.Condition{paused}, metav1.ConditionFalse},
+		{"finished", v1.CycleNodeRequestSuccessful, true, nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cnr := newTestCycleNodeRequest(tt.phase)
+			cnr.Spec.Paused = tt.paused
+			cnr.Status.Conditions = tt.conditions
+			transitioner := newTestTransitioner(t, cnr)
+
+			assert.NoError(t, transitioner.updatePausedCondition())
+
+			// The condition is saved to the API
+			var saved v1.CycleNodeRequest
+			assert.NoError(t, transitioner.rm.Client.Get(context.TODO(), client.ObjectKeyFromObject(cnr), &saved))
+			condition := meta.FindStatusCondition(saved.Status.Conditions, v1.CycleNodeRequestConditionPaused)
+			if tt.expect == "" {
+				assert.Nil(t, condition)
+				return
+			}
+			if assert.NotNil(t, condition) {
+				assert.Equal(t, tt.expect, condition.Status)
+			}
+		})
+	}
+}
+
+func TestWaitToSelectNodes(t *testing.T) {
+	cnr := newTestCycleNodeRequest(v1.CycleNodeRequestInitialised)
+	cnr.Status.ActiveChildren = 2
+
+	successful := &v1.CycleNodeStatus{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-node-1", Namespace: "kube-system", Labels: map[string]string{"name": "test"}},
+		Status:     v1.CycleNodeStatusStatus{Phase: v1.CycleNodeStatusSuccessful},
+	}
+	inProgress := &v1.CycleNodeStatus{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-node-2", Namespace: "kube-system", Labels: map[string]string{"name": "test"}},
+		Status:     v1.CycleNodeStatusStatus{Phase: v1.CycleNodeStatusDrainingPods},
+	}
+	transitioner := newTestTransitioner(t, cnr, successful, inProgress)
+
+	result, err := transitioner.waitToSelectNodes(cnr.Status.NodesAvailable, "WaitingResume", "waiting")
+	assert.NoError(t, err)
+	assert.True(t, result.Requeue)
+
+	// Finished children are reaped while waiting, without leaving the Initialised phase
+	assert.Equal(t, v1.CycleNodeRequestInitialised, cnr.Status.Phase)
+	assert.Equal(t, int64(1), cnr.Status.ActiveChildren)
+
+	var children v1.CycleNodeStatusList
+	assert.NoError(t, transitioner.rm.Client.List(context.TODO(), &children))
+	assert.Len(t, children.Items, 1)
+}
+
+func TestCancel(t *testing.T) {
+	cnr := newTestCycleNodeRequest(v1.CycleNodeRequestCordoningNode)
+	cnr.Spec.Cancel = true
+	cnr.Status.CurrentNodes = []v1.CycleNodeRequestNode{
+		{Name: "node-1", ProviderID: "aws:///us-east-1a/i-1", NodeGroupName: "nodegroup"},
+		{Name: "node-2", ProviderID: "aws:///us-east-1a/i-2", NodeGroupName: "nodegroup"},
+	}
+
+	// node-2 has already been handed off for draining
+	draining := &v1.CycleNodeStatus{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-node-2", Namespace: "kube-system", Labels: map[string]string{"name": "test"}},
+		Spec:       v1.CycleNodeStatusSpec{NodeName: "node-2"},
+		Status:     v1.CycleNodeStatusStatus{Phase: v1.CycleNodeStatusDrainingPods},
+	}
+	transitioner := newTestTransitioner(t, cnr, draining)
+
+	cloudProvider := &testCloudProvider{attached: map[string]string{}}
+	transitioner.rm.CloudProvider = cloudProvider
+	transitioner.rm.RawClient = fakeclientset.NewSimpleClientset(
+		&corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "node-1", Labels: map[string]string{cycleNodeLabel: "test"}},
+			Spec:       corev1.NodeSpec{Unschedulable: true},
+		},
+		&corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "node-2", Labels: map[string]string{cycleNodeLabel: "test"}},
+			Spec:       corev1.NodeSpec{Unschedulable: true},
+		},
+	)
+
+	// The cancel takes precedence over the current phase
+	_, err := transitioner.Run()
+	assert.NoError(t, err)
+	assert.Equal(t, v1.CycleNodeRequestCancelling, cnr.Status.Phase)
+
+	// Only the node that isn't draining is rolled back
+	_, err = transitioner.Run()
+	assert.NoError(t, err)
+	assert.Equal(t, v1.CycleNodeRequestCancelled, cnr.Status.Phase)
+	assert.Empty(t, cnr.Status.CurrentNodes)
+	assert.Equal(t, map[string]string{"aws:///us-east-1a/i-1": "nodegroup"}, cloudProvider.attached)
+
+	node1, err := transitioner.rm.RawClient.CoreV1().Nodes().Get(context.TODO(), "node-1", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.False(t, node1.Spec.Unschedulable)
+	assert.NotContains(t, node1.Labels, cycleNodeLabel)
+	node2, err := transitioner.rm.RawClient.CoreV1().Nodes().Get(context.TODO(), "node-2", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.True(t, node2.Spec.Unschedulable)
+	assert.Equal(t, "test", node2.Labels[cycleNodeLabel])
+
+	// The draining node is left to finish
+	result, err := transitioner.Run()
+	assert.NoError(t, err)
+	assert.True(t, result.Requeue)
+	assert.Equal(t, int64(1), cnr.Status.ActiveChildren)
+
+	draining.Status.Phase = v1.CycleNodeStatusSuccessful
+	assert.NoError(t, transitioner.rm.Client.Update(context.TODO(), draining))
+
+	result, err = transitioner.Run()
+	assert.NoError(t, err)
+	assert.False(t, result.Requeue)
+	assert.Equal(t, v1.CycleNodeRequestCancelled, cnr.Status.Phase)
+	assert.Equal(t, int64(0), cnr.Status.ActiveChildren)
+}
+
+func TestCancel_CannotReattach(t *testing.T) {
+	cnr := newTestCycleNodeRequest(v1.CycleNodeRequestCancelling)
+	cnr.Spec.CycleSettings.Method = v1.CycleNodeRequestMethodWait
+	cnr.Status.CurrentNodes = []v1.CycleNodeRequestNode{
+		{Name: "node-1", ProviderID: "gce://my-project/us-central1-a/node-1", NodeGroupName: "nodegroup"},
+	}
+	transitioner := newTestTransitioner(t, cnr)
+
+	cloudProvider := &testCloudProvider{
+		attached:       map[string]string{},
+		cannotReattach: map[string]bool{"gce://my-project/us-central1-a/node-1": true},
+	}
+	transitioner.rm.CloudProvider = cloudProvider
+	transitioner.rm.RawClient = fakeclientset.NewSimpleClientset(
+		&corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "node-1", Labels: map[string]string{corev1.LabelHostname: "node-1"}},
+			Spec:       corev1.NodeSpec{Unschedulable: true},
+		},
+	)
+
+	_, err := transitioner.Run()
+	assert.NoError(t, err)
+	assert.Equal(t, v1.CycleNodeRequestCancelled, cnr.Status.Phase)
+	assert.Empty(t, cloudProvider.attached)
+
+	// The node is left cordoned and in progress, and handed off to be drained and terminated
+	node, err := transitioner.rm.RawClient.CoreV1().Nodes().Get(context.TODO(), "node-1", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.True(t, node.Spec.Unschedulable)
+	assert.Equal(t, "test", node.Labels[cycleNodeLabel])
+
+	var children v1.CycleNodeStatusList
+	assert.NoError(t, transitioner.rm.Client.List(context.TODO(), &children))
+	if assert.Len(t, children.Items, 1) {
+		assert.Equal(t, "node-1", children.Items[0].Spec.NodeName)
+		assert.Equal(t, v1.CycleNodeRequestMethod(v1.CycleNodeRequestMethodDrain), children.Items[0].Spec.CycleSettings.Method)
+	}
+}
+
+func TestCancellable(t *testing.T) {
+	tests := []struct {
+		phase  v1.CycleNodeRequestPhase
+		expect bool
+	}{
+		{v1.CycleNodeRequestUndefined, true},
+		{v1.CycleNodeRequestInitialised, true},
+		{v1.CycleNodeRequestWaitingTermination, true},
+		{v1.CycleNodeRequestHealing, false},
+		{v1.CycleNodeRequestFailed, false},
+		{v1.CycleNodeRequestSuccessful, false},
+		{v1.CycleNodeRequestCancelled, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.phase), func(t *testing.T) {
+			transitioner := newTestTransitioner(t, newTestCycleNodeRequest(tt.phase))
+			assert.Equal(t, tt.expect, transitioner.cancellable())
+		})
+	}
+}
+
+func TestRetry(t *testing.T) {
+	newNode := func(name string, labels map[string]string) *corev1.Node {
+		return &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+	}
+	inProgress := map[string]string{cycleNodeLabel: "test"}
+
+	tests := []struct {
+		name          string
+		retries       int
+		nodes         []v1.CycleNodeRequestNode
+		expectPhase   v1.CycleNodeRequestPhase
+		expectRetries int
+	}{
+		{"retry", 0, []v1.CycleNodeRequestNode{{Name: "node-1"}, {Name: "node-2"}}, v1.CycleNodeRequestInitialised, 1},
+		{"failed before selecting nodes", 1, nil, v1.CycleNodeRequestPending, 2},
+		{"retry limit reached", 2, []v1.CycleNodeRequestNode{{Name: "node-1"}}, v1.CycleNodeRequestFailed, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cnr := newTestCycleNodeRequest(v1.CycleNodeRequestFailed)
+			cnr.Annotations = map[string]string{v1.CycleNodeRequestRetryAnnotation: "true"}
+			cnr.Status.Message = "failed to cycle"
+			cnr.Status.Retries = tt.retries
+			cnr.Status.NumNodesCycled = 3
+			cnr.Status.NodesToTerminate = tt.nodes
+			cnr.Status.CurrentNodes = tt.nodes
+			cnr.Status.HealthChecks = map[string]v1.HealthCheckStatus{"node-1": {Checks: []bool{true}}}
+
+			// node-1 failed to cycle and node-2 is still being drained
+			draining := &v1.CycleNodeStatus{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-node-2", Namespace: "kube-system", Labels: map[string]string{"name": "test"}},
+				Spec:       v1.CycleNodeStatusSpec{NodeName: "node-2"},
+				Status:     v1.CycleNodeStatusStatus{Phase: v1.CycleNodeStatusDrainingPods},
+			}
+			node1, node2 := newNode("node-1", inProgress), newNode("node-2", inProgress)
+			transitioner := newTestTransitioner(t, cnr, draining, node1, node2)
+			transitioner.options.MaxRetries = 2
+			transitioner.rm.RawClient = fakeclientset.NewSimpleClientset(node1.DeepCopy(), node2.DeepCopy())
+			transitioner.rm.CloudProvider = &testCloudProvider{attached: map[string]string{}}
+
+			_, err := transitioner.Run()
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectPhase, cnr.Status.Phase)
+			assert.Equal(t, tt.expectRetries, cnr.Status.Retries)
+			assert.Equal(t, 3, cnr.Status.NumNodesCycled)
+			assert.NotContains(t, cnr.Annotations, v1.CycleNodeRequestRetryAnnotation)
+
+			node1, err = transitioner.rm.RawClient.CoreV1().Nodes().Get(context.TODO(), "node-1", metav1.GetOptions{})
+			assert.NoError(t, err)
+			node2, err = transitioner.rm.RawClient.CoreV1().Nodes().Get(context.TODO(), "node-2", metav1.GetOptions{})
+			assert.NoError(t, err)
+			assert.Contains(t, node2.Labels, cycleNodeLabel)
+
+			if tt.expectPhase == v1.CycleNodeRequestFailed {
+				assert.Equal(t, "failed to cycle", cnr.Status.Message)
+				assert.Contains(t, node1.Labels, cycleNodeLabel)
+				return
+			}
+			assert.Empty(t, cnr.Status.Message)
+			assert.Empty(t, cnr.Status.CurrentNodes)
+			assert.Nil(t, cnr.Status.HealthChecks)
+			assert.Equal(t, tt.nodes, cnr.Status.NodesToTerminate)
+			assert.NotContains(t, node1.Labels, cycleNodeLabel)
+		})
+	}
+}
+
+func TestRetry_FailedMidDrain(t *testing.T) {
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1", Labels: map[string]string{cycleNodeLabel: "test"}},
+		Spec:       corev1.NodeSpec{ProviderID: "aws:///us-east-1a/i-1", Unschedulable: true},
+	}
+	nodes := []v1.CycleNodeRequestNode{{Name: "node-1", ProviderID: "aws:///us-east-1a/i-1", NodeGroupName: "nodegroup"}}
+
+	cnr := newTestCycleNodeRequest(v1.CycleNodeRequestWaitingTermination)
+	cnr.Status.NodesToTerminate = nodes
+	cnr.Status.CurrentNodes = nodes
+	cnr.Status.ActiveChildren = 1
+
+	// The node was detached and cordoned before its CycleNodeStatus failed while draining it
+	failed := &v1.CycleNodeStatus{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-node-1", Namespace: "kube-system", Labels: map[string]string{"name": "test"}},
+		Spec:       v1.CycleNodeStatusSpec{NodeName: "node-1"},
+		Status:     v1.CycleNodeStatusStatus{Phase: v1.CycleNodeStatusFailed, Message: "timed out while draining pods"},
+	}
+	cloudProvider := &testCloudProvider{attached: map[string]string{}}
+	transitioner := newTestTransitioner(t, cnr, failed, node)
+	transitioner.options.MaxRetries = 1
+	transitioner.rm.RawClient = fakeclientset.NewSimpleClientset(node.DeepCopy())
+	transitioner.rm.CloudProvider = cloudProvider
+
+	_, err := transitioner.Run()
+	assert.NoError(t, err)
+	assert.Equal(t, v1.CycleNodeRequestFailed, cnr.Status.Phase)
+
+	cnr.Annotations = map[string]string{v1.CycleNodeRequestRetryAnnotation: "true"}
+	_, err = transitioner.Run()
+	assert.NoError(t, err)
+	assert.Equal(t, v1.CycleNodeRequestInitialised, cnr.Status.Phase)
+
+	// The node is rolled back so that it is selected and cycled again
+	assert.Equal(t, map[string]string{"aws:///us-east-1a/i-1": "nodegroup"}, cloudProvider.attached)
+	node, err = transitioner.rm.RawClient.CoreV1().Nodes().Get(context.TODO(), "node-1", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.False(t, node.Spec.Unschedulable)
+	assert.NotContains(t, node.Labels, cycleNodeLabel)
+}
+
+func TestPhaseConditions(t *testing.T) {
+	tests := []struct {
+		phase             v1.CycleNodeRequestPhase
+		expectProgressing metav1.ConditionStatus
+		expectDegraded    metav1.ConditionStatus
+	}{
+		{v1.CycleNodeRequestScalingUp, metav1.ConditionTrue, metav1.ConditionFalse},
+		{v1.CycleNodeRequestHealing, metav1.ConditionTrue, metav1.ConditionTrue},
+		{v1.CycleNodeRequestFailed, metav1.ConditionFalse, metav1.ConditionTrue},
+		{v1.CycleNodeRequestSuccessful, metav1.ConditionFalse, metav1.ConditionFalse},
+		{v1.CycleNodeRequestCancelled, metav1.ConditionFalse, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.phase), func(t *testing.T) {
+			transitioner := newTestTransitioner(t, newTestCycleNodeRequest(v1.CycleNodeRequestPending))
+			_, err := transitioner.transitionObject(tt.phase)
+			assert.NoError(t, err)
+
+			// The conditions are saved along with the phase
+			var saved v1.CycleNodeRequest
+			assert.NoError(t, transitioner.rm.Client.Get(context.TODO(), client.ObjectKeyFromObject(transitioner.cycleNodeRequest), &saved))
+
+			progressing := meta.FindStatusCondition(saved.Status.Conditions, v1.CycleNodeRequestConditionProgressing)
+			if assert.NotNil(t, progressing) {
+				assert.Equal(t, tt.expectProgressing, progressing.Status)
+				assert.Equal(t, string(tt.phase), progressing.Reason)
+			}
+
+			degraded := meta.FindStatusCondition(saved.Status.Conditions, v1.CycleNodeRequestConditionDegraded)
+			if tt.expectDegraded == "" {
+				assert.Nil(t, degraded)
+				return
+			}
+			if assert.NotNil(t, degraded) {
+				assert.Equal(t, tt.expectDegraded, degraded.Status)
+			}
+		})
+	}
+}
+
+func TestTransitionToHealing_DegradedMessage(t *testing.T) {
+	transitioner := newTestTransitioner(t, newTestCycleNodeRequest(v1.CycleNodeRequestScalingUp))
+
+	_, err := transitioner.transitionToHealing(fmt.Errorf("nodes failed to come up"))
+	assert.Error(t, err)
+
+	degraded := meta.FindStatusCondition(transitioner.cycleNodeRequest.Status.Conditions, v1.CycleNodeRequestConditionDegraded)
+	if assert.NotNil(t, degraded) {
+		assert.Equal(t, metav1.ConditionTrue, degraded.Status)
+		assert.Equal(t, "nodes failed to come up", degraded.Message)
+	}
+}
+
+func TestTransitionUndefined_ReplaceInPlace(t *testing.T) {
+	tests := []struct {
+		name           string
+		noReplacements bool
+		method         v1.CycleNodeRequestMethod
+		expectPhase    v1.CycleNodeRequestPhase
+	}{
+		{"replace in place", false, v1.CycleNodeRequestMethodReplaceInPlace, v1.CycleNodeRequestPending},
+		{"drain without replacements", true, v1.CycleNodeRequestMethodDrain, v1.CycleNodeRequestPending},
+		{"replace in place without replacements", true, v1.CycleNodeRequestMethodReplaceInPlace, v1.CycleNodeRequestHealing},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cnr := newTestCycleNodeRequest(v1.CycleNodeRequestUndefined)
+			cnr.Spec.Selector = metav1.LabelSelector{MatchLabels: map[string]string{"role": "test"}}
+			cnr.Spec.CycleSettings.Method = tt.method
+			transitioner := newTestTransitioner(t, cnr)
+			cloudProvider := newTestCloudProvider("test")
+			cloudProvider.noReplacements = tt.noReplacements
+			transitioner.rm.CloudProvider = cloudProvider
+
+			_, err := transitioner.Run()
+			assert.Equal(t, tt.expectPhase == v1.CycleNodeRequestHealing, err != nil)
+			assert.Equal(t, tt.expectPhase, cnr.Status.Phase)
+		})
+	}
+}
+
+func TestTransitionInitialised_ReplaceInPlace(t *testing.T) {
+	node1 := newTestZoneNode("node-1", "us-east-1a", nil)
+	node2 := newTestZoneNode("node-2", "us-east-1a", nil)
+	nodes := []v1.CycleNodeRequestNode{
+		{Name: "node-1", ProviderID: node1.Spec.ProviderID, NodeGroupName: "nodegroup"},
+		{Name: "node-2", ProviderID: node2.Spec.ProviderID, NodeGroupName: "nodegroup"},
+	}
+
+	cnr := newTestCycleNodeRequest(v1.CycleNodeRequestInitialised)
+	cnr.Spec.CycleSettings.Method = v1.CycleNodeRequestMethodReplaceInPlace
+	cnr.Status.NodesToTerminate = nodes
+	cnr.Status.NodesAvailable = append([]v1.CycleNodeRequestNode(nil), nodes...)
+	cloudProvider := newTestCloudProvider("aws", node1.Spec.ProviderID, node2.Spec.ProviderID)
+	transitioner := newTestTransitioner(t, cnr, node1, node2)
+	transitioner.rm.CloudProvider = cloudProvider
+
+	_, err := transitioner.Run()
+	assert.NoError(t, err)
+
+	// The selected node is left in the node group and goes straight to being cordoned
+	assert.Equal(t, v1.CycleNodeRequestCordoningNode, cnr.Status.Phase)
+	assert.Len(t, cnr.Status.CurrentNodes, 1)
+	assert.Len(t, cnr.Status.NodesAvailable, 1)
+	assert.Empty(t, cloudProvider.detached)
+}
+
+func TestTransitionWaitingTermination_ReplaceInPlace(t *testing.T) {
+	tests := []struct {
+		name        string
+		inProgress  bool
+		expectPhase v1.CycleNodeRequestPhase
+	}{
+		{"batch still terminating", true, v1.CycleNodeRequestWaitingTermination},
+		{"batch terminated", false, v1.CycleNodeRequestWaitingReplacement},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cnr := newTestCycleNodeRequest(v1.CycleNodeRequestWaitingTermination)
+			cnr.Spec.CycleSettings.Method = v1.CycleNodeRequestMethodReplaceInPlace
+			cnr.Spec.CycleSettings.Concurrency = intstr.FromInt(2)
+			cnr.Status.NodesAvailable = []v1.CycleNodeRequestNode{{Name: "node-3"}}
+
+			children := []runtime.Object{&v1.CycleNodeStatus{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-node-1", Namespace: "kube-system", Labels: map[string]string{"name": "test"}},
+				Status:     v1.CycleNodeStatusStatus{Phase: v1.CycleNodeStatusSuccessful},
+			}}
+			if tt.inProgress {
+				children = append(children, &v1.CycleNodeStatus{
+					ObjectMeta: metav1.ObjectMeta{Name: "test-node-2", Namespace: "kube-system", Labels: map[string]string{"name": "test"}},
+					Status:     v1.CycleNodeStatusStatus{Phase: v1.CycleNodeStatusDrainingPods},
+				})
+			}
+			transitioner := newTestTransitioner(t, cnr, children...)
+
+			// The next batch isn't selected until the whole batch has been terminated
+			result, err := transitioner.Run()
+			assert.NoError(t, err)
+			assert.True(t, result.Requeue)
+			assert.Equal(t, tt.expectPhase, cnr.Status.Phase)
+			assert.Equal(t, tt.expectPhase == v1.CycleNodeRequestWaitingReplacement, cnr.Status.ScaleUpStarted != nil)
+		})
+	}
+}
+
+func TestTransitionWaitingReplacement(t *testing.T) {
+	node1 := newTestZoneNode("node-1", "us-east-1a", nil)
+	node2 := newTestZoneNode("node-2", "us-east-1a", nil)
+
+	tests := []struct {
+		name        string
+		waited      time.Duration
+		providerIDs []string
+		expectPhase v1.CycleNodeRequestPhase
+	}{
+		{"not replaced yet", 2 * scaleUpWait, []string{node1.Spec.ProviderID}, v1.CycleNodeRequestWaitingReplacement},
+		{"replaced", 2 * scaleUpWait, []string{node1.Spec.ProviderID, node2.Spec.ProviderID}, v1.CycleNodeRequestInitialised},
+		{"timed out", scaleUpLimit + time.Minute, []string{node1.Spec.ProviderID}, v1.CycleNodeRequestHealing},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cnr := newTestCycleNodeRequest(v1.CycleNodeRequestWaitingReplacement)
+			cnr.Spec.CycleSettings.Method = v1.CycleNodeRequestMethodReplaceInPlace
+			cnr.Status.NodeGroupSize = 2
+			scaleUpStarted := metav1.NewTime(time.Now().Add(-tt.waited))
+			cnr.Status.ScaleUpStarted = &scaleUpStarted
+			transitioner := newTestTransitioner(t, cnr, node1, node2)
+			transitioner.rm.CloudProvider = newTestCloudProvider("aws", tt.providerIDs...)
+
+			_, err := transitioner.Run()
+			assert.Equal(t, tt.expectPhase == v1.CycleNodeRequestHealing, err != nil)
+			assert.Equal(t, tt.expectPhase, cnr.Status.Phase)
+			if tt.expectPhase == v1.CycleNodeRequestHealing {
+				assert.Contains(t, cnr.Status.Message, "replacement nodes failed to come up in time")
+			}
+		})
+	}
+}
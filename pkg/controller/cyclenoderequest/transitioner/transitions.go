@@ -7,7 +7,9 @@ import (
 	"time"
 
 	v1 "github.com/atlassian-labs/cyclops/pkg/apis/atlassian/v1"
+	"github.com/atlassian-labs/cyclops/pkg/checks"
 	"github.com/atlassian-labs/cyclops/pkg/k8s"
+	"github.com/atlassian-labs/cyclops/pkg/maintenance"
 	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -41,6 +43,55 @@ func (t *CycleNodeRequestTransitioner) transitionUndefined() (reconcile.Result,
 		return t.transitionToHealing(fmt.Errorf("selector cannot be empty"))
 	}
 
+	// Check the maintenance windows can be parsed
+	for _, window := range t.cycleNodeRequest.Spec.MaintenanceWindows {
+		if err := maintenance.ValidateWindow(&window); err != nil {
+			return t.transitionToHealing(errors.Wrap(err, "invalid maintenance window"))
+		}
+	}
+
+	// Check the health checks use a protocol they can be performed with
+	for _, healthCheck := range t.cycleNodeRequest.Spec.HealthChecks {
+		if err := checks.ValidateHealthCheck(&healthCheck); err != nil {
+			return t.transitionToHealing(errors.Wrapf(err, "invalid health check %s", healthCheck.Endpoint))
+		}
+	}
+	for _, preTerminationCheck := range t.cycleNodeRequest.Spec.PreTerminationChecks {
+		if err := checks.ValidatePreTerminationCheck(&preTerminationCheck); err != nil {
+			return t.transitionToHealing(errors.Wrapf(err, "invalid pre-termination check %s", preTerminationCheck.Endpoint))
+		}
+	}
+
+	// Check the Secrets referenced by the health checks and pre-termination checks exist
+	if err := t.validateSecretReferences(); err != nil {
+		return t.transitionToHealing(err)
+	}
+
+	// Check the Kubernetes health checks name the objects they need
+	for _, healthCheck := range t.cycleNodeRequest.Spec.KubernetesHealthChecks {
+		if err := healthCheck.Validate(); err != nil {
+			return t.transitionToHealing(errors.Wrapf(err, "invalid kubernetes health check %s", healthCheck.String()))
+		}
+	}
+
+	// Check the Prometheus checks can be evaluated
+	for _, check := range t.cycleNodeRequest.Spec.PrometheusChecks {
+		if err := checks.ValidatePrometheusCheck(&check); err != nil {
+			return t.transitionToHealing(errors.Wrapf(err, "invalid prometheus check %s", check.Name))
+		}
+	}
+	if len(t.cycleNodeRequest.Spec.PrometheusChecks) > 0 && t.options.PrometheusAPI == nil {
+		return t.transitionToHealing(fmt.Errorf("prometheus checks require the controller to be given the address of Prometheus"))
+	}
+
+	// Check the cloud provider replaces terminated instances for the ReplaceInPlace method, otherwise the nodes
+	// would never be replaced
+	if t.cycleNodeRequest.Spec.CycleSettings.Method == v1.CycleNodeRequestMethodReplaceInPlace &&
+		t.rm.CloudProvider != nil && !t.rm.CloudProvider.ReplacesTerminatedInstances() {
+		return t.transitionToHealing(fmt.Errorf("the %s method is not supported by the %s cloud provider",
+			v1.CycleNodeRequestMethodReplaceInPlace, t.rm.CloudProvider.Name()))
+	}
+
 	// Protect against failure case where cyclops checks for leftover CycleNodeStatus objects using the CycleNodeRequest name in the label selector
 	// Label values must be no more than 63 characters long
 	validationErrors := validation.IsDNS1035Label(t.cycleNodeRequest.Name)
@@ -171,16 +222,22 @@ func (t *CycleNodeRequestTransitioner) transitionPending() (reconcile.Result, er
 		}
 	}
 
-	if len(t.cycleNodeRequest.Spec.HealthChecks) > 0 {
+	if t.hasHealthChecks() {
 		if err = t.performInitialHealthChecks(kubeNodes); err != nil {
 			return t.transitionToHealing(err)
 		}
 	}
 
-	// If the concurrency isn't provided, then default it to the number of nodesToTerminate
-	if t.cycleNodeRequest.Spec.CycleSettings.Concurrency <= 0 {
-		t.cycleNodeRequest.Spec.CycleSettings.Concurrency = int64(len(t.cycleNodeRequest.Status.NodesToTerminate))
+	// Keep track of the size of the node groups so the ReplaceInPlace method knows when terminated nodes
+	// have been replaced
+	t.cycleNodeRequest.Status.NodeGroupSize = len(nodeGroupInstances)
+
+	// Resolve the concurrency against the number of nodesToTerminate. If it isn't provided, then it defaults to all of them
+	concurrency, err := t.cycleNodeRequest.Spec.CycleSettings.ResolveConcurrency(len(t.cycleNodeRequest.Status.NodesToTerminate))
+	if err != nil {
+		return t.transitionToHealing(errors.Wrap(err, "invalid concurrency"))
 	}
+	t.cycleNodeRequest.Status.Concurrency = concurrency
 
 	// Remove any children that may be left over from previous runs. Should most often be a no-op.
 	// We do this after all the other error checking to avoid changing cluster state unless we would actually
@@ -196,12 +253,28 @@ func (t *CycleNodeRequestTransitioner) transitionPending() (reconcile.Result, er
 // If there aren't any more nodes that need to be cycled, it transitions straight to successful.
 // It detaches a number of nodes from the node group, based on the available concurrency, which will
 // trigger the cloud provider to create a new node in the old node's AZs.
+// For the ReplaceInPlace method the nodes are not detached, and it transitions straight to the Cordoning phase.
+// No nodes are selected while the CycleNodeRequest is paused or outside of its maintenance windows.
 func (t *CycleNodeRequestTransitioner) transitionInitialised() (reconcile.Result, error) {
 	t.rm.LogEvent(t.cycleNodeRequest, "SelectingNodes", "Selecting nodes to terminate")
 
 	// The maximum nodes we can select are bounded by our concurrency. We take into account the number
 	// of nodes we are already working on, and only introduce up to our concurrency cap more nodes in this step.
-	maxNodesToSelect := t.cycleNodeRequest.Spec.CycleSettings.Concurrency - t.cycleNodeRequest.Status.ActiveChildren
+	// While a rollout is ramping up, the batch size is limited further.
+	maxNodesToSelect := t.batchSize() - t.cycleNodeRequest.Status.ActiveChildren
+
+	// The cluster-wide cycling budget shared with the other cycleNodeRequests can bound it further
+	budget, budgetMessage, err := t.cyclingBudget(time.Now())
+	if err != nil {
+		return t.transitionToHealing(err)
+	}
+	if budget > 0 && budget < maxNodesToSelect {
+		maxNodesToSelect = budget
+	}
+
+	// Picking the nodes takes them out of the available nodes, which are put back if it has to wait to select them
+	nodesAvailable := append([]v1.CycleNodeRequestNode(nil), t.cycleNodeRequest.Status.NodesAvailable...)
+
 	t.rm.Logger.Info("Selecting nodes to terminate", "numNodes", maxNodesToSelect)
 	nodes, numNodesInProgress, err := t.getNodesToTerminate(maxNodesToSelect)
 	if err != nil {
@@ -216,6 +289,42 @@ func (t *CycleNodeRequestTransitioner) transitionInitialised() (reconcile.Result
 		return reconcileResult, err
 	}
 
+	// Stop selecting new nodes while paused or outside of the maintenance windows. Nodes that are already being
+	// cycled are left to finish.
+	if t.cycleNodeRequest.Spec.Paused {
+		return t.waitToSelectNodes(nodesAvailable, "WaitingResume", "Paused, waiting to be resumed before selecting more nodes")
+	}
+
+	inMaintenanceWindow, err := maintenance.InWindow(t.cycleNodeRequest.Spec.MaintenanceWindows, time.Now())
+	if err != nil {
+		return t.transitionToHealing(err)
+	}
+	if !inMaintenanceWindow {
+		return t.waitToSelectNodes(nodesAvailable, "WaitingMaintenanceWindow", "Outside of maintenance windows, waiting to select more nodes")
+	}
+
+	if budget == 0 {
+		t.setCondition(v1.CycleNodeRequestConditionWaitingForBudget, metav1.ConditionTrue, "BudgetExhausted", budgetMessage)
+		return t.waitToSelectNodes(nodesAvailable, "WaitingCyclingBudget", budgetMessage)
+	}
+	if t.budgetEnabled() {
+		t.setCondition(v1.CycleNodeRequestConditionWaitingForBudget, metav1.ConditionFalse, "BudgetAvailable", "Nodes can be selected within the cluster-wide cycling budget")
+	}
+
+	// Hold off selecting more nodes while any of the Prometheus checks are not passing, unless they haven't been
+	// able to be evaluated for too long
+	now := time.Now()
+	passed, message, err := t.performPrometheusChecks(now)
+	if err != nil {
+		if t.prometheusChecksErrorTimedOut(now) {
+			return t.transitionToHealing(errors.Wrapf(err, "prometheus checks could not be evaluated for %v", t.options.PrometheusCheckErrorTimeout))
+		}
+		return t.waitToSelectNodes(nodesAvailable, "WaitingPrometheusChecks", err.Error())
+	}
+	if !passed {
+		return t.waitToSelectNodes(nodesAvailable, "WaitingPrometheusChecks", message)
+	}
+
 	nodeGroups, err := t.rm.CloudProvider.GetNodeGroups(t.cycleNodeRequest.GetNodeGroupNames())
 	if err != nil {
 		return t.transitionToHealing(err)
@@ -265,6 +374,16 @@ func (t *CycleNodeRequestTransitioner) transitionInitialised() (reconcile.Result
 		}
 	}
 
+	// The ReplaceInPlace method doesn't scale up, the nodes are left in the node group and go straight to being
+	// cordoned. The node group replaces them once they have been terminated.
+	if t.cycleNodeRequest.Spec.CycleSettings.Method == v1.CycleNodeRequestMethodReplaceInPlace {
+		t.recordNodeSelections(time.Now())
+		t.recordRolloutBatch()
+		t.setZoneReplacements(time.Now())
+		t.rm.LogEvent(t.cycleNodeRequest, "ReplacingNodes", "Replacing nodes in place: %v", t.cycleNodeRequest.Status.CurrentNodes)
+		return t.transitionObject(v1.CycleNodeRequestCordoningNode)
+	}
+
 	// Detach the nodes from the nodes group - this will trigger a replacement, and start the scale up
 	// Detach each node independently so that valid nodes are not affected by invalid nodes
 	t.rm.LogEvent(t.cycleNodeRequest, "DetachingNodes", "Detaching instances from nodes group: %v", t.cycleNodeRequest.Status.CurrentNodes)
@@ -289,10 +408,13 @@ func (t *CycleNodeRequestTransitioner) transitionInitialised() (reconcile.Result
 	}
 
 	t.cycleNodeRequest.Status.CurrentNodes = validNodes
+	t.recordNodeSelections(time.Now())
+	t.recordRolloutBatch()
 
 	// Set the scale up started time
 	currentTime := metav1.Now()
 	t.cycleNodeRequest.Status.ScaleUpStarted = &currentTime
+	t.setZoneReplacements(currentTime.Time)
 	return t.transitionObject(v1.CycleNodeRequestScalingUp)
 }
 
@@ -369,6 +491,12 @@ func (t *CycleNodeRequestTransitioner) transitionScalingUp() (reconcile.Result,
 		return reconcile.Result{Requeue: true, RequeueAfter: requeueDuration}, nil
 	}
 
+	// The new nodes must have come up in the same zones as the nodes they replace
+	if ready, message := t.zoneReplacementsReady(kubeNodes); !ready {
+		t.rm.LogEvent(t.cycleNodeRequest, "ScalingUpWaiting", message)
+		return reconcile.Result{Requeue: true, RequeueAfter: requeueDuration}, nil
+	}
+
 	// Remove any nodes from the CNR object which are found to have been removed prematurely due to a race condition
 	for _, nodeToRemove := range nodesToRemove {
 		for i, node := range t.cycleNodeRequest.Status.CurrentNodes {
@@ -381,7 +509,7 @@ func (t *CycleNodeRequestTransitioner) transitionScalingUp() (reconcile.Result,
 	}
 
 	// Skip looping through nodes if no health checks need to be performed
-	if len(t.cycleNodeRequest.Spec.HealthChecks) > 0 {
+	if t.hasHealthChecks() {
 		allHealthChecksPassed, err := t.performCyclingHealthChecks(kubeNodes)
 		if err != nil {
 			return t.transitionToHealing(err)
@@ -497,30 +625,186 @@ func (t *CycleNodeRequestTransitioner) transitionWaitingTermination() (reconcile
 		return t.transitionToHealing(err)
 	}
 
+	// The ReplaceInPlace method works in batches. Wait for the whole batch to be terminated, then wait for the
+	// node group to replace it before selecting more nodes.
+	if t.cycleNodeRequest.Spec.CycleSettings.Method == v1.CycleNodeRequestMethodReplaceInPlace &&
+		desiredPhase == v1.CycleNodeRequestInitialised {
+		if t.cycleNodeRequest.Status.ActiveChildren > 0 {
+			return reconcile.Result{Requeue: true, RequeueAfter: requeueDuration}, nil
+		}
+
+		currentTime := metav1.Now()
+		t.cycleNodeRequest.Status.ScaleUpStarted = &currentTime
+		return t.transitionObject(v1.CycleNodeRequestWaitingReplacement)
+	}
+
+	// While a rollout is ramping up, wait for the whole batch to finish and bake it before selecting more nodes
+	if t.rolloutInProgress() && desiredPhase == v1.CycleNodeRequestInitialised &&
+		len(t.cycleNodeRequest.Status.NodesAvailable) > 0 {
+		if t.cycleNodeRequest.Status.ActiveChildren > 0 {
+			return reconcile.Result{Requeue: true, RequeueAfter: requeueDuration}, nil
+		}
+
+		return t.transitionToBaking()
+	}
+
 	return t.transitionObject(desiredPhase)
 }
 
+// transitionWaitingReplacement transitions any CycleNodeRequests in the WaitingReplacement phase to the
+// Initialised phase. It is only used by the ReplaceInPlace method, and waits until the node groups are back at
+// their original size with all instances ready in the cloud provider and in Kubernetes.
+func (t *CycleNodeRequestTransitioner) transitionWaitingReplacement() (reconcile.Result, error) {
+	scaleUpStarted := t.cycleNodeRequest.Status.ScaleUpStarted
+
+	// Check we have waited long enough - give the replacement nodes some time to start up
+	if time.Since(scaleUpStarted.Time) <= scaleUpWait {
+		t.rm.LogEvent(t.cycleNodeRequest, "WaitingReplacement", "Waiting for replacement nodes to be ready")
+		return reconcile.Result{Requeue: true, RequeueAfter: requeueDuration}, nil
+	}
+
+	nodeGroups, err := t.rm.CloudProvider.GetNodeGroups(t.cycleNodeRequest.GetNodeGroupNames())
+	if err != nil {
+		return t.transitionToHealing(err)
+	}
+
+	// If we have exceeded the max scale up time, then fail
+	if scaleUpStarted.Add(scaleUpLimit).Before(time.Now()) {
+		return t.transitionToHealing(
+			fmt.Errorf("replacement nodes failed to come up in time - node group instances: %d/%d, instances not ready in cloud provider: %+v",
+				len(nodeGroups.Instances()), t.cycleNodeRequest.Status.NodeGroupSize, nodeGroups.NotReadyInstances()))
+	}
+
+	kubeNodes, err := t.listReadyNodes(false)
+	if err != nil {
+		return t.transitionToHealing(err)
+	}
+
+	// The terminated nodes were never detached, so the node groups shrink while the nodes are terminated and
+	// grow back to their original size once the replacements have been created.
+	allInstancesReplaced := len(nodeGroups.Instances()) >= t.cycleNodeRequest.Status.NodeGroupSize
+	allInstancesReady := len(nodeGroups.ReadyInstances()) >= len(nodeGroups.Instances())
+	allKubernetesNodesReady := len(kubeNodes) >= len(nodeGroups.Instances())
+
+	t.rm.Logger.Info("Waiting for replacement nodes to be ready",
+		"numReadyInstances", len(nodeGroups.ReadyInstances()),
+		"numInstances", len(nodeGroups.Instances()),
+		"nodeGroupSize", t.cycleNodeRequest.Status.NodeGroupSize,
+		"numKubeNodesReady", len(kubeNodes))
+
+	if !(allInstancesReplaced && allInstancesReady && allKubernetesNodesReady) {
+		t.rm.LogEvent(t.cycleNodeRequest, "WaitingReplacement", "Waiting for replacement nodes to be ready")
+		return reconcile.Result{Requeue: true, RequeueAfter: requeueDuration}, nil
+	}
+
+	// The replacement nodes must have come up in the same zones as the nodes they replace
+	if ready, message := t.zoneReplacementsReady(kubeNodes); !ready {
+		t.rm.LogEvent(t.cycleNodeRequest, "WaitingReplacement", message)
+		return reconcile.Result{Requeue: true, RequeueAfter: requeueDuration}, nil
+	}
+
+	// Skip looping through nodes if no health checks need to be performed
+	if t.hasHealthChecks() {
+		allHealthChecksPassed, err := t.performCyclingHealthChecks(kubeNodes)
+		if err != nil {
+			return t.transitionToHealing(err)
+		}
+
+		if !allHealthChecksPassed {
+			// Reconcile any health checks passed to the cnr object
+			if err := t.rm.UpdateObject(t.cycleNodeRequest); err != nil {
+				return t.transitionToHealing(err)
+			}
+
+			return reconcile.Result{Requeue: true, RequeueAfter: requeueDuration}, nil
+		}
+	}
+
+	t.rm.LogEvent(t.cycleNodeRequest, "ReplacementCompleted", "Replacement nodes are now ready")
+
+	if t.rolloutInProgress() && len(t.cycleNodeRequest.Status.NodesAvailable) > 0 {
+		return t.transitionToBaking()
+	}
+
+	return t.transitionObject(v1.CycleNodeRequestInitialised)
+}
+
+// transitionBaking transitions any CycleNodeRequests in the Baking phase to the Initialised phase once the bake
+// duration has passed since the last batch of the rollout finished. The health checks on the new nodes are repeated
+// while baking, and any failure sends the CycleNodeRequest to Healing before more nodes are selected.
+func (t *CycleNodeRequestTransitioner) transitionBaking() (reconcile.Result, error) {
+	if t.hasHealthChecks() {
+		kubeNodes, err := t.listReadyNodes(false)
+		if err != nil {
+			return t.transitionToHealing(err)
+		}
+
+		if err := t.performBakingHealthChecks(kubeNodes); err != nil {
+			return t.transitionToHealing(err)
+		}
+	}
+
+	// A Prometheus check that doesn't pass while baking fails the rollout. If the checks can't be evaluated, keep
+	// baking until they can, or until they haven't been able to be evaluated for too long.
+	now := time.Now()
+	passed, message, err := t.performPrometheusChecks(now)
+	if err != nil {
+		if t.prometheusChecksErrorTimedOut(now) {
+			return t.transitionToHealing(errors.Wrapf(err, "baking: prometheus checks could not be evaluated for %v", t.options.PrometheusCheckErrorTimeout))
+		}
+		t.rm.LogWarningEvent(t.cycleNodeRequest, "WaitingPrometheusChecks", err.Error())
+		if err := t.rm.UpdateObject(t.cycleNodeRequest); err != nil {
+			return t.transitionToHealing(err)
+		}
+		return reconcile.Result{Requeue: true, RequeueAfter: requeueDuration}, nil
+	}
+	if !passed {
+		return t.transitionToHealing(fmt.Errorf("baking: %s", message))
+	}
+
+	if bakeStarted := t.cycleNodeRequest.Status.BakeStarted; bakeStarted != nil {
+		if remaining := time.Until(bakeStarted.Add(t.bakeDuration())); remaining > 0 {
+			t.rm.LogEvent(t.cycleNodeRequest, "Baking", "Baking batch %d of the rollout, %v left", t.cycleNodeRequest.Status.RolloutBatch, remaining.Round(time.Second))
+
+			requeueAfter := requeueDuration
+			if remaining < requeueAfter {
+				requeueAfter = remaining
+			}
+			return reconcile.Result{Requeue: true, RequeueAfter: requeueAfter}, nil
+		}
+	}
+
+	t.rm.LogEvent(t.cycleNodeRequest, "BakingCompleted", "Finished baking batch %d of the rollout", t.cycleNodeRequest.Status.RolloutBatch)
+	t.cycleNodeRequest.Status.BakeStarted = nil
+	return t.transitionObject(v1.CycleNodeRequestInitialised)
+}
+
 // transitionFailed handles failed CycleNodeRequests
 func (t *CycleNodeRequestTransitioner) transitionHealing() (reconcile.Result, error) {
+	// Let the upstream hosts undo the pre-termination triggers of nodes that won't be terminated now
+	if len(t.cycleNodeRequest.Status.PreTerminationChecks) > 0 {
+		nodes, err := t.currentNodesNotHandedOff()
+		if err != nil {
+			return t.transitionToFailed(err)
+		}
+		t.sendPreTerminationCancels(nodes)
+	}
+
 	nodeGroups, err := t.rm.CloudProvider.GetNodeGroups(t.cycleNodeRequest.GetNodeGroupNames())
 	if err != nil {
 		return t.transitionToFailed(err)
 	}
 
-	// try and re-attach the nodes, if any were un-attached
+	// try and re-attach the nodes, if any were un-attached, and un-cordon the ones that were
 	for _, node := range t.cycleNodeRequest.Status.NodesToTerminate {
-		t.rm.LogEvent(t.cycleNodeRequest, "AttachingNodes", "Attaching instances to nodes group: %v", node.Name)
-		alreadyAttached, err := nodeGroups.AttachInstance(node.ProviderID, node.NodeGroupName)
-		if alreadyAttached {
-			continue
-		}
+		attached, err := t.attachOrTerminateNode(nodeGroups, node)
 		if err != nil {
 			return t.transitionToFailed(err)
 		}
-	}
+		if !attached {
+			continue
+		}
 
-	// un-cordon after attach as well
-	for _, node := range t.cycleNodeRequest.Status.NodesToTerminate {
 		t.rm.LogEvent(t.cycleNodeRequest, "UncordoningNodes", "Uncordoning nodes in node group: %v", node.Name)
 		if err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
 			return k8s.UncordonNode(node.Name, t.rm.RawClient)
@@ -532,6 +816,70 @@ func (t *CycleNodeRequestTransitioner) transitionHealing() (reconcile.Result, er
 	return t.transitionToFailed(nil)
 }
 
+// transitionCancelling rolls back the nodes that the cancelled CycleNodeRequest was working on and transitions it
+// to the Cancelled phase. Nodes that were detached but have not been handed off to a CycleNodeStatus yet are
+// re-attached to their node group, uncordoned and no longer marked as in progress, or drained and terminated if their
+// instance can't be re-attached.
+// Nodes that are already being drained are left to finish.
+func (t *CycleNodeRequestTransitioner) transitionCancelling() (reconcile.Result, error) {
+	nodesToRollBack, err := t.currentNodesNotHandedOff()
+	if err != nil {
+		return t.transitionToFailed(err)
+	}
+
+	t.sendPreTerminationCancels(nodesToRollBack)
+
+	if len(nodesToRollBack) > 0 {
+		nodeGroups, err := t.rm.CloudProvider.GetNodeGroups(t.cycleNodeRequest.GetNodeGroupNames())
+		if err != nil {
+			return t.transitionToFailed(err)
+		}
+
+		for _, node := range nodesToRollBack {
+			attached, err := t.attachOrTerminateNode(nodeGroups, node)
+			if err != nil {
+				return t.transitionToFailed(err)
+			}
+			if !attached {
+				continue
+			}
+
+			t.rm.LogEvent(t.cycleNodeRequest, "UncordoningNodes", "Uncordoning nodes in node group: %v", node.Name)
+			if err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+				return k8s.UncordonNode(node.Name, t.rm.RawClient)
+			}); err != nil {
+				return t.transitionToFailed(err)
+			}
+
+			if err := t.removeCycleNodeLabel(node.Name); err != nil {
+				return t.transitionToFailed(err)
+			}
+		}
+	}
+
+	t.rm.LogEvent(t.cycleNodeRequest, "Cancelled", "Cancelled, rolled back nodes: %v", nodesToRollBack)
+	t.cycleNodeRequest.Status.CurrentNodes = []v1.CycleNodeRequestNode{}
+	return t.transitionObject(v1.CycleNodeRequestCancelled)
+}
+
+// transitionCancelled handles cancelled CycleNodeRequests. It keeps reaping the children that were already being
+// drained when the CycleNodeRequest was cancelled until they have all finished.
+func (t *CycleNodeRequestTransitioner) transitionCancelled() (reconcile.Result, error) {
+	if _, err := t.reapChildren(); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	if err := t.rm.UpdateObject(t.cycleNodeRequest); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	if t.cycleNodeRequest.Status.ActiveChildren > 0 {
+		return reconcile.Result{Requeue: true, RequeueAfter: transitionDuration}, nil
+	}
+
+	return reconcile.Result{}, nil
+}
+
 // transitionFailed handles failed CycleNodeRequests
 func (t *CycleNodeRequestTransitioner) transitionFailed() (reconcile.Result, error) {
 	shouldRequeue, err := t.finalReapChildren()
@@ -2,12 +2,16 @@ package transitioner
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	v1 "github.com/atlassian-labs/cyclops/pkg/apis/atlassian/v1"
 	"github.com/atlassian-labs/cyclops/pkg/cloudprovider"
+	"github.com/atlassian-labs/cyclops/pkg/k8s"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -35,6 +39,7 @@ func (t *CycleNodeRequestTransitioner) transitionToFailed(err error) (reconcile.
 func (t *CycleNodeRequestTransitioner) transitionToSuccessful() (reconcile.Result, error) {
 	t.rm.LogEvent(t.cycleNodeRequest, "Successful", "Successfully cycled nodes")
 	t.cycleNodeRequest.Status.Phase = v1.CycleNodeRequestSuccessful
+	t.setPhaseConditions()
 
 	// Notify that the cycling has succeeded
 	if t.rm.Notifier != nil {
@@ -50,6 +55,7 @@ func (t *CycleNodeRequestTransitioner) transitionToSuccessful() (reconcile.Resul
 func (t *CycleNodeRequestTransitioner) transitionObject(desiredPhase v1.CycleNodeRequestPhase) (reconcile.Result, error) {
 	currentPhase := t.cycleNodeRequest.Status.Phase
 	t.cycleNodeRequest.Status.Phase = desiredPhase
+	t.setPhaseConditions()
 	if err := t.rm.UpdateObject(t.cycleNodeRequest); err != nil {
 		return reconcile.Result{}, err
 	}
@@ -89,19 +95,7 @@ func (t *CycleNodeRequestTransitioner) reapChildren() (v1.CycleNodeRequestPhase,
 	nextPhase := t.cycleNodeRequest.Status.Phase
 
 	// List the cycleNodeStatus objects in the cluster
-	cycleNodeStatusList := &v1.CycleNodeStatusList{}
-
-	labelSelector, err := labels.Parse("name=" + t.cycleNodeRequest.Name)
-	if err != nil {
-		return nextPhase, err
-	}
-
-	listOptions := client.ListOptions{
-		Namespace:     t.cycleNodeRequest.Namespace,
-		LabelSelector: labelSelector,
-	}
-
-	err = t.rm.Client.List(context.TODO(), cycleNodeStatusList, &listOptions)
+	cycleNodeStatusList, err := t.listChildren()
 	if err != nil {
 		return nextPhase, err
 	}
@@ -144,13 +138,277 @@ func (t *CycleNodeRequestTransitioner) reapChildren() (v1.CycleNodeRequestPhase,
 	// It is assumed that nodes selected for cycling will take roughly the same time to finish
 	// Bringing up multiple nodes together will speed up the whole process as well as spread out pods properly across the new nodes
 	// If the next phase should be failed, skip this since transitioning back to initialised would be flip-flopping behaviour
-	if nextPhase != v1.CycleNodeRequestFailed && t.cycleNodeRequest.Status.ActiveChildren <= t.cycleNodeRequest.Spec.CycleSettings.Concurrency/2 {
-		t.rm.Logger.Info("Transition back to Initialised to grab more child nodes", "ActiveChildren", t.cycleNodeRequest.Status.ActiveChildren, "Concurrency", t.cycleNodeRequest.Spec.CycleSettings.Concurrency)
+	if nextPhase != v1.CycleNodeRequestFailed && t.cycleNodeRequest.Status.ActiveChildren <= t.concurrency()/2 {
+		t.rm.Logger.Info("Transition back to Initialised to grab more child nodes", "ActiveChildren", t.cycleNodeRequest.Status.ActiveChildren, "Concurrency", t.concurrency())
 		nextPhase = v1.CycleNodeRequestInitialised
 	}
 	return nextPhase, nil
 }
 
+// concurrency returns the number of nodes to work on in parallel, which is resolved in the Pending phase.
+// CycleNodeRequests that passed the Pending phase before it was resolved have it defaulted in the spec instead.
+func (t *CycleNodeRequestTransitioner) concurrency() int64 {
+	if t.cycleNodeRequest.Status.Concurrency > 0 {
+		return t.cycleNodeRequest.Status.Concurrency
+	}
+	return int64(t.cycleNodeRequest.Spec.CycleSettings.Concurrency.IntValue())
+}
+
+// waitToSelectNodes keeps the CycleNodeRequest in the Initialised phase without selecting new nodes, for example
+// while outside of the maintenance windows. The available nodes are reset to nodesAvailable, from before any nodes
+// were picked. It continues to reap children so that nodes which were already being cycled can finish.
+func (t *CycleNodeRequestTransitioner) waitToSelectNodes(nodesAvailable []v1.CycleNodeRequestNode, reason, message string) (reconcile.Result, error) {
+	t.rm.LogEvent(t.cycleNodeRequest, reason, message)
+	t.cycleNodeRequest.Status.NodesAvailable = nodesAvailable
+
+	desiredPhase, err := t.reapChildren()
+	if err != nil {
+		return t.transitionToHealing(err)
+	}
+	if desiredPhase == v1.CycleNodeRequestFailed {
+		return t.transitionObject(desiredPhase)
+	}
+
+	if err := t.rm.UpdateObject(t.cycleNodeRequest); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	return reconcile.Result{Requeue: true, RequeueAfter: requeueDuration}, nil
+}
+
+// setCondition sets a condition of the CycleNodeRequest for the current generation. It doesn't save the
+// CycleNodeRequest.
+func (t *CycleNodeRequestTransitioner) setCondition(conditionType string, status metav1.ConditionStatus, reason, message string) {
+	k8s.SetCondition(&t.cycleNodeRequest.Status.Conditions, t.cycleNodeRequest.Generation, conditionType, status, reason, message)
+}
+
+// setPhaseConditions sets the Progressing and Degraded conditions to match the phase of the CycleNodeRequest. It
+// doesn't save the CycleNodeRequest.
+func (t *CycleNodeRequestTransitioner) setPhaseConditions() {
+	phase := t.cycleNodeRequest.Status.Phase
+	if phase == v1.CycleNodeRequestUndefined {
+		return
+	}
+	reason := string(phase)
+
+	switch phase {
+	case v1.CycleNodeRequestSuccessful:
+		t.setCondition(v1.CycleNodeRequestConditionProgressing, metav1.ConditionFalse, reason, "Finished cycling nodes")
+		t.setCondition(v1.CycleNodeRequestConditionDegraded, metav1.ConditionFalse, reason, "")
+	case v1.CycleNodeRequestHealing:
+		t.setCondition(v1.CycleNodeRequestConditionProgressing, metav1.ConditionTrue, reason, "Putting the nodes back in a consistent state after a failure")
+		t.setCondition(v1.CycleNodeRequestConditionDegraded, metav1.ConditionTrue, reason, t.cycleNodeRequest.Status.Message)
+	case v1.CycleNodeRequestFailed:
+		t.setCondition(v1.CycleNodeRequestConditionProgressing, metav1.ConditionFalse, reason, "Stopped cycling nodes after a failure")
+		t.setCondition(v1.CycleNodeRequestConditionDegraded, metav1.ConditionTrue, reason, t.cycleNodeRequest.Status.Message)
+	case v1.CycleNodeRequestCancelling:
+		t.setCondition(v1.CycleNodeRequestConditionProgressing, metav1.ConditionTrue, reason, "Rolling back nodes after being cancelled")
+	case v1.CycleNodeRequestCancelled:
+		t.setCondition(v1.CycleNodeRequestConditionProgressing, metav1.ConditionFalse, reason, "Stopped cycling nodes after being cancelled")
+	default:
+		t.setCondition(v1.CycleNodeRequestConditionProgressing, metav1.ConditionTrue, reason, "Cycling nodes")
+		t.setCondition(v1.CycleNodeRequestConditionDegraded, metav1.ConditionFalse, reason, "")
+	}
+}
+
+// updatePausedCondition sets the Paused condition to match the spec of the CycleNodeRequest, saving the
+// CycleNodeRequest if it changed. The condition is only added once the CycleNodeRequest has been paused.
+func (t *CycleNodeRequestTransitioner) updatePausedCondition() error {
+	switch t.cycleNodeRequest.Status.Phase {
+	case v1.CycleNodeRequestSuccessful, v1.CycleNodeRequestFailed, v1.CycleNodeRequestCancelling, v1.CycleNodeRequestCancelled:
+		return nil
+	}
+
+	condition := metav1.Condition{
+		Type:               v1.CycleNodeRequestConditionPaused,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: t.cycleNodeRequest.Generation,
+		Reason:             "Resumed",
+		Message:            "Nodes are being selected for cycling",
+	}
+	if t.cycleNodeRequest.Spec.Paused {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = "Paused"
+		condition.Message = "No new nodes are selected for cycling, nodes already being cycled will finish"
+	}
+
+	existing := meta.FindStatusCondition(t.cycleNodeRequest.Status.Conditions, v1.CycleNodeRequestConditionPaused)
+	if existing == nil && !t.cycleNodeRequest.Spec.Paused {
+		return nil
+	}
+	if existing != nil && existing.Status == condition.Status {
+		return nil
+	}
+
+	t.rm.LogEvent(t.cycleNodeRequest, condition.Reason, condition.Message)
+	meta.SetStatusCondition(&t.cycleNodeRequest.Status.Conditions, condition)
+	return t.rm.UpdateObject(t.cycleNodeRequest)
+}
+
+// cancellable returns true if the CycleNodeRequest is in a phase that can be cancelled. Healing is left to finish
+// since it is already rolling back.
+func (t *CycleNodeRequestTransitioner) cancellable() bool {
+	switch t.cycleNodeRequest.Status.Phase {
+	case v1.CycleNodeRequestHealing, v1.CycleNodeRequestFailed, v1.CycleNodeRequestSuccessful,
+		v1.CycleNodeRequestCancelling, v1.CycleNodeRequestCancelled:
+		return false
+	}
+	return true
+}
+
+// retry handles a request to retry a failed CycleNodeRequest. The per-attempt state is reset and the
+// CycleNodeRequest goes back to Initialised, keeping track of the nodes that have already been cycled. The nodes
+// that were being worked on when it failed become available to select again, except for the ones still draining.
+func (t *CycleNodeRequestTransitioner) retry() (reconcile.Result, error) {
+	delete(t.cycleNodeRequest.Annotations, v1.CycleNodeRequestRetryAnnotation)
+
+	if t.cycleNodeRequest.Status.Retries >= t.options.MaxRetries {
+		t.rm.LogWarningEvent(t.cycleNodeRequest, "RetryLimitReached", "Not retrying, already retried %d times", t.cycleNodeRequest.Status.Retries)
+		return reconcile.Result{}, t.rm.UpdateObject(t.cycleNodeRequest)
+	}
+
+	if err := t.releaseFailedNodes(); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	t.cycleNodeRequest.Status.Retries++
+	t.cycleNodeRequest.Status.Message = ""
+	t.cycleNodeRequest.Status.CurrentNodes = []v1.CycleNodeRequestNode{}
+	t.cycleNodeRequest.Status.HealthChecks = nil
+	t.cycleNodeRequest.Status.PreTerminationChecks = nil
+	t.cycleNodeRequest.Status.ScaleUpStarted = nil
+	t.cycleNodeRequest.Status.EquilibriumWaitStarted = nil
+	t.cycleNodeRequest.Status.ZoneReplacements = nil
+	t.cycleNodeRequest.Status.BatchStarted = nil
+	t.cycleNodeRequest.Status.RolloutBatch = 0
+	t.cycleNodeRequest.Status.BakeStarted = nil
+	t.cycleNodeRequest.Status.PrometheusChecksErroringSince = nil
+
+	// If it failed before the nodes to terminate were stored then start over from Pending
+	desiredPhase := v1.CycleNodeRequestInitialised
+	if len(t.cycleNodeRequest.Status.NodesToTerminate) == 0 {
+		desiredPhase = v1.CycleNodeRequestPending
+	}
+
+	t.rm.LogEvent(t.cycleNodeRequest, "Retrying", "Retrying from %s, attempt %d of %d", desiredPhase, t.cycleNodeRequest.Status.Retries, t.options.MaxRetries)
+	return t.transitionObject(desiredPhase)
+}
+
+// releaseFailedNodes rolls back the nodes that no longer have a CycleNodeStatus working on them, so they can be
+// selected for cycling again. A CycleNodeStatus that failed part way through leaves its node detached from its node
+// group and cordoned, so the node is re-attached and uncordoned like in the Healing phase before the label marking
+// it as in progress is removed. Nodes whose instance can't be re-attached are drained and terminated instead.
+func (t *CycleNodeRequestTransitioner) releaseFailedNodes() error {
+	cycleNodeStatusList, err := t.listChildren()
+	if err != nil {
+		return err
+	}
+
+	draining := make(map[string]bool, len(cycleNodeStatusList.Items))
+	for _, cycleNodeStatus := range cycleNodeStatusList.Items {
+		draining[cycleNodeStatus.Spec.NodeName] = true
+	}
+
+	selector, err := t.cycleNodeRequest.NodeLabelSelector()
+	if err != nil {
+		return err
+	}
+	nodes, err := t.rm.ListNodes(selector)
+	if err != nil {
+		return err
+	}
+
+	nodesToTerminate := make(map[string]v1.CycleNodeRequestNode, len(t.cycleNodeRequest.Status.NodesToTerminate))
+	for _, node := range t.cycleNodeRequest.Status.NodesToTerminate {
+		nodesToTerminate[node.Name] = node
+	}
+
+	var nodeGroups cloudprovider.NodeGroups
+	for _, node := range nodes {
+		if value, ok := node.Labels[cycleNodeLabel]; !ok || value != t.cycleNodeRequest.Name || draining[node.Name] {
+			continue
+		}
+		t.rm.Logger.Info("Releasing node for cycling again", "nodeName", node.Name)
+
+		if nodeToTerminate, ok := nodesToTerminate[node.Name]; ok {
+			if nodeGroups == nil {
+				if nodeGroups, err = t.rm.CloudProvider.GetNodeGroups(t.cycleNodeRequest.GetNodeGroupNames()); err != nil {
+					return err
+				}
+			}
+
+			attached, err := t.attachOrTerminateNode(nodeGroups, nodeToTerminate)
+			if err != nil {
+				return err
+			}
+			if !attached {
+				continue
+			}
+		}
+
+		t.rm.LogEvent(t.cycleNodeRequest, "UncordoningNodes", "Uncordoning nodes in node group: %v", node.Name)
+		if err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			return k8s.UncordonNode(node.Name, t.rm.RawClient)
+		}); err != nil {
+			return err
+		}
+
+		if err := k8s.RemoveLabelFromNode(node.Name, cycleNodeLabel, t.rm.RawClient); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// attachOrTerminateNode attaches the instance of the node back to its node group, so that the node can be uncordoned
+// and put back into service. Instances that the cloud provider can't attach again are handed off to a
+// CycleNodeStatus to be drained and terminated instead, so they aren't left running outside of any node group. It
+// returns whether the instance is attached.
+func (t *CycleNodeRequestTransitioner) attachOrTerminateNode(nodeGroups cloudprovider.NodeGroups, node v1.CycleNodeRequestNode) (attached bool, err error) {
+	t.rm.LogEvent(t.cycleNodeRequest, "AttachingNodes", "Attaching instances to nodes group: %v", node.Name)
+	alreadyAttached, err := nodeGroups.AttachInstance(node.ProviderID, node.NodeGroupName)
+	switch {
+	case alreadyAttached:
+		return true, nil
+	case errors.Is(err, cloudprovider.ErrCannotReattach):
+		t.rm.LogWarningEvent(t.cycleNodeRequest, "TerminatingDetachedNode",
+			"Draining and terminating node %v because its instance can't be attached again: %v", node.Name, err)
+		return false, t.terminateDetachedNode(node.Name)
+	case err != nil:
+		return false, err
+	}
+	return true, nil
+}
+
+// terminateDetachedNode cordons the node and creates a CycleNodeStatus to drain and terminate it, whatever the
+// method of the CycleNodeRequest. The node keeps the label marking it as in progress so that no other
+// CycleNodeRequest selects it. A node that already has a CycleNodeStatus is left to it.
+func (t *CycleNodeRequestTransitioner) terminateDetachedNode(nodeName string) error {
+	if err := k8s.CordonNode(nodeName, t.rm.RawClient); err != nil {
+		return err
+	}
+
+	cycleNodeStatus := t.makeCycleNodeStatusForNode(nodeName)
+	cycleNodeStatus.Spec.CycleSettings.Method = v1.CycleNodeRequestMethodDrain
+	if err := t.rm.Client.Create(context.TODO(), cycleNodeStatus); err != nil && !apierrors.IsAlreadyExists(err) {
+		return err
+	}
+
+	return k8s.AddLabelToNode(nodeName, cycleNodeLabel, t.cycleNodeRequest.Name, t.rm.RawClient)
+}
+
+// removeCycleNodeLabel removes the label marking the node as in progress, if the node has it
+func (t *CycleNodeRequestTransitioner) removeCycleNodeLabel(nodeName string) error {
+	node, err := t.rm.RawClient.CoreV1().Nodes().Get(context.TODO(), nodeName, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if _, ok := node.Labels[cycleNodeLabel]; !ok {
+		return nil
+	}
+	return k8s.RemoveLabelFromNode(nodeName, cycleNodeLabel, t.rm.RawClient)
+}
+
 // finalReapChildren handles reaping of children where instead of going back to Initialised,
 // we need to end the cycle for this CycleNodeRequest.
 func (t *CycleNodeRequestTransitioner) finalReapChildren() (shouldRequeue bool, err error) {
@@ -175,14 +433,13 @@ func (t *CycleNodeRequestTransitioner) finalReapChildren() (shouldRequeue bool,
 	}
 }
 
-// removeOldChildrenFromCluster removes any leftover children from a previous CycleNodeRequest with the same
-// name.
-func (t *CycleNodeRequestTransitioner) removeOldChildrenFromCluster() error {
+// listChildren lists the CycleNodeStatus children of the CycleNodeRequest
+func (t *CycleNodeRequestTransitioner) listChildren() (*v1.CycleNodeStatusList, error) {
 	cycleNodeStatusList := &v1.CycleNodeStatusList{}
 
 	labelSelector, err := labels.Parse("name=" + t.cycleNodeRequest.Name)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	listOptions := client.ListOptions{
@@ -190,7 +447,38 @@ func (t *CycleNodeRequestTransitioner) removeOldChildrenFromCluster() error {
 		LabelSelector: labelSelector,
 	}
 
-	err = t.rm.Client.List(context.TODO(), cycleNodeStatusList, &listOptions)
+	if err := t.rm.Client.List(context.TODO(), cycleNodeStatusList, &listOptions); err != nil {
+		return nil, err
+	}
+	return cycleNodeStatusList, nil
+}
+
+// currentNodesNotHandedOff returns the current nodes that don't have a CycleNodeStatus yet, so haven't been handed
+// off to be drained and terminated
+func (t *CycleNodeRequestTransitioner) currentNodesNotHandedOff() ([]v1.CycleNodeRequestNode, error) {
+	cycleNodeStatusList, err := t.listChildren()
+	if err != nil {
+		return nil, err
+	}
+
+	draining := make(map[string]bool, len(cycleNodeStatusList.Items))
+	for _, cycleNodeStatus := range cycleNodeStatusList.Items {
+		draining[cycleNodeStatus.Spec.NodeName] = true
+	}
+
+	var nodes []v1.CycleNodeRequestNode
+	for _, node := range t.cycleNodeRequest.Status.CurrentNodes {
+		if !draining[node.Name] {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes, nil
+}
+
+// removeOldChildrenFromCluster removes any leftover children from a previous CycleNodeRequest with the same
+// name.
+func (t *CycleNodeRequestTransitioner) removeOldChildrenFromCluster() error {
+	cycleNodeStatusList, err := t.listChildren()
 	if err != nil {
 		return err
 	}
@@ -285,6 +573,7 @@ func (t *CycleNodeRequestTransitioner) transitionToUnsuccessful(phase v1.CycleNo
 
 		t.cycleNodeRequest.Status.Message += err.Error()
 	}
+	t.setPhaseConditions()
 
 	// handle conflicts before complaining
 	if err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
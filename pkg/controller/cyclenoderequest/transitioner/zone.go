@@ -0,0 +1,160 @@
+package transitioner
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "github.com/atlassian-labs/cyclops/pkg/apis/atlassian/v1"
+)
+
+// nodeZone returns the availability zone of the node from the well known topology label. Nodes without the label
+// are all treated as being in the same zone.
+func nodeZone(node *corev1.Node) string {
+	return node.Labels[corev1.LabelTopologyZone]
+}
+
+// selectNodesByZone selects up to numNodes of the candidate nodes following the zone strategy. inProgressZones counts
+// the nodes already being cycled in each zone. The candidates keep their order within each zone.
+func selectNodesByZone(strategy v1.CycleNodeRequestZoneStrategy, candidates []*corev1.Node, inProgressZones map[string]int, numNodes int64) []*corev1.Node {
+	candidatesByZone := make(map[string][]*corev1.Node)
+	for _, node := range candidates {
+		zone := nodeZone(node)
+		candidatesByZone[zone] = append(candidatesByZone[zone], node)
+	}
+
+	zones := make([]string, 0, len(candidatesByZone))
+	for zone := range candidatesByZone {
+		zones = append(zones, zone)
+	}
+	sort.Strings(zones)
+
+	var selected []*corev1.Node
+
+	switch strategy {
+	case v1.CycleNodeRequestZoneStrategyOneZoneAtATime:
+		if len(zones) == 0 {
+			return nil
+		}
+
+		// Finish the zone that is being cycled before moving on to the next one
+		zone := zones[0]
+		for _, inProgressZone := range sortedKeys(inProgressZones) {
+			if inProgressZones[inProgressZone] > 0 {
+				zone = inProgressZone
+				break
+			}
+		}
+
+		for _, node := range candidatesByZone[zone] {
+			if int64(len(selected)) >= numNodes {
+				break
+			}
+			selected = append(selected, node)
+		}
+
+	case v1.CycleNodeRequestZoneStrategySpreadAcrossZones:
+		counts := make(map[string]int, len(inProgressZones))
+		for zone, count := range inProgressZones {
+			counts[zone] = count
+		}
+
+		// Repeatedly take a node from the zone with the fewest nodes being cycled
+		for int64(len(selected)) < numNodes {
+			var next string
+			found := false
+			for _, zone := range zones {
+				if len(candidatesByZone[zone]) == 0 {
+					continue
+				}
+				if !found || counts[zone] < counts[next] {
+					next = zone
+					found = true
+				}
+			}
+			if !found {
+				break
+			}
+
+			selected = append(selected, candidatesByZone[next][0])
+			candidatesByZone[next] = candidatesByZone[next][1:]
+			counts[next]++
+		}
+
+	default:
+		for _, node := range candidates {
+			if int64(len(selected)) >= numNodes {
+				break
+			}
+			selected = append(selected, node)
+		}
+	}
+
+	return selected
+}
+
+// sortedKeys returns the keys of the map in alphabetical order
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// setZoneReplacements records the zones of the current nodes so the replacements for them can be confirmed to come
+// up in the same zones. It doesn't save the CycleNodeRequest.
+func (t *CycleNodeRequestTransitioner) setZoneReplacements(batchStarted time.Time) {
+	t.cycleNodeRequest.Status.ZoneReplacements = nil
+	t.cycleNodeRequest.Status.BatchStarted = nil
+
+	if t.cycleNodeRequest.Spec.CycleSettings.ZoneStrategy == "" {
+		return
+	}
+
+	zoneReplacements := make(map[string]int)
+	for _, node := range t.cycleNodeRequest.Status.CurrentNodes {
+		zoneReplacements[node.Zone]++
+	}
+
+	started := metav1.NewTime(batchStarted)
+	t.cycleNodeRequest.Status.ZoneReplacements = zoneReplacements
+	t.cycleNodeRequest.Status.BatchStarted = &started
+}
+
+// zoneReplacementsReady checks that the replacements for the current batch of nodes have come up in the same zones as
+// the nodes they replace. Nodes that were created after the batch started, and are not being cycled, are counted as
+// replacements. It returns a message describing the zones still waiting for replacements.
+func (t *CycleNodeRequestTransitioner) zoneReplacementsReady(kubeNodes []corev1.Node) (bool, string) {
+	if len(t.cycleNodeRequest.Status.ZoneReplacements) == 0 || t.cycleNodeRequest.Status.BatchStarted == nil {
+		return true, ""
+	}
+
+	toTerminate := make(map[string]bool, len(t.cycleNodeRequest.Status.NodesToTerminate))
+	for _, node := range t.cycleNodeRequest.Status.NodesToTerminate {
+		toTerminate[node.Name] = true
+	}
+
+	replacements := make(map[string]int)
+	for i, kubeNode := range kubeNodes {
+		if toTerminate[kubeNode.Name] || kubeNode.CreationTimestamp.Before(t.cycleNodeRequest.Status.BatchStarted) {
+			continue
+		}
+		replacements[nodeZone(&kubeNodes[i])]++
+	}
+
+	var waiting []string
+	for _, zone := range sortedKeys(t.cycleNodeRequest.Status.ZoneReplacements) {
+		if expected := t.cycleNodeRequest.Status.ZoneReplacements[zone]; replacements[zone] < expected {
+			waiting = append(waiting, fmt.Sprintf("%q (%d/%d)", zone, replacements[zone], expected))
+		}
+	}
+	if len(waiting) > 0 {
+		return false, fmt.Sprintf("Waiting for replacement nodes in zones: %v", waiting)
+	}
+	return true, ""
+}
@@ -0,0 +1,152 @@
+package transitioner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	v1 "github.com/atlassian-labs/cyclops/pkg/apis/atlassian/v1"
+)
+
+func newTestZoneNode(name, zone string, labels map[string]string) *corev1.Node {
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: map[string]string{"role": "test", corev1.LabelTopologyZone: zone},
+		},
+		Spec: corev1.NodeSpec{ProviderID: "aws:///" + name},
+		Status: corev1.NodeStatus{
+			Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}},
+		},
+	}
+	for k, v := range labels {
+		node.Labels[k] = v
+	}
+	return node
+}
+
+func nodeNames(nodes []*corev1.Node) []string {
+	var names []string
+	for _, node := range nodes {
+		names = append(names, node.Name)
+	}
+	return names
+}
+
+func TestSelectNodesByZone(t *testing.T) {
+	candidates := []*corev1.Node{
+		newTestZoneNode("b-1", "zone-b", nil),
+		newTestZoneNode("a-1", "zone-a", nil),
+		newTestZoneNode("b-2", "zone-b", nil),
+		newTestZoneNode("a-2", "zone-a", nil),
+		newTestZoneNode("b-3", "zone-b", nil),
+		newTestZoneNode("c-1", "zone-c", nil),
+	}
+
+	tests := []struct {
+		name            string
+		strategy        v1.CycleNodeRequestZoneStrategy
+		inProgressZones map[string]int
+		numNodes        int64
+		expect          []string
+	}{
+		{"no strategy keeps the order", "", nil, 3, []string{"b-1", "a-1", "b-2"}},
+		{"one zone at a time starts with the first zone", v1.CycleNodeRequestZoneStrategyOneZoneAtATime, nil, 3, []string{"a-1", "a-2"}},
+		{"one zone at a time finishes the zone in progress", v1.CycleNodeRequestZoneStrategyOneZoneAtATime, map[string]int{"zone-b": 1}, 2, []string{"b-1", "b-2"}},
+		{"one zone at a time waits for the zone in progress", v1.CycleNodeRequestZoneStrategyOneZoneAtATime, map[string]int{"zone-d": 1}, 2, nil},
+		{"spread across zones", v1.CycleNodeRequestZoneStrategySpreadAcrossZones, nil, 4, []string{"a-1", "b-1", "c-1", "a-2"}},
+		{"spread across zones counts the nodes in progress", v1.CycleNodeRequestZoneStrategySpreadAcrossZones, map[string]int{"zone-a": 2, "zone-c": 1}, 3, []string{"b-1", "b-2", "c-1"}},
+		{"spread across zones runs out of nodes", v1.CycleNodeRequestZoneStrategySpreadAcrossZones, nil, 10, []string{"a-1", "b-1", "c-1", "a-2", "b-2", "b-3"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := selectNodesByZone(tt.strategy, candidates, tt.inProgressZones, tt.numNodes)
+			assert.Equal(t, tt.expect, nodeNames(got))
+		})
+	}
+}
+
+func TestGetNodesToTerminate_ZoneStrategy(t *testing.T) {
+	nodes := []*corev1.Node{
+		newTestZoneNode("a-1", "zone-a", map[string]string{cycleNodeLabel: "test"}),
+		newTestZoneNode("a-2", "zone-a", nil),
+		newTestZoneNode("b-1", "zone-b", nil),
+		newTestZoneNode("b-2", "zone-b", nil),
+	}
+
+	cnr := newTestCycleNodeRequest(v1.CycleNodeRequestInitialised)
+	cnr.Spec.Selector = metav1.LabelSelector{MatchLabels: map[string]string{"role": "test"}}
+	cnr.Spec.CycleSettings.ZoneStrategy = v1.CycleNodeRequestZoneStrategySpreadAcrossZones
+	var objects []runtime.Object
+	for _, node := range nodes {
+		cnr.Status.NodesToTerminate = append(cnr.Status.NodesToTerminate, newCycleNodeRequestNode(node, "nodegroup"))
+		objects = append(objects, node)
+	}
+	cnr.Status.NodesAvailable = cnr.Status.NodesToTerminate[1:]
+
+	transitioner := newTestTransitioner(t, cnr, objects...)
+	selected, numNodesInProgress, err := transitioner.getNodesToTerminate(2)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, numNodesInProgress)
+
+	// Zone b has no nodes in progress so it gets a node first
+	assert.Equal(t, []string{"b-1", "a-2"}, nodeNames(selected))
+	assert.Len(t, transitioner.cycleNodeRequest.Status.NodesAvailable, 1)
+	assert.Equal(t, "b-2", transitioner.cycleNodeRequest.Status.NodesAvailable[0].Name)
+	assert.Equal(t, "zone-b", transitioner.cycleNodeRequest.Status.NodesAvailable[0].Zone)
+}
+
+func TestZoneReplacementsReady(t *testing.T) {
+	batchStarted := metav1.NewTime(time.Now().Add(-time.Minute))
+	newNode := func(name, zone string, created time.Time) corev1.Node {
+		node := newTestZoneNode(name, zone, nil)
+		node.CreationTimestamp = metav1.NewTime(created)
+		return *node
+	}
+	oldNodes := []corev1.Node{
+		newNode("old-a", "zone-a", time.Now().Add(-time.Hour)),
+		newNode("old-b", "zone-b", time.Now().Add(-time.Hour)),
+		// Not one of the nodes to terminate, but existed before the batch started
+		newNode("other-b", "zone-b", time.Now().Add(-time.Hour)),
+	}
+
+	tests := []struct {
+		name             string
+		zoneReplacements map[string]int
+		nodes            []corev1.Node
+		expect           bool
+	}{
+		{"no zone strategy", nil, oldNodes, true},
+		{"replacements not up yet", map[string]int{"zone-a": 1, "zone-b": 1}, oldNodes, false},
+		{
+			"replacement in the wrong zone",
+			map[string]int{"zone-a": 1, "zone-b": 1},
+			append([]corev1.Node{newNode("new-1", "zone-a", time.Now()), newNode("new-2", "zone-a", time.Now())}, oldNodes...),
+			false,
+		},
+		{
+			"replacements in the same zones",
+			map[string]int{"zone-a": 1, "zone-b": 1},
+			append([]corev1.Node{newNode("new-1", "zone-a", time.Now()), newNode("new-2", "zone-b", time.Now())}, oldNodes...),
+			true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cnr := newTestCycleNodeRequest(v1.CycleNodeRequestScalingUp)
+			cnr.Status.NodesToTerminate = []v1.CycleNodeRequestNode{{Name: "old-a"}, {Name: "old-b"}}
+			cnr.Status.ZoneReplacements = tt.zoneReplacements
+			cnr.Status.BatchStarted = &batchStarted
+
+			ready, message := newTestTransitioner(t, cnr).zoneReplacementsReady(tt.nodes)
+			assert.Equal(t, tt.expect, ready)
+			assert.Equal(t, tt.expect, message == "")
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package transitioner
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	v1 "github.com/atlassian-labs/cyclops/pkg/apis/atlassian/v1"
+	"github.com/atlassian-labs/cyclops/pkg/k8s"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// cnrNameLabel is the label on a CycleNodeStatus with the name of the CycleNodeRequest that created it
+const cnrNameLabel = "name"
+
+// updateBlockedPods records the pods whose eviction is blocked in the status of the CycleNodeStatus. Pods that were
+// blocked before keep the time they were first blocked at. Pods that weren't attempted by this drain are kept while
+// they are still on the node and not terminating. Failing to look up the PodDisruptionBudgets or pods only leaves
+// the status less complete, so that the CycleNodeStatus can still time out. It doesn't save the CycleNodeStatus.
+func (t *CycleNodeStatusTransitioner) updateBlockedPods(blocked []*corev1.Pod, now time.Time) {
+	previous := make(map[types.NamespacedName]v1.BlockedPod)
+	for _, blockedPod := range t.cycleNodeStatus.Status.BlockedPods {
+		previous[types.NamespacedName{Namespace: blockedPod.Namespace, Name: blockedPod.Name}] = blockedPod
+	}
+
+	var blockedPods []v1.BlockedPod
+	seen := make(map[types.NamespacedName]bool)
+	for _, pod := range blocked {
+		key := types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name}
+		seen[key] = true
+
+		pdbs, err := t.rm.GetPodDisruptionBudgetsForPod(pod)
+		if err != nil {
+			t.rm.Logger.Error(err, "Unable to get PodDisruptionBudgets for pod with blocked eviction",
+				"podName", pod.Name, "podNamespace", pod.Namespace)
+		}
+
+		blockedPod, ok := previous[key]
+		if !ok {
+			blockedPod = v1.BlockedPod{
+				Name:         pod.Name,
+				Namespace:    pod.Namespace,
+				BlockedSince: metav1.NewTime(now),
+			}
+		}
+		blockedPod.PodDisruptionBudgets = pdbs
+		blockedPods = append(blockedPods, blockedPod)
+	}
+
+	for _, blockedPod := range t.cycleNodeStatus.Status.BlockedPods {
+		if seen[types.NamespacedName{Namespace: blockedPod.Namespace, Name: blockedPod.Name}] {
+			continue
+		}
+
+		pod, err := t.rm.RawClient.CoreV1().Pods(blockedPod.Namespace).Get(context.TODO(), blockedPod.Name, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			t.rm.Logger.Error(err, "Unable to get pod with blocked eviction",
+				"podName", blockedPod.Name, "podNamespace", blockedPod.Namespace)
+			blockedPods = append(blockedPods, blockedPod)
+			continue
+		}
+		if pod.DeletionTimestamp == nil && pod.Spec.NodeName == t.cycleNodeStatus.Status.CurrentNode.Name {
+			blockedPods = append(blockedPods, blockedPod)
+		}
+	}
+
+	t.cycleNodeStatus.Status.BlockedPods = blockedPods
+}
+
+// warnBlockedPods raises a warning event and notification for the pods that have been blocked for longer than the
+// warning threshold and haven't been warned about yet. It doesn't save the CycleNodeStatus.
+func (t *CycleNodeStatusTransitioner) warnBlockedPods(now time.Time) {
+	threshold := t.options.PDBBlockedWarningThreshold
+	if drain := t.cycleNodeStatus.Spec.CycleSettings.Drain; drain != nil && drain.BlockedWarningAfter != nil {
+		threshold = drain.BlockedWarningAfter.Duration
+	}
+
+	var warned []string
+	for i, blockedPod := range t.cycleNodeStatus.Status.BlockedPods {
+		if blockedPod.Warned || now.Sub(blockedPod.BlockedSince.Time) < threshold {
+			continue
+		}
+		t.cycleNodeStatus.Status.BlockedPods[i].Warned = true
+		warned = append(warned, blockedPodString(blockedPod))
+	}
+	if len(warned) == 0 {
+		return
+	}
+
+	t.rm.LogWarningEvent(t.cycleNodeStatus, "EvictionBlocked",
+		"Eviction blocked for longer than %v for pods: %s", threshold, strings.Join(warned, ", "))
+
+	if t.rm.Notifier != nil {
+		cnr := &v1.CycleNodeRequest{}
+		key := types.NamespacedName{Namespace: t.cycleNodeStatus.Namespace, Name: t.cycleNodeStatus.Labels[cnrNameLabel]}
+		if err := t.rm.Client.Get(context.TODO(), key, cnr); err != nil {
+			t.rm.Logger.Error(err, "Unable to get cycleNodeRequest to notify of blocked eviction")
+			return
+		}
+		if err := t.rm.Notifier.EvictionBlocked(cnr, t.cycleNodeStatus); err != nil {
+			t.rm.Logger.Error(err, "Unable to post message to messaging provider", "phase", t.cycleNodeStatus.Status.Phase)
+		}
+	}
+}
+
+// escalateBlockedPods removes the pods whose eviction has been blocked for longer than allowed by the blocked
+// escalation of the drain settings, if there is one. Pods that haven't been warned about by warnBlockedPods yet are
+// left, so that the warning always comes first even when the warning threshold is longer than the escalation.
+func (t *CycleNodeStatusTransitioner) escalateBlockedPods(blocked []*corev1.Pod, now time.Time) error {
+	drain := t.cycleNodeStatus.Spec.CycleSettings.Drain
+	if drain == nil || drain.BlockedEscalation == nil {
+		return nil
+	}
+	escalation := drain.BlockedEscalation
+
+	pods := make(map[types.NamespacedName]*corev1.Pod)
+	for _, pod := range blocked {
+		pods[types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name}] = pod
+	}
+
+	for _, blockedPod := range t.cycleNodeStatus.Status.BlockedPods {
+		pod, ok := pods[types.NamespacedName{Namespace: blockedPod.Namespace, Name: blockedPod.Name}]
+		if !ok || !blockedPod.Warned || now.Sub(blockedPod.BlockedSince.Time) < escalation.After.Duration {
+			continue
+		}
+
+		t.rm.LogWarningEvent(t.cycleNodeStatus, "EvictionEscalated",
+			"Eviction blocked for longer than %v, removing pod with %s: %s", escalation.After.Duration, escalation.Action,
+			blockedPodString(blockedPod))
+
+		var err error
+		switch escalation.Action {
+		case v1.DrainEscalationActionForceDelete:
+			err = k8s.ForciblyDeletePod(pod.Name, pod.Namespace, pod.Spec.NodeName, t.rm.RawClient)
+		default:
+			err = k8s.DeletePod(pod, nil, t.rm.RawClient)
+		}
+		if err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to remove pod %s/%s with blocked eviction: %v", pod.Namespace, pod.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// blockedPodsString returns a description of the pods with blocked evictions in the status of the CycleNodeStatus
+func (t *CycleNodeStatusTransitioner) blockedPodsString() string {
+	var blockedPods []string
+	for _, blockedPod := range t.cycleNodeStatus.Status.BlockedPods {
+		blockedPods = append(blockedPods, blockedPodString(blockedPod))
+	}
+	return strings.Join(blockedPods, ", ")
+}
+
+// blockedPodString returns a description of a pod with a blocked eviction and the PodDisruptionBudgets blocking it
+func blockedPodString(blockedPod v1.BlockedPod) string {
+	if len(blockedPod.PodDisruptionBudgets) == 0 {
+		return fmt.Sprintf("%s/%s", blockedPod.Namespace, blockedPod.Name)
+	}
+	return fmt.Sprintf("%s/%s (%s)", blockedPod.Namespace, blockedPod.Name, strings.Join(blockedPod.PodDisruptionBudgets, ", "))
+}
@@ -0,0 +1,205 @@
+package transitioner
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/kubernetes/scheme"
+	testingCore "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/atlassian-labs/cyclops/pkg/apis"
+	v1 "github.com/atlassian-labs/cyclops/pkg/apis/atlassian/v1"
+	"github.com/atlassian-labs/cyclops/pkg/controller"
+	"github.com/atlassian-labs/cyclops/pkg/notifications"
+)
+
+// testNotifier records the CycleNodeRequests it is notified of blocked evictions for. Other methods are not
+// implemented.
+type testNotifier struct {
+	notifications.Notifier
+	evictionBlocked []string
+}
+
+func (n *testNotifier) EvictionBlocked(cnr *v1.CycleNodeRequest, cns *v1.CycleNodeStatus) error {
+	n.evictionBlocked = append(n.evictionBlocked, cnr.Name)
+	return nil
+}
+
+// newTestBlockedTransitioner creates a transitioner for a CycleNodeStatus draining test-node, backed by fake clients
+// containing the pods and PodDisruptionBudgets
+func newTestBlockedTransitioner(t *testing.T, drain *v1.DrainSettings, objects ...runtime.Object) (*CycleNodeStatusTransitioner, *testNotifier) {
+	testScheme := runtime.NewScheme()
+	assert.NoError(t, scheme.AddToScheme(testScheme))
+	assert.NoError(t, apis.AddToScheme(testScheme))
+
+	cnr := &v1.CycleNodeRequest{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "kube-system"}}
+	cns := &v1.CycleNodeStatus{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-test-node", Namespace: "kube-system", Labels: map[string]string{"name": "test"}},
+		Spec: v1.CycleNodeStatusSpec{
+			NodeName:      "test-node",
+			CycleSettings: v1.CycleSettings{Method: v1.CycleNodeRequestMethodDrain, Drain: drain},
+		},
+		Status: v1.CycleNodeStatusStatus{
+			Phase:       v1.CycleNodeStatusDrainingPods,
+			CurrentNode: v1.CycleNodeRequestNode{Name: "test-node"},
+		},
+	}
+
+	notifier := &testNotifier{}
+	rm := &controller.ResourceManager{
+		Client:    fake.NewClientBuilder().WithScheme(testScheme).WithRuntimeObjects(cnr, cns).Build(),
+		RawClient: fakeclientset.NewSimpleClientset(objects...),
+		Recorder:  record.NewFakeRecorder(10),
+		Logger:    logf.Log.WithName("transitioner-test"),
+		Notifier:  notifier,
+	}
+	return NewCycleNodeStatusTransitioner(cns, rm, Options{PDBBlockedWarningThreshold: 10 * time.Minute}), notifier
+}
+
+func testBlockedPod(name string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: map[string]string{"app": name}},
+		Spec:       corev1.PodSpec{NodeName: "test-node"},
+	}
+}
+
+func testPodDisruptionBudget(name string, selector *metav1.LabelSelector) *policyv1.PodDisruptionBudget {
+	return &policyv1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec:       policyv1.PodDisruptionBudgetSpec{Selector: selector},
+	}
+}
+
+func TestUpdateBlockedPods(t *testing.T) {
+	now := time.Now()
+	earlier := metav1.NewTime(now.Add(-time.Hour))
+
+	blocked := testBlockedPod("blocked")
+	stillBlocked := testBlockedPod("still-blocked")
+	notAttempted := testBlockedPod("not-attempted")
+	evicting := testBlockedPod("evicting")
+	evicting.DeletionTimestamp = &earlier
+
+	transitioner, _ := newTestBlockedTransitioner(t, nil, blocked, stillBlocked, notAttempted, evicting,
+		testPodDisruptionBudget("blocked-pdb", &metav1.LabelSelector{MatchLabels: map[string]string{"app": "blocked"}}),
+		testPodDisruptionBudget("everything", &metav1.LabelSelector{}),
+		testPodDisruptionBudget("nothing", nil),
+		testPodDisruptionBudget("other", &metav1.LabelSelector{MatchLabels: map[string]string{"app": "other"}}))
+
+	transitioner.cycleNodeStatus.Status.BlockedPods = []v1.BlockedPod{
+		{Name: "still-blocked", Namespace: "default", BlockedSince: earlier, Warned: true},
+		{Name: "not-attempted", Namespace: "default", BlockedSince: earlier},
+		{Name: "evicting", Namespace: "default", BlockedSince: earlier},
+		{Name: "evicted", Namespace: "default", BlockedSince: earlier},
+	}
+
+	transitioner.updateBlockedPods([]*corev1.Pod{blocked, stillBlocked}, now)
+	assert.Equal(t, []v1.BlockedPod{
+		{Name: "blocked", Namespace: "default", PodDisruptionBudgets: []string{"blocked-pdb", "everything"}, BlockedSince: metav1.NewTime(now)},
+		{Name: "still-blocked", Namespace: "default", PodDisruptionBudgets: []string{"everything"}, BlockedSince: earlier, Warned: true},
+		{Name: "not-attempted", Namespace: "default", BlockedSince: earlier},
+	}, transitioner.cycleNodeStatus.Status.BlockedPods)
+}
+
+func TestUpdateBlockedPodsLookupFailure(t *testing.T) {
+	now := time.Now()
+	earlier := metav1.NewTime(now.Add(-time.Hour))
+	blocked := testBlockedPod("blocked")
+
+	transitioner, _ := newTestBlockedTransitioner(t, nil, blocked)
+	client := transitioner.rm.RawClient.(*fakeclientset.Clientset)
+	client.Fake.PrependReactor("*", "*", func(action testingCore.Action) (bool, runtime.Object, error) {
+		return true, nil, fmt.Errorf("the server could not find the requested resource")
+	})
+	transitioner.cycleNodeStatus.Status.BlockedPods = []v1.BlockedPod{
+		{Name: "not-attempted", Namespace: "default", BlockedSince: earlier},
+	}
+
+	// The pods are still recorded so that the CycleNodeStatus can warn, escalate and time out
+	transitioner.updateBlockedPods([]*corev1.Pod{blocked}, now)
+	assert.Equal(t, []v1.BlockedPod{
+		{Name: "blocked", Namespace: "default", BlockedSince: metav1.NewTime(now)},
+		{Name: "not-attempted", Namespace: "default", BlockedSince: earlier},
+	}, transitioner.cycleNodeStatus.Status.BlockedPods)
+}
+
+func TestWarnBlockedPods(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name         string
+		drain        *v1.DrainSettings
+		blockedFor   time.Duration
+		expectWarned bool
+	}{
+		{"not blocked for long", nil, 5 * time.Minute, false},
+		{"blocked for longer than the flag", nil, 15 * time.Minute, true},
+		{"blocked for longer than the settings", &v1.DrainSettings{BlockedWarningAfter: &metav1.Duration{Duration: time.Minute}}, 5 * time.Minute, true},
+		{"not blocked for longer than the settings", &v1.DrainSettings{BlockedWarningAfter: &metav1.Duration{Duration: time.Hour}}, 15 * time.Minute, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transitioner, notifier := newTestBlockedTransitioner(t, tt.drain)
+			transitioner.cycleNodeStatus.Status.BlockedPods = []v1.BlockedPod{
+				{Name: "blocked", Namespace: "default", BlockedSince: metav1.NewTime(now.Add(-tt.blockedFor))},
+			}
+
+			transitioner.warnBlockedPods(now)
+			assert.Equal(t, tt.expectWarned, transitioner.cycleNodeStatus.Status.BlockedPods[0].Warned)
+
+			// Pods are only warned about once
+			transitioner.warnBlockedPods(now)
+			if tt.expectWarned {
+				assert.Equal(t, []string{"test"}, notifier.evictionBlocked)
+			} else {
+				assert.Empty(t, notifier.evictionBlocked)
+			}
+		})
+	}
+}
+
+func TestEscalateBlockedPods(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name          string
+		drain         *v1.DrainSettings
+		blockedFor    time.Duration
+		warned        bool
+		expectDeleted bool
+	}{
+		{"no escalation", nil, 24 * time.Hour, true, false},
+		{"not blocked for long", &v1.DrainSettings{BlockedEscalation: &v1.DrainEscalation{Action: v1.DrainEscalationActionDelete, After: metav1.Duration{Duration: time.Hour}}}, 15 * time.Minute, true, false},
+		{"not warned yet", &v1.DrainSettings{BlockedEscalation: &v1.DrainEscalation{Action: v1.DrainEscalationActionDelete, After: metav1.Duration{Duration: time.Minute}}}, 5 * time.Minute, false, false},
+		{"delete", &v1.DrainSettings{BlockedEscalation: &v1.DrainEscalation{Action: v1.DrainEscalationActionDelete, After: metav1.Duration{Duration: time.Hour}}}, 2 * time.Hour, true, true},
+		{"force delete", &v1.DrainSettings{BlockedEscalation: &v1.DrainEscalation{Action: v1.DrainEscalationActionForceDelete, After: metav1.Duration{Duration: time.Hour}}}, 2 * time.Hour, true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := testBlockedPod("blocked")
+			transitioner, _ := newTestBlockedTransitioner(t, tt.drain, pod)
+			transitioner.cycleNodeStatus.Status.BlockedPods = []v1.BlockedPod{
+				{Name: "blocked", Namespace: "default", BlockedSince: metav1.NewTime(now.Add(-tt.blockedFor)), Warned: tt.warned},
+			}
+
+			assert.NoError(t, transitioner.escalateBlockedPods([]*corev1.Pod{pod}, now))
+
+			_, err := transitioner.rm.RawClient.CoreV1().Pods("default").Get(context.TODO(), "blocked", metav1.GetOptions{})
+			assert.Equal(t, tt.expectDeleted, errors.IsNotFound(err))
+		})
+	}
+}
@@ -42,6 +42,9 @@ type Options struct {
 	// UnhealthyPodTerminationThreshold controls how long we tolerate a pod being unhealthy and holding up the
 	// CycleNodeStatus before forcibly removing it
 	UnhealthyPodTerminationThreshold time.Duration
+	// PDBBlockedWarningThreshold controls how long the eviction of a pod can be blocked by a PodDisruptionBudget
+	// before a warning is raised, unless overridden by the drain settings of the CycleNodeStatus
+	PDBBlockedWarningThreshold time.Duration
 }
 
 // Run runs the CycleNodeStatusTransitioner and returns a reconcile result and an error
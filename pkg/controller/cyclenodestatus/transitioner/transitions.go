@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	v1 "github.com/atlassian-labs/cyclops/pkg/apis/atlassian/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
@@ -127,26 +128,17 @@ func (t *CycleNodeStatusTransitioner) transitionRemovingLabelsFromPods() (reconc
 func (t *CycleNodeStatusTransitioner) transitionDraining() (reconcile.Result, error) {
 	// Drain pods off the node
 	t.rm.LogEvent(t.cycleNodeStatus, "DrainingPods", "Draining pods from node: %v", t.cycleNodeStatus.Status.CurrentNode.Name)
-	finished, errs := t.rm.DrainPods(t.cycleNodeStatus.Status.CurrentNode.Name, t.options.UnhealthyPodTerminationThreshold)
+	finished, blocked, errs := t.rm.DrainPods(t.cycleNodeStatus.Status.CurrentNode.Name, t.options.UnhealthyPodTerminationThreshold,
+		t.cycleNodeStatus.Spec.CycleSettings.Drain)
 
-	// We need to do some fairly complicated error handling here. It is most efficient to drain all pods at once, as
-	// this stops us being blocked behind one pod that takes a long time to get evicted. This means we need to handle
-	// all the errors at once. One class of error, StatusTooManyRequests, indicates that the evicted pod is
-	// "undisruptable" via a pod disruption budget. This error is fine. All others are not, and we have to combine
-	// them and fail this CycleNodeStatus if we encounter them.
+	// It is most efficient to drain all pods at once, as this stops us being blocked behind one pod that takes a long
+	// time to get evicted. This means we need to handle all the errors at once. Pods whose eviction is blocked by a
+	// PodDisruptionBudget aren't errors, they are returned separately to be retried. All errors are combined and
+	// fail this CycleNodeStatus.
 	var unexpectedErrors []string
-	tooManyRequests := false
 	for _, err := range errs {
 		if err != nil {
-			// Custom logic handling, mainly for handling pods that are undisruptable via a PodDisruptionBudget
-			if serr, ok := err.(*errors.StatusError); ok && errors.IsTooManyRequests(serr) {
-				// API says we should retry, we do the actual retry further down but log the pod here
-				t.rm.Logger.Info("waiting to retry after receiving StatusTooManyRequests error",
-					"podName", serr.ErrStatus.Details.Name)
-				tooManyRequests = true
-			} else {
-				unexpectedErrors = append(unexpectedErrors, err.Error())
-			}
+			unexpectedErrors = append(unexpectedErrors, err.Error())
 		}
 	}
 	// Fail with all of the combined encountered errors if we got any. If we failed inside the loop we would
@@ -156,15 +148,44 @@ func (t *CycleNodeStatusTransitioner) transitionDraining() (reconcile.Result, er
 	}
 	// No serious errors were encountered. If we're done, move on.
 	if finished {
+		t.cycleNodeStatus.Status.BlockedPods = nil
 		return t.transitionObject(v1.CycleNodeStatusDeletingNode)
 	}
 
+	// Keep track of the pods whose eviction is blocked, and for how long they have been blocked
+	previousStatus := t.cycleNodeStatus.Status.DeepCopy()
+	now := time.Now()
+	t.updateBlockedPods(blocked, now)
+
 	// Fail if we've taken too long in this phase.
 	if t.timedOut() {
+		if len(t.cycleNodeStatus.Status.BlockedPods) > 0 {
+			return t.transitionToFailed(fmt.Errorf("timed out while draining pods, eviction blocked for pods: %s",
+				t.blockedPodsString()))
+		}
 		return t.transitionToFailed(fmt.Errorf("timed out while draining pods"))
 	}
-	// The API says we should retry (likely due to currently undisruptable pods)
-	if tooManyRequests {
+
+	t.warnBlockedPods(now)
+	if err := t.escalateBlockedPods(blocked, now); err != nil {
+		return t.transitionToFailed(err)
+	}
+
+	// Keep track of whether evicting pods is blocked by a PodDisruptionBudget
+	if len(t.cycleNodeStatus.Status.BlockedPods) > 0 {
+		t.setCondition(v1.CycleNodeStatusConditionWaitingForPDB, metav1.ConditionTrue, "EvictionBlocked",
+			fmt.Sprintf("Eviction blocked by a PodDisruptionBudget for pods: %s", t.blockedPodsString()))
+	} else {
+		t.setCondition(v1.CycleNodeStatusConditionWaitingForPDB, metav1.ConditionFalse, "EvictionAllowed", "")
+	}
+	if !apiequality.Semantic.DeepEqual(previousStatus, &t.cycleNodeStatus.Status) {
+		if err := t.rm.UpdateObject(t.cycleNodeStatus); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+
+	// Retry sooner while evictions are blocked (likely due to currently undisruptable pods)
+	if len(blocked) > 0 {
 		return reconcile.Result{Requeue: true, RequeueAfter: 15 * time.Second}, nil
 	}
 	// If all the pods aren't finished draining, try again a while later to avoid spamming the API server.
@@ -4,6 +4,8 @@ import (
 	"time"
 
 	v1 "github.com/atlassian-labs/cyclops/pkg/apis/atlassian/v1"
+	"github.com/atlassian-labs/cyclops/pkg/k8s"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
@@ -11,6 +13,7 @@ import (
 func (t *CycleNodeStatusTransitioner) transitionToFailed(err error) (reconcile.Result, error) {
 	t.cycleNodeStatus.Status.Phase = v1.CycleNodeStatusFailed
 	t.cycleNodeStatus.Status.Message = err.Error()
+	t.setPhaseConditions()
 	if err := t.rm.UpdateObject(t.cycleNodeStatus); err != nil {
 		t.rm.Logger.Error(err, "unable to update cycleNodeStatus")
 	}
@@ -21,12 +24,14 @@ func (t *CycleNodeStatusTransitioner) transitionToFailed(err error) (reconcile.R
 func (t *CycleNodeStatusTransitioner) transitionToSuccessful() (reconcile.Result, error) {
 	t.rm.LogEvent(t.cycleNodeStatus, "Successful", "Successfully cycled node")
 	t.cycleNodeStatus.Status.Phase = v1.CycleNodeStatusSuccessful
+	t.setPhaseConditions()
 	return reconcile.Result{}, t.rm.UpdateObject(t.cycleNodeStatus)
 }
 
 // transitionObject transitions the current cycleNodeStatus to the specified phase
 func (t *CycleNodeStatusTransitioner) transitionObject(desiredPhase v1.CycleNodeStatusPhase) (reconcile.Result, error) {
 	t.cycleNodeStatus.Status.Phase = desiredPhase
+	t.setPhaseConditions()
 	if err := t.rm.UpdateObject(t.cycleNodeStatus); err != nil {
 		return reconcile.Result{}, err
 	}
@@ -41,3 +46,36 @@ func (t *CycleNodeStatusTransitioner) transitionObject(desiredPhase v1.CycleNode
 func (t *CycleNodeStatusTransitioner) timedOut() bool {
 	return time.Now().After(t.cycleNodeStatus.Status.TimeoutTimestamp.Time)
 }
+
+// setCondition sets a condition of the CycleNodeStatus for the current generation. It doesn't save the
+// CycleNodeStatus. It returns true if the condition changed.
+func (t *CycleNodeStatusTransitioner) setCondition(conditionType string, status metav1.ConditionStatus, reason, message string) bool {
+	return k8s.SetCondition(&t.cycleNodeStatus.Status.Conditions, t.cycleNodeStatus.Generation, conditionType, status, reason, message)
+}
+
+// setPhaseConditions sets the Progressing and Degraded conditions to match the phase of the CycleNodeStatus. It
+// doesn't save the CycleNodeStatus.
+func (t *CycleNodeStatusTransitioner) setPhaseConditions() {
+	phase := t.cycleNodeStatus.Status.Phase
+	if phase == v1.CycleNodeStatusUndefined {
+		return
+	}
+	reason := string(phase)
+
+	switch phase {
+	case v1.CycleNodeStatusSuccessful:
+		t.setCondition(v1.CycleNodeStatusConditionProgressing, metav1.ConditionFalse, reason, "Finished cycling the node")
+		t.setCondition(v1.CycleNodeStatusConditionDegraded, metav1.ConditionFalse, reason, "")
+	case v1.CycleNodeStatusFailed:
+		t.setCondition(v1.CycleNodeStatusConditionProgressing, metav1.ConditionFalse, reason, "Stopped cycling the node after a failure")
+		t.setCondition(v1.CycleNodeStatusConditionDegraded, metav1.ConditionTrue, reason, t.cycleNodeStatus.Status.Message)
+	default:
+		t.setCondition(v1.CycleNodeStatusConditionProgressing, metav1.ConditionTrue, reason, "Cycling the node")
+		t.setCondition(v1.CycleNodeStatusConditionDegraded, metav1.ConditionFalse, reason, "")
+	}
+
+	// The node is only waiting for a PodDisruptionBudget while draining
+	if phase != v1.CycleNodeStatusDrainingPods {
+		t.setCondition(v1.CycleNodeStatusConditionWaitingForPDB, metav1.ConditionFalse, reason, "")
+	}
+}
@@ -0,0 +1,42 @@
+package transitioner
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "github.com/atlassian-labs/cyclops/pkg/apis/atlassian/v1"
+)
+
+func TestSetPhaseConditions(t *testing.T) {
+	waitingForPDB := metav1.Condition{Type: v1.CycleNodeStatusConditionWaitingForPDB, Status: metav1.ConditionTrue, Reason: "EvictionBlocked"}
+
+	tests := []struct {
+		phase               v1.CycleNodeStatusPhase
+		expectProgressing   metav1.ConditionStatus
+		expectDegraded      metav1.ConditionStatus
+		expectWaitingForPDB metav1.ConditionStatus
+	}{
+		{v1.CycleNodeStatusDrainingPods, metav1.ConditionTrue, metav1.ConditionFalse, metav1.ConditionTrue},
+		{v1.CycleNodeStatusDeletingNode, metav1.ConditionTrue, metav1.ConditionFalse, metav1.ConditionFalse},
+		{v1.CycleNodeStatusFailed, metav1.ConditionFalse, metav1.ConditionTrue, metav1.ConditionFalse},
+		{v1.CycleNodeStatusSuccessful, metav1.ConditionFalse, metav1.ConditionFalse, metav1.ConditionFalse},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.phase), func(t *testing.T) {
+			transitioner := &CycleNodeStatusTransitioner{cycleNodeStatus: &v1.CycleNodeStatus{}}
+			transitioner.cycleNodeStatus.Status.Phase = tt.phase
+			transitioner.cycleNodeStatus.Status.Conditions = []metav1.Condition{waitingForPDB}
+
+			transitioner.setPhaseConditions()
+
+			conditions := transitioner.cycleNodeStatus.Status.Conditions
+			assert.Equal(t, tt.expectProgressing, meta.FindStatusCondition(conditions, v1.CycleNodeStatusConditionProgressing).Status)
+			assert.Equal(t, tt.expectDegraded, meta.FindStatusCondition(conditions, v1.CycleNodeStatusConditionDegraded).Status)
+			assert.Equal(t, tt.expectWaitingForPDB, meta.FindStatusCondition(conditions, v1.CycleNodeStatusConditionWaitingForPDB).Status)
+		})
+	}
+}
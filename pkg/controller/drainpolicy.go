@@ -0,0 +1,64 @@
+package controller
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	atlassianv1 "github.com/atlassian-labs/cyclops/pkg/apis/atlassian/v1"
+	"github.com/atlassian-labs/cyclops/pkg/k8s"
+)
+
+// GetDrainPoliciesForPods returns the DrainPolicy that applies to each of the pods. Pods without a DrainPolicy are
+// left out. When more than one DrainPolicy in a namespace selects a pod, the first one in order of name is used.
+func (rm *ResourceManager) GetDrainPoliciesForPods(pods []*v1.Pod) (map[*v1.Pod]*atlassianv1.DrainPolicy, error) {
+	namespacePolicies := make(map[string][]atlassianv1.DrainPolicy)
+	podPolicies := make(map[*v1.Pod]*atlassianv1.DrainPolicy)
+
+	for _, pod := range pods {
+		policies, ok := namespacePolicies[pod.Namespace]
+		if !ok {
+			var policyList atlassianv1.DrainPolicyList
+			if err := rm.Client.List(context.TODO(), &policyList, client.InNamespace(pod.Namespace)); err != nil {
+				return nil, err
+			}
+
+			policies = policyList.Items
+			sort.Slice(policies, func(i, j int) bool {
+				return policies[i].Name < policies[j].Name
+			})
+			namespacePolicies[pod.Namespace] = policies
+		}
+
+		for i := range policies {
+			matches, err := policies[i].Matches(pod)
+			if err != nil {
+				return nil, err
+			}
+			if matches {
+				podPolicies[pod] = &policies[i]
+				break
+			}
+		}
+	}
+
+	return podPolicies, nil
+}
+
+// drainOptionsForPolicy returns the options to drain a pod with its DrainPolicy, which may be nil
+func drainOptionsForPolicy(policy *atlassianv1.DrainPolicy, unhealthyAfter time.Duration) k8s.PodDrainOptions {
+	options := k8s.PodDrainOptions{UnhealthyAfter: unhealthyAfter}
+	if policy == nil {
+		return options
+	}
+
+	options.Delete = policy.Spec.Method == atlassianv1.DrainPolicyMethodDelete
+	options.GracePeriodSeconds = policy.Spec.GracePeriodSeconds
+	if policy.Spec.ForceDeleteAfter != nil {
+		options.UnhealthyAfter = policy.Spec.ForceDeleteAfter.Duration
+	}
+	return options
+}
@@ -0,0 +1,86 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	coreV1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/atlassian-labs/cyclops/pkg/apis"
+	atlassianv1 "github.com/atlassian-labs/cyclops/pkg/apis/atlassian/v1"
+	"github.com/atlassian-labs/cyclops/pkg/k8s"
+)
+
+func TestGetDrainPoliciesForPods(t *testing.T) {
+	testScheme := runtime.NewScheme()
+	assert.NoError(t, scheme.AddToScheme(testScheme))
+	assert.NoError(t, apis.AddToScheme(testScheme))
+
+	policies := []runtime.Object{
+		&atlassianv1.DrainPolicy{
+			ObjectMeta: v1.ObjectMeta{Name: "b-all", Namespace: "team-a"},
+			Spec:       atlassianv1.DrainPolicySpec{Method: atlassianv1.DrainPolicyMethodDelete},
+		},
+		&atlassianv1.DrainPolicy{
+			ObjectMeta: v1.ObjectMeta{Name: "a-jobs", Namespace: "team-a"},
+			Spec: atlassianv1.DrainPolicySpec{
+				Selector: &v1.LabelSelector{MatchLabels: map[string]string{"app": "job"}},
+				Method:   atlassianv1.DrainPolicyMethodWaitForCompletion,
+			},
+		},
+	}
+	rm := &ResourceManager{
+		Client: fake.NewClientBuilder().WithScheme(testScheme).WithRuntimeObjects(policies...).Build(),
+	}
+
+	job := &coreV1.Pod{ObjectMeta: v1.ObjectMeta{Name: "job", Namespace: "team-a", Labels: map[string]string{"app": "job"}}}
+	web := &coreV1.Pod{ObjectMeta: v1.ObjectMeta{Name: "web", Namespace: "team-a", Labels: map[string]string{"app": "web"}}}
+	other := &coreV1.Pod{ObjectMeta: v1.ObjectMeta{Name: "web", Namespace: "team-b"}}
+
+	podPolicies, err := rm.GetDrainPoliciesForPods([]*coreV1.Pod{job, web, other})
+	assert.NoError(t, err)
+	assert.Len(t, podPolicies, 2)
+	assert.Equal(t, "a-jobs", podPolicies[job].Name)
+	assert.Equal(t, "b-all", podPolicies[web].Name)
+	assert.Nil(t, podPolicies[other])
+}
+
+func TestDrainOptionsForPolicy(t *testing.T) {
+	gracePeriod := int64(5)
+
+	tests := []struct {
+		name   string
+		policy *atlassianv1.DrainPolicy
+		expect k8s.PodDrainOptions
+	}{
+		{
+			"no policy",
+			nil,
+			k8s.PodDrainOptions{UnhealthyAfter: time.Hour},
+		},
+		{
+			"evict with overrides",
+			&atlassianv1.DrainPolicy{Spec: atlassianv1.DrainPolicySpec{
+				GracePeriodSeconds: &gracePeriod,
+				ForceDeleteAfter:   &v1.Duration{Duration: time.Minute},
+			}},
+			k8s.PodDrainOptions{GracePeriodSeconds: &gracePeriod, UnhealthyAfter: time.Minute},
+		},
+		{
+			"delete",
+			&atlassianv1.DrainPolicy{Spec: atlassianv1.DrainPolicySpec{Method: atlassianv1.DrainPolicyMethodDelete}},
+			k8s.PodDrainOptions{Delete: true, UnhealthyAfter: time.Hour},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expect, drainOptionsForPolicy(tt.policy, time.Hour))
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"time"
 
+	atlassianv1 "github.com/atlassian-labs/cyclops/pkg/apis/atlassian/v1"
 	"github.com/atlassian-labs/cyclops/pkg/k8s"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/labels"
@@ -49,17 +50,19 @@ func (rm *ResourceManager) DeleteNode(name string) error {
 	return rm.Client.Delete(context.TODO(), node)
 }
 
-// DrainPods drains the pods off the named node.
-func (rm *ResourceManager) DrainPods(nodeName string, unhealthyAfter time.Duration) (finished bool, errs []error) {
+// DrainPods drains the pods off the named node. The drain settings, if given, limit which of the pods are evicted
+// each time this is called. The DrainPolicy of each pod's namespace that selects it controls how it is drained.
+// The pods whose eviction is blocked, such as by a PodDisruptionBudget, are returned.
+func (rm *ResourceManager) DrainPods(nodeName string, unhealthyAfter time.Duration, drainSettings *atlassianv1.DrainSettings) (finished bool, blocked []*v1.Pod, errs []error) {
 	// Get drainable pods and drain them
 	drainablePods, err := rm.GetDrainablePodsOnNode(nodeName)
 	if err != nil {
-		return false, []error{err}
+		return false, nil, []error{err}
 	}
 
 	// No pods to drain, finish early
 	if len(drainablePods) == 0 {
-		return true, errs
+		return true, nil, errs
 	}
 	rm.Logger.Info("found drainable pods", "numPods", len(drainablePods), "nodeName", nodeName)
 
@@ -69,5 +72,30 @@ func (rm *ResourceManager) DrainPods(nodeName string, unhealthyAfter time.Durati
 		pods = append(pods, &drainablePods[i])
 	}
 
-	return false, k8s.DrainPods(pods, rm.RawClient, unhealthyAfter)
+	policies, err := rm.GetDrainPoliciesForPods(pods)
+	if err != nil {
+		return false, nil, []error{err}
+	}
+
+	// Pods with a WaitForCompletion policy are left to finish on their own
+	waitFor := func(pod *v1.Pod) bool {
+		policy := policies[pod]
+		return policy != nil && policy.Spec.Method == atlassianv1.DrainPolicyMethodWaitForCompletion
+	}
+
+	// Only evict the pods of the current wave, up to the limit of concurrent evictions
+	if drainSettings != nil {
+		pods = k8s.SelectPodsToEvict(pods, drainSettings.PodWave, int(drainSettings.MaxConcurrentEvictions), waitFor)
+	} else {
+		pods = k8s.SelectPodsToEvict(pods, nil, 0, waitFor)
+	}
+	if len(pods) == 0 {
+		return false, nil, errs
+	}
+	rm.Logger.Info("evicting pods", "numPods", len(pods), "nodeName", nodeName)
+
+	blocked, errs = k8s.DrainPods(pods, rm.RawClient, func(pod *v1.Pod) k8s.PodDrainOptions {
+		return drainOptionsForPolicy(policies[pod], unhealthyAfter)
+	})
+	return false, blocked, errs
 }
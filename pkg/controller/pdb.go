@@ -0,0 +1,40 @@
+package controller
+
+import (
+	"context"
+	"sort"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// GetPodDisruptionBudgetsForPod returns the names of the PodDisruptionBudgets in the pod's namespace that select it.
+// The raw client is used so that PodDisruptionBudgets aren't cached by the controller.
+func (rm *ResourceManager) GetPodDisruptionBudgetsForPod(pod *v1.Pod) ([]string, error) {
+	pdbList, err := rm.RawClient.PolicyV1().PodDisruptionBudgets(pod.Namespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, pdb := range pdbList.Items {
+		// A PodDisruptionBudget without a selector matches no pods, while an empty selector matches every pod in the
+		// namespace
+		if pdb.Spec.Selector == nil {
+			continue
+		}
+
+		selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+		if err != nil {
+			return nil, err
+		}
+		if !selector.Matches(labels.Set(pod.Labels)) {
+			continue
+		}
+		names = append(names, pdb.Name)
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
@@ -48,6 +48,22 @@ func ValidateCNR(nodeLister k8s.NodeLister, cnr atlassianv1.CycleNodeRequest) (b
 		return ok, reason
 	}
 
+	if ok, reason := validateMaintenanceWindows(cnr.Spec.MaintenanceWindows); !ok {
+		return ok, reason
+	}
+
+	if ok, reason := validatePrometheusChecks(cnr.Spec.PrometheusChecks); !ok {
+		return ok, reason
+	}
+
+	if ok, reason := validateHealthChecks(cnr.Spec.HealthChecks, cnr.Spec.PreTerminationChecks); !ok {
+		return ok, reason
+	}
+
+	if ok, reason := validateKubernetesHealthChecks(cnr.Spec.KubernetesHealthChecks); !ok {
+		return ok, reason
+	}
+
 	// Protect against failure case where cyclops checks for leftover CycleNodeStatus objects using the CycleNodeRequest name in the label selector
 	// Label values must be no more than 63 characters long
 	name, suffix := GetNameExample(cnr.ObjectMeta)
@@ -107,9 +123,12 @@ func GenerateCNR(nodeGroup atlassianv1.NodeGroup, nodes []string, name, namespac
 			NodeNames:                nodes,
 			CycleSettings:            nodeGroup.Spec.CycleSettings,
 			HealthChecks:             nodeGroup.Spec.HealthChecks,
+			KubernetesHealthChecks:   nodeGroup.Spec.KubernetesHealthChecks,
 			PreTerminationChecks:     nodeGroup.Spec.PreTerminationChecks,
 			SkipInitialHealthChecks:  nodeGroup.Spec.SkipInitialHealthChecks,
 			SkipPreTerminationChecks: nodeGroup.Spec.SkipPreTerminationChecks,
+			MaintenanceWindows:       nodeGroup.Spec.MaintenanceWindows,
+			PrometheusChecks:         nodeGroup.Spec.PrometheusChecks,
 		},
 	}
 }
@@ -12,6 +12,7 @@ import (
 	atlassianv1 "github.com/atlassian-labs/cyclops/pkg/apis/atlassian/v1"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 func TestGiveReason(t *testing.T) {
@@ -44,7 +45,7 @@ func TestApplyCNR(t *testing.T) {
 	nodeGroup.Spec.NodeSelector = *selector
 	nodeGroup.Spec.CycleSettings = atlassianv1.CycleSettings{
 		Method:      "Drain",
-		Concurrency: 1,
+		Concurrency: intstr.FromInt(1),
 	}
 	var ingressGroup atlassianv1.NodeGroup
 	ingressGroup.Name = "ingress"
@@ -52,7 +53,7 @@ func TestApplyCNR(t *testing.T) {
 	ingressGroup.Spec.NodeSelector = *selector
 	ingressGroup.Spec.CycleSettings = atlassianv1.CycleSettings{
 		Method:      "Drain",
-		Concurrency: 1,
+		Concurrency: intstr.FromInt(1),
 	}
 
 	tests := []struct {
@@ -122,7 +123,7 @@ func TestValidateCNR(t *testing.T) {
 	nodeGroup.Spec.NodeSelector = *selectorMeta
 	nodeGroup.Spec.CycleSettings = atlassianv1.CycleSettings{
 		Method:         "Drain",
-		Concurrency:    1,
+		Concurrency:    intstr.FromInt(1),
 		CyclingTimeout: nil,
 	}
 
@@ -130,7 +131,7 @@ func TestValidateCNR(t *testing.T) {
 		name           string
 		nodes          []*v1.Node
 		nodeNames      []string
-		concurrency    int64
+		concurrency    intstr.IntOrString
 		cyclingTimeout *metav1.Duration
 		ok             bool
 		reason         string
@@ -139,7 +140,7 @@ func TestValidateCNR(t *testing.T) {
 			"ok-test",
 			nodes,
 			names,
-			1,
+			intstr.FromInt(1),
 			nil,
 			true,
 			"",
@@ -148,7 +149,7 @@ func TestValidateCNR(t *testing.T) {
 			"bad-name-test-",
 			nodes,
 			names,
-			1,
+			intstr.FromInt(1),
 			nil,
 			false,
 			`label value is not valid: a lowercase RFC 1123 label must consist of lower case alphanumeric characters or '-', and must start and end with an alphanumeric character (e.g. 'my-name',  or '123-abc', regex used for validation is '[a-z0-9]([-a-z0-9]*[a-z0-9])?')`,
@@ -157,7 +158,7 @@ func TestValidateCNR(t *testing.T) {
 			"long-test-" + strings.Repeat("a", 256),
 			nodes,
 			names,
-			1,
+			intstr.FromInt(1),
 			nil,
 			false,
 			"name is not valid: must be no more than 253 characters",
@@ -166,7 +167,7 @@ func TestValidateCNR(t *testing.T) {
 			"test-0-c",
 			nodes,
 			names,
-			0,
+			intstr.FromInt(0),
 			nil,
 			false,
 			concurrencyEqualsZeroMessage,
@@ -175,7 +176,7 @@ func TestValidateCNR(t *testing.T) {
 			"test-negative-c",
 			nodes,
 			names,
-			-1,
+			intstr.FromInt(-1),
 			nil,
 			false,
 			concurrencyLessThanZeroMessage,
@@ -184,7 +185,7 @@ func TestValidateCNR(t *testing.T) {
 			"test-missing",
 			nodes,
 			append(names, "missing"),
-			1,
+			intstr.FromInt(1),
 			nil,
 			false,
 			`the node "missing" does not exist in the nodegroup but it is specified to cycle`,
@@ -193,7 +194,7 @@ func TestValidateCNR(t *testing.T) {
 			"test-scaled-0",
 			nil,
 			nil,
-			1,
+			intstr.FromInt(1),
 			nil,
 			false,
 			nodeGroupScaledToZeroMessage,
@@ -202,7 +203,7 @@ func TestValidateCNR(t *testing.T) {
 			"test-positive-cyclingtimeout",
 			nodes,
 			names,
-			1,
+			intstr.FromInt(1),
 			&metav1.Duration{Duration: 1 * time.Hour},
 			true,
 			"",
@@ -211,7 +212,7 @@ func TestValidateCNR(t *testing.T) {
 			"test-negative-cyclingtimeout",
 			nodes,
 			names,
-			1,
+			intstr.FromInt(1),
 			&metav1.Duration{Duration: -1 * time.Hour},
 			false,
 			cyclingTimeoutLessThanZeroMessage,
@@ -9,21 +9,35 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/util/validation"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	atlassianv1 "github.com/atlassian-labs/cyclops/pkg/apis/atlassian/v1"
+	"github.com/atlassian-labs/cyclops/pkg/checks"
 	"github.com/atlassian-labs/cyclops/pkg/k8s"
+	"github.com/atlassian-labs/cyclops/pkg/maintenance"
 )
 
 const (
-	generateExample                   = "xxxxx"
-	concurrencyLessThanZeroMessage    = "concurrency cannot be less than 0"
-	concurrencyEqualsZeroMessage      = "concurrency set to 0"
-	nodeGroupScaledToZeroMessage      = "node group is scaled to 0"
-	cnrNameLabelKey                   = "name"
-	cnrReasonAnnotationKey            = "reason"
-	cyclingTimeoutLessThanZeroMessage = "cyclingTimeout cannot be less than 0 seconds"
+	generateExample                     = "xxxxx"
+	concurrencyLessThanZeroMessage      = "concurrency cannot be less than 0"
+	concurrencyEqualsZeroMessage        = "concurrency set to 0"
+	concurrencyNotPercentageMessage     = "concurrency must be a number or a percentage"
+	concurrencyOverOneHundredMessage    = "concurrency cannot be more than 100%"
+	nodeGroupScaledToZeroMessage        = "node group is scaled to 0"
+	cnrNameLabelKey                     = "name"
+	cnrReasonAnnotationKey              = "reason"
+	cyclingTimeoutLessThanZeroMessage   = "cyclingTimeout cannot be less than 0 seconds"
+	rolloutCanaryLessThanZeroMessage    = "rollout canaryBatchSize cannot be less than 0"
+	rolloutBakeLessThanZeroMessage      = "rollout bakeDuration cannot be less than 0 seconds"
+	rolloutStepNotPositiveMessage       = "rollout steps must be numbers or percentages greater than 0"
+	drainEvictionsLessThanZeroMessage   = "drain maxConcurrentEvictions cannot be less than 0"
+	drainOrderNotValidMessage           = "drain order must be PriorityClass or WaveAnnotation"
+	drainWarningLessThanZeroMessage     = "drain blockedWarningAfter cannot be less than 0 seconds"
+	drainEscalationNotValidMessage      = "drain blockedEscalation action must be Delete or ForceDelete"
+	drainEscalationNotPositiveMessage   = "drain blockedEscalation after must be greater than 0 seconds"
+	drainEscalationBeforeWarningMessage = "drain blockedEscalation after must be greater than blockedWarningAfter"
 )
 
 // onceShotNodeLister creates a node lister that lists nodes with the controller client.Client as a Get/List
@@ -51,11 +65,24 @@ func (o *onceShotNodeLister) List(selector labels.Selector) ([]*corev1.Node, err
 
 // validateCycleSettings returns if the cycle settings are valid for cycling and why not
 func validateCycleSettings(settings atlassianv1.CycleSettings) (bool, string) {
-	if settings.Concurrency < 0 {
+	concurrency := int(settings.Concurrency.IntVal)
+	if settings.Concurrency.Type == intstr.String {
+		// Scaling the percentage against 100 nodes gives back the percentage itself
+		percent, err := intstr.GetScaledValueFromIntOrPercent(&settings.Concurrency, 100, false)
+		if err != nil {
+			return false, concurrencyNotPercentageMessage
+		}
+		if percent > 100 {
+			return false, concurrencyOverOneHundredMessage
+		}
+		concurrency = percent
+	}
+
+	if concurrency < 0 {
 		return false, concurrencyLessThanZeroMessage
 	}
 
-	if settings.Concurrency == 0 {
+	if concurrency == 0 {
 		return false, concurrencyEqualsZeroMessage
 	}
 
@@ -64,6 +91,107 @@ func validateCycleSettings(settings atlassianv1.CycleSettings) (bool, string) {
 		return false, cyclingTimeoutLessThanZeroMessage
 	}
 
+	// Rollout is optional, only validate if set
+	if rollout := settings.Rollout; rollout != nil {
+		if rollout.CanaryBatchSize < 0 {
+			return false, rolloutCanaryLessThanZeroMessage
+		}
+
+		if rollout.BakeDuration != nil && rollout.BakeDuration.Duration < 0*time.Second {
+			return false, rolloutBakeLessThanZeroMessage
+		}
+
+		for i := range rollout.Steps {
+			if step, err := intstr.GetScaledValueFromIntOrPercent(&rollout.Steps[i], 100, false); err != nil || step <= 0 {
+				return false, rolloutStepNotPositiveMessage
+			}
+		}
+	}
+
+	// Drain is optional, only validate if set
+	if drain := settings.Drain; drain != nil {
+		if drain.MaxConcurrentEvictions < 0 {
+			return false, drainEvictionsLessThanZeroMessage
+		}
+
+		switch drain.Order {
+		case "", atlassianv1.DrainOrderPriorityClass, atlassianv1.DrainOrderWaveAnnotation:
+		default:
+			return false, drainOrderNotValidMessage
+		}
+
+		if drain.BlockedWarningAfter != nil && drain.BlockedWarningAfter.Duration < 0 {
+			return false, drainWarningLessThanZeroMessage
+		}
+
+		if escalation := drain.BlockedEscalation; escalation != nil {
+			switch escalation.Action {
+			case atlassianv1.DrainEscalationActionDelete, atlassianv1.DrainEscalationActionForceDelete:
+			default:
+				return false, drainEscalationNotValidMessage
+			}
+
+			if escalation.After.Duration <= 0 {
+				return false, drainEscalationNotPositiveMessage
+			}
+
+			if drain.BlockedWarningAfter != nil && escalation.After.Duration <= drain.BlockedWarningAfter.Duration {
+				return false, drainEscalationBeforeWarningMessage
+			}
+		}
+	}
+
+	return true, ""
+}
+
+// validateMaintenanceWindows returns if the maintenance windows are valid and why not
+func validateMaintenanceWindows(windows []atlassianv1.MaintenanceWindow) (bool, string) {
+	for _, window := range windows {
+		if err := maintenance.ValidateWindow(&window); err != nil {
+			return false, fmt.Sprint("maintenance window is not valid: ", err.Error())
+		}
+	}
+
+	return true, ""
+}
+
+// validatePrometheusChecks returns if the prometheus checks are valid and why not
+func validatePrometheusChecks(prometheusChecks []atlassianv1.PrometheusCheck) (bool, string) {
+	for _, check := range prometheusChecks {
+		if err := checks.ValidatePrometheusCheck(&check); err != nil {
+			return false, fmt.Sprintf("prometheus check %q is not valid: %s", check.Name, err.Error())
+		}
+	}
+
+	return true, ""
+}
+
+// validateHealthChecks returns if the health checks, and the health checks of the pre-termination checks, are valid
+// and why not
+func validateHealthChecks(healthChecks []atlassianv1.HealthCheck, preTerminationChecks []atlassianv1.PreTerminationCheck) (bool, string) {
+	for _, healthCheck := range healthChecks {
+		if err := checks.ValidateHealthCheck(&healthCheck); err != nil {
+			return false, fmt.Sprintf("health check %q is not valid: %s", healthCheck.Endpoint, err.Error())
+		}
+	}
+
+	for _, preTerminationCheck := range preTerminationChecks {
+		if err := checks.ValidatePreTerminationCheck(&preTerminationCheck); err != nil {
+			return false, fmt.Sprintf("pre-termination check %q is not valid: %s", preTerminationCheck.Endpoint, err.Error())
+		}
+	}
+
+	return true, ""
+}
+
+// validateKubernetesHealthChecks returns if the kubernetes health checks are valid and why not
+func validateKubernetesHealthChecks(checks []atlassianv1.KubernetesHealthCheck) (bool, string) {
+	for _, check := range checks {
+		if err := check.Validate(); err != nil {
+			return false, fmt.Sprintf("kubernetes health check %q is not valid: %s", check.String(), err.Error())
+		}
+	}
+
 	return true, ""
 }
 
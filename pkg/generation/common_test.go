@@ -11,6 +11,7 @@ import (
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 func TestGetName(t *testing.T) {
@@ -105,58 +106,186 @@ func TestValidateCycleSettings(t *testing.T) {
 	}{
 		{
 			"test concurrency positive",
-			atlassianv1.CycleSettings{Concurrency: 1},
+			atlassianv1.CycleSettings{Concurrency: intstr.FromInt(1)},
 			true,
 			"",
 		},
 		{
 			"test concurrency positive large",
-			atlassianv1.CycleSettings{Concurrency: 20},
+			atlassianv1.CycleSettings{Concurrency: intstr.FromInt(20)},
 			true,
 			"",
 		},
 		{
 			"test concurrency 0",
-			atlassianv1.CycleSettings{Concurrency: 0},
+			atlassianv1.CycleSettings{Concurrency: intstr.FromInt(0)},
 			false,
 			concurrencyEqualsZeroMessage,
 		},
 		{
 			"test concurrency negative",
-			atlassianv1.CycleSettings{Concurrency: -1},
+			atlassianv1.CycleSettings{Concurrency: intstr.FromInt(-1)},
 			false,
 			concurrencyLessThanZeroMessage,
 		},
 		{
 			"test concurrency negative large",
-			atlassianv1.CycleSettings{Concurrency: -20},
+			atlassianv1.CycleSettings{Concurrency: intstr.FromInt(-20)},
 			false,
 			concurrencyLessThanZeroMessage,
 		},
+		{
+			"test concurrency percentage",
+			atlassianv1.CycleSettings{Concurrency: intstr.FromString("10%")},
+			true,
+			"",
+		},
+		{
+			"test concurrency percentage 100",
+			atlassianv1.CycleSettings{Concurrency: intstr.FromString("100%")},
+			true,
+			"",
+		},
+		{
+			"test concurrency percentage 0",
+			atlassianv1.CycleSettings{Concurrency: intstr.FromString("0%")},
+			false,
+			concurrencyEqualsZeroMessage,
+		},
+		{
+			"test concurrency percentage negative",
+			atlassianv1.CycleSettings{Concurrency: intstr.FromString("-10%")},
+			false,
+			concurrencyLessThanZeroMessage,
+		},
+		{
+			"test concurrency percentage over 100",
+			atlassianv1.CycleSettings{Concurrency: intstr.FromString("150%")},
+			false,
+			concurrencyOverOneHundredMessage,
+		},
+		{
+			"test concurrency string not a percentage",
+			atlassianv1.CycleSettings{Concurrency: intstr.FromString("10")},
+			false,
+			concurrencyNotPercentageMessage,
+		},
 		{
 			"test cyclingTimeout positive small",
-			atlassianv1.CycleSettings{CyclingTimeout: &metav1.Duration{Duration: 1 * time.Hour}, Concurrency: 1},
+			atlassianv1.CycleSettings{CyclingTimeout: &metav1.Duration{Duration: 1 * time.Hour}, Concurrency: intstr.FromInt(1)},
 			true,
 			"",
 		},
 		{
 			"test cyclingTimeout positive large",
-			atlassianv1.CycleSettings{CyclingTimeout: &metav1.Duration{Duration: 99 * time.Hour}, Concurrency: 1},
+			atlassianv1.CycleSettings{CyclingTimeout: &metav1.Duration{Duration: 99 * time.Hour}, Concurrency: intstr.FromInt(1)},
 			true,
 			"",
 		},
 		{
 			"test cyclingTimeout negative small",
-			atlassianv1.CycleSettings{CyclingTimeout: &metav1.Duration{Duration: -1 * time.Second}, Concurrency: 1},
+			atlassianv1.CycleSettings{CyclingTimeout: &metav1.Duration{Duration: -1 * time.Second}, Concurrency: intstr.FromInt(1)},
 			false,
 			cyclingTimeoutLessThanZeroMessage,
 		},
 		{
 			"test cyclingTimeout negative large",
-			atlassianv1.CycleSettings{CyclingTimeout: &metav1.Duration{Duration: -99 * time.Hour}, Concurrency: 1},
+			atlassianv1.CycleSettings{CyclingTimeout: &metav1.Duration{Duration: -99 * time.Hour}, Concurrency: intstr.FromInt(1)},
 			false,
 			cyclingTimeoutLessThanZeroMessage,
 		},
+		{
+			"test rollout",
+			atlassianv1.CycleSettings{Concurrency: intstr.FromInt(4), Rollout: &atlassianv1.CycleRollout{
+				CanaryBatchSize: 1,
+				BakeDuration:    &metav1.Duration{Duration: 10 * time.Minute},
+				Steps:           []intstr.IntOrString{intstr.FromString("25%"), intstr.FromInt(4)},
+			}},
+			true,
+			"",
+		},
+		{
+			"test rollout canary negative",
+			atlassianv1.CycleSettings{Concurrency: intstr.FromInt(4), Rollout: &atlassianv1.CycleRollout{CanaryBatchSize: -1}},
+			false,
+			rolloutCanaryLessThanZeroMessage,
+		},
+		{
+			"test rollout bake negative",
+			atlassianv1.CycleSettings{Concurrency: intstr.FromInt(4), Rollout: &atlassianv1.CycleRollout{BakeDuration: &metav1.Duration{Duration: -time.Minute}}},
+			false,
+			rolloutBakeLessThanZeroMessage,
+		},
+		{
+			"test rollout step 0",
+			atlassianv1.CycleSettings{Concurrency: intstr.FromInt(4), Rollout: &atlassianv1.CycleRollout{Steps: []intstr.IntOrString{intstr.FromString("0%")}}},
+			false,
+			rolloutStepNotPositiveMessage,
+		},
+		{
+			"test rollout step not a percentage",
+			atlassianv1.CycleSettings{Concurrency: intstr.FromInt(4), Rollout: &atlassianv1.CycleRollout{Steps: []intstr.IntOrString{intstr.FromString("half")}}},
+			false,
+			rolloutStepNotPositiveMessage,
+		},
+		{
+			"test drain",
+			atlassianv1.CycleSettings{Concurrency: intstr.FromInt(1), Drain: &atlassianv1.DrainSettings{MaxConcurrentEvictions: 5, Order: atlassianv1.DrainOrderPriorityClass}},
+			true,
+			"",
+		},
+		{
+			"test drain evictions negative",
+			atlassianv1.CycleSettings{Concurrency: intstr.FromInt(1), Drain: &atlassianv1.DrainSettings{MaxConcurrentEvictions: -1}},
+			false,
+			drainEvictionsLessThanZeroMessage,
+		},
+		{
+			"test drain order unknown",
+			atlassianv1.CycleSettings{Concurrency: intstr.FromInt(1), Drain: &atlassianv1.DrainSettings{Order: "Alphabetical"}},
+			false,
+			drainOrderNotValidMessage,
+		},
+		{
+			"test drain blocked escalation",
+			atlassianv1.CycleSettings{Concurrency: intstr.FromInt(1), Drain: &atlassianv1.DrainSettings{
+				BlockedWarningAfter: &metav1.Duration{Duration: 10 * time.Minute},
+				BlockedEscalation:   &atlassianv1.DrainEscalation{Action: atlassianv1.DrainEscalationActionForceDelete, After: metav1.Duration{Duration: time.Hour}},
+			}},
+			true,
+			"",
+		},
+		{
+			"test drain blocked warning negative",
+			atlassianv1.CycleSettings{Concurrency: intstr.FromInt(1), Drain: &atlassianv1.DrainSettings{BlockedWarningAfter: &metav1.Duration{Duration: -1 * time.Second}}},
+			false,
+			drainWarningLessThanZeroMessage,
+		},
+		{
+			"test drain blocked escalation action unknown",
+			atlassianv1.CycleSettings{Concurrency: intstr.FromInt(1), Drain: &atlassianv1.DrainSettings{
+				BlockedEscalation: &atlassianv1.DrainEscalation{Action: "Ignore", After: metav1.Duration{Duration: time.Hour}},
+			}},
+			false,
+			drainEscalationNotValidMessage,
+		},
+		{
+			"test drain blocked escalation after zero",
+			atlassianv1.CycleSettings{Concurrency: intstr.FromInt(1), Drain: &atlassianv1.DrainSettings{
+				BlockedEscalation: &atlassianv1.DrainEscalation{Action: atlassianv1.DrainEscalationActionDelete},
+			}},
+			false,
+			drainEscalationNotPositiveMessage,
+		},
+		{
+			"test drain blocked escalation before warning",
+			atlassianv1.CycleSettings{Concurrency: intstr.FromInt(1), Drain: &atlassianv1.DrainSettings{
+				BlockedWarningAfter: &metav1.Duration{Duration: time.Hour},
+				BlockedEscalation:   &atlassianv1.DrainEscalation{Action: atlassianv1.DrainEscalationActionDelete, After: metav1.Duration{Duration: 10 * time.Minute}},
+			}},
+			false,
+			drainEscalationBeforeWarningMessage,
+		},
 	}
 
 	for _, tt := range tests {
@@ -168,6 +297,195 @@ func TestValidateCycleSettings(t *testing.T) {
 	}
 }
 
+func TestValidateMaintenanceWindows(t *testing.T) {
+	tests := []struct {
+		name    string
+		windows []atlassianv1.MaintenanceWindow
+		ok      bool
+	}{
+		{
+			"test no windows",
+			nil,
+			true,
+		},
+		{
+			"test valid windows",
+			[]atlassianv1.MaintenanceWindow{
+				{Days: []string{"Sat", "Sun"}, StartTime: "22:00", EndTime: "06:00", TimeZone: "Europe/London"},
+				{Schedule: "0 2 * * *", Duration: &metav1.Duration{Duration: 4 * time.Hour}},
+			},
+			true,
+		},
+		{
+			"test invalid window",
+			[]atlassianv1.MaintenanceWindow{
+				{Days: []string{"Sat"}},
+				{Schedule: "0 2 * * *"},
+			},
+			false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, reason := validateMaintenanceWindows(tt.windows)
+			assert.Equal(t, tt.ok, ok)
+			if tt.ok {
+				assert.Empty(t, reason)
+			} else {
+				assert.Contains(t, reason, "maintenance window is not valid")
+			}
+		})
+	}
+}
+
+func TestValidatePrometheusChecks(t *testing.T) {
+	tests := []struct {
+		name   string
+		checks []atlassianv1.PrometheusCheck
+		ok     bool
+	}{
+		{
+			"test no checks",
+			nil,
+			true,
+		},
+		{
+			"test valid checks",
+			[]atlassianv1.PrometheusCheck{
+				{Name: "errors", Query: "error_rate", Threshold: "< 0.01", Window: &metav1.Duration{Duration: 5 * time.Minute}},
+				{Name: "alerts", Query: `ALERTS{team="x"}`, Threshold: "==0"},
+			},
+			true,
+		},
+		{
+			"test invalid threshold",
+			[]atlassianv1.PrometheusCheck{
+				{Name: "errors", Query: "error_rate", Threshold: "below 0.01"},
+			},
+			false,
+		},
+		{
+			"test empty query",
+			[]atlassianv1.PrometheusCheck{
+				{Name: "errors", Threshold: "< 0.01"},
+			},
+			false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, reason := validatePrometheusChecks(tt.checks)
+			assert.Equal(t, tt.ok, ok)
+			if tt.ok {
+				assert.Empty(t, reason)
+			} else {
+				assert.Contains(t, reason, "prometheus check \"errors\" is not valid")
+			}
+		})
+	}
+}
+
+func TestValidateHealthChecks(t *testing.T) {
+	tests := []struct {
+		name                 string
+		healthChecks         []atlassianv1.HealthCheck
+		preTerminationChecks []atlassianv1.PreTerminationCheck
+		ok                   bool
+		reason               string
+	}{
+		{
+			"test no checks",
+			nil,
+			nil,
+			true,
+			"",
+		},
+		{
+			"test valid checks",
+			[]atlassianv1.HealthCheck{
+				{Endpoint: "http://{{ .NodeIP }}/health"},
+				{Protocol: atlassianv1.HealthCheckProtocolGRPC, Endpoint: "{{ .NodeIP }}:9000", GRPCService: "agent"},
+			},
+			[]atlassianv1.PreTerminationCheck{
+				{HealthCheck: atlassianv1.HealthCheck{Protocol: atlassianv1.HealthCheckProtocolTCP, Endpoint: "{{ .NodeIP }}:9000"}},
+			},
+			true,
+			"",
+		},
+		{
+			"test invalid health check",
+			[]atlassianv1.HealthCheck{
+				{Protocol: atlassianv1.HealthCheckProtocolTCP, Endpoint: "{{ .NodeIP }}:9000", RegexMatch: "ok"},
+			},
+			nil,
+			false,
+			"health check \"{{ .NodeIP }}:9000\" is not valid",
+		},
+		{
+			"test invalid pre-termination check",
+			nil,
+			[]atlassianv1.PreTerminationCheck{
+				{HealthCheck: atlassianv1.HealthCheck{Protocol: "udp", Endpoint: "{{ .NodeIP }}:9000"}},
+			},
+			false,
+			"pre-termination check",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, reason := validateHealthChecks(tt.healthChecks, tt.preTerminationChecks)
+			assert.Equal(t, tt.ok, ok)
+			assert.Contains(t, reason, tt.reason)
+		})
+	}
+}
+
+func TestValidateKubernetesHealthChecks(t *testing.T) {
+	waitPeriod := &metav1.Duration{Duration: time.Minute}
+
+	tests := []struct {
+		name   string
+		checks []atlassianv1.KubernetesHealthCheck
+		ok     bool
+	}{
+		{
+			"test no checks",
+			nil,
+			true,
+		},
+		{
+			"test valid checks",
+			[]atlassianv1.KubernetesHealthCheck{
+				{Type: atlassianv1.KubernetesHealthCheckDaemonSetPodsReady, WaitPeriod: waitPeriod},
+				{Type: atlassianv1.KubernetesHealthCheckDeploymentAvailable, Namespace: "default", Name: "web", WaitPeriod: waitPeriod},
+			},
+			true,
+		},
+		{
+			"test missing name",
+			[]atlassianv1.KubernetesHealthCheck{
+				{Type: atlassianv1.KubernetesHealthCheckServiceReadyEndpoints, Namespace: "default", WaitPeriod: waitPeriod},
+			},
+			false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, reason := validateKubernetesHealthChecks(tt.checks)
+			assert.Equal(t, tt.ok, ok)
+			if tt.ok {
+				assert.Empty(t, reason)
+			} else {
+				assert.Contains(t, reason, "kubernetes health check")
+			}
+		})
+	}
+}
+
 func TestValidateMetadata(t *testing.T) {
 	tests := []struct {
 		name   string
@@ -49,6 +49,22 @@ func ValidateNodeGroup(nodeLister k8s.NodeLister, nodegroup atlassianv1.NodeGrou
 		return ok, reason
 	}
 
+	if ok, reason := validateMaintenanceWindows(nodegroup.Spec.MaintenanceWindows); !ok {
+		return ok, reason
+	}
+
+	if ok, reason := validatePrometheusChecks(nodegroup.Spec.PrometheusChecks); !ok {
+		return ok, reason
+	}
+
+	if ok, reason := validateHealthChecks(nodegroup.Spec.HealthChecks, nodegroup.Spec.PreTerminationChecks); !ok {
+		return ok, reason
+	}
+
+	if ok, reason := validateKubernetesHealthChecks(nodegroup.Spec.KubernetesHealthChecks); !ok {
+		return ok, reason
+	}
+
 	// validate against nodes in api
 	selector, err := metav1.LabelSelectorAsSelector(&nodegroup.Spec.NodeSelector)
 	if err != nil {
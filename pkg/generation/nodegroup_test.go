@@ -12,6 +12,7 @@ import (
 
 	atlassianv1 "github.com/atlassian-labs/cyclops/pkg/apis/atlassian/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 func TestValidateNodeGroup(t *testing.T) {
@@ -28,7 +29,7 @@ func TestValidateNodeGroup(t *testing.T) {
 		name           string
 		nodes          []*v1.Node
 		nodeNames      []string
-		concurrency    int64
+		concurrency    intstr.IntOrString
 		cyclingTimeout *metav1.Duration
 		ok             bool
 		reason         string
@@ -37,7 +38,7 @@ func TestValidateNodeGroup(t *testing.T) {
 			"ok-test",
 			nodes,
 			names,
-			1,
+			intstr.FromInt(1),
 			nil,
 			true,
 			"",
@@ -46,7 +47,7 @@ func TestValidateNodeGroup(t *testing.T) {
 			"bad-name-test-",
 			nodes,
 			names,
-			1,
+			intstr.FromInt(1),
 			nil,
 			false,
 			`name is not valid: a lowercase RFC 1123 subdomain must consist of lower case alphanumeric characters, '-' or '.', and must start and end with an alphanumeric character (e.g. 'example.com', regex used for validation is '[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*')`,
@@ -55,7 +56,7 @@ func TestValidateNodeGroup(t *testing.T) {
 			"long-test-" + strings.Repeat("a", 256),
 			nodes,
 			names,
-			1,
+			intstr.FromInt(1),
 			nil,
 			false,
 			"name is not valid: must be no more than 253 characters",
@@ -64,7 +65,7 @@ func TestValidateNodeGroup(t *testing.T) {
 			"test-0-c",
 			nodes,
 			names,
-			0,
+			intstr.FromInt(0),
 			nil,
 			false,
 			concurrencyEqualsZeroMessage,
@@ -73,7 +74,7 @@ func TestValidateNodeGroup(t *testing.T) {
 			"test-negative-c",
 			nodes,
 			names,
-			-1,
+			intstr.FromInt(-1),
 			nil,
 			false,
 			concurrencyLessThanZeroMessage,
@@ -82,7 +83,7 @@ func TestValidateNodeGroup(t *testing.T) {
 			"test-does-not-check-missing",
 			nodes,
 			append(names, "missing"),
-			1,
+			intstr.FromInt(1),
 			nil,
 			true,
 			"",
@@ -91,7 +92,7 @@ func TestValidateNodeGroup(t *testing.T) {
 			"test-scaled-0",
 			nil,
 			nil,
-			1,
+			intstr.FromInt(1),
 			nil,
 			false,
 			nodeGroupScaledToZeroMessage,
@@ -100,7 +101,7 @@ func TestValidateNodeGroup(t *testing.T) {
 			"test-positive-cyclingtimeout",
 			nodes,
 			names,
-			1,
+			intstr.FromInt(1),
 			&metav1.Duration{Duration: 1 * time.Hour},
 			true,
 			"",
@@ -109,7 +110,7 @@ func TestValidateNodeGroup(t *testing.T) {
 			"test-negative-cyclingtimeout",
 			nodes,
 			names,
-			1,
+			intstr.FromInt(1),
 			&metav1.Duration{Duration: -1 * time.Hour},
 			false,
 			cyclingTimeoutLessThanZeroMessage,
@@ -0,0 +1,30 @@
+package k8s
+
+import (
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// maxConditionMessageLength is the longest message allowed in a condition by the API
+const maxConditionMessageLength = 32768
+
+// SetCondition sets a condition in the list of conditions for the given generation of an object. The message is
+// truncated to the length allowed by the API. It returns true if the condition changed.
+func SetCondition(conditions *[]metav1.Condition, generation int64, conditionType string, status metav1.ConditionStatus, reason, message string) bool {
+	if len(message) > maxConditionMessageLength {
+		message = message[:maxConditionMessageLength]
+	}
+
+	existing := meta.FindStatusCondition(*conditions, conditionType)
+	changed := existing == nil || existing.Status != status || existing.Reason != reason ||
+		existing.Message != message || existing.ObservedGeneration != generation
+
+	meta.SetStatusCondition(conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             status,
+		ObservedGeneration: generation,
+		Reason:             reason,
+		Message:            message,
+	})
+	return changed
+}
@@ -0,0 +1,31 @@
+package k8s
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSetCondition(t *testing.T) {
+	var conditions []metav1.Condition
+
+	assert.True(t, SetCondition(&conditions, 1, "Progressing", metav1.ConditionTrue, "Pending", "Cycling nodes"))
+	assert.False(t, SetCondition(&conditions, 1, "Progressing", metav1.ConditionTrue, "Pending", "Cycling nodes"))
+	assert.True(t, SetCondition(&conditions, 1, "Progressing", metav1.ConditionTrue, "ScalingUp", "Cycling nodes"))
+	assert.True(t, SetCondition(&conditions, 2, "Progressing", metav1.ConditionTrue, "ScalingUp", "Cycling nodes"))
+
+	condition := meta.FindStatusCondition(conditions, "Progressing")
+	if assert.NotNil(t, condition) {
+		assert.Equal(t, "ScalingUp", condition.Reason)
+		assert.Equal(t, int64(2), condition.ObservedGeneration)
+		assert.False(t, condition.LastTransitionTime.IsZero())
+	}
+
+	// Long messages are truncated to what the API accepts
+	assert.True(t, SetCondition(&conditions, 2, "Degraded", metav1.ConditionTrue, "Failed", strings.Repeat("a", maxConditionMessageLength+1)))
+	assert.Len(t, meta.FindStatusCondition(conditions, "Degraded").Message, maxConditionMessageLength)
+	assert.Len(t, conditions, 2)
+}
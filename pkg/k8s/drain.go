@@ -2,6 +2,7 @@ package k8s
 
 import (
 	"fmt"
+	"sort"
 	"time"
 
 	v1 "k8s.io/api/core/v1"
@@ -14,20 +15,82 @@ const (
 )
 
 // DrainPods attempts to delete or evict pods so that the node can be terminated.
-// Will prioritise using Evict if the API server supports it.
-// Pods that have been unhealthy for longer than the given duration will be forcibly removed to prevent stalling.
-func DrainPods(pods []*v1.Pod, client kubernetes.Interface, unhealthyAfter time.Duration) []error {
+// Will prioritise using Evict if the API server supports it. The options for each pod choose whether it is deleted
+// instead. Pods that have been unhealthy for longer than the UnhealthyAfter of their options will be forcibly
+// removed to prevent stalling. The pods whose eviction is blocked, such as by a PodDisruptionBudget, are returned.
+func DrainPods(pods []*v1.Pod, client kubernetes.Interface, drainOptions func(*v1.Pod) PodDrainOptions) (blocked []*v1.Pod, errs []error) {
 	// Determine whether we are able to delete or evict pods
 	apiVersion, err := SupportEviction(client)
 	if err != nil {
-		return []error{err}
+		return nil, []error{err}
 	}
 
 	// If we are able to evict
 	if len(apiVersion) == 0 {
-		return []error{fmt.Errorf("apiVersion does not support pod eviction API")}
+		return nil, []error{fmt.Errorf("apiVersion does not support pod eviction API")}
 	}
-	return EvictPods(pods, apiVersion, client, unhealthyAfter, time.Now())
+	return drainPods(pods, apiVersion, client, drainOptions, time.Now())
+}
+
+// SelectPodsToEvict returns the pods in the lowest wave that can be evicted now. Pods that are already terminating
+// count against maxConcurrent but aren't returned again. A maxConcurrent of 0 doesn't limit the number of pods, and
+// every pod is in the same wave if waveOf is nil. Later waves are only returned once the lower waves have left.
+// Pods that waitFor returns true for are left to complete on their own: they hold back later waves, but aren't
+// returned and don't count against maxConcurrent.
+func SelectPodsToEvict(pods []*v1.Pod, waveOf func(*v1.Pod) int64, maxConcurrent int, waitFor func(*v1.Pod) bool) []*v1.Pod {
+	if len(pods) == 0 {
+		return nil
+	}
+
+	wave := func(pod *v1.Pod) int64 {
+		if waveOf == nil {
+			return 0
+		}
+		return waveOf(pod)
+	}
+
+	currentWave := wave(pods[0])
+	for _, pod := range pods[1:] {
+		if w := wave(pod); w < currentWave {
+			currentWave = w
+		}
+	}
+
+	var evicting int
+	var candidates []*v1.Pod
+	for _, pod := range pods {
+		if wave(pod) != currentWave {
+			continue
+		}
+		if waitFor != nil && waitFor(pod) {
+			continue
+		}
+		if pod.DeletionTimestamp != nil {
+			evicting++
+			continue
+		}
+		candidates = append(candidates, pod)
+	}
+
+	// Evict in a stable order so that the same pods are picked each time
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].Namespace != candidates[j].Namespace {
+			return candidates[i].Namespace < candidates[j].Namespace
+		}
+		return candidates[i].Name < candidates[j].Name
+	})
+
+	if maxConcurrent > 0 {
+		available := maxConcurrent - evicting
+		if available <= 0 {
+			return nil
+		}
+		if available < len(candidates) {
+			candidates = candidates[:available]
+		}
+	}
+
+	return candidates
 }
 
 // SupportEviction uses Discovery API to find out if the API server supports the eviction subresource
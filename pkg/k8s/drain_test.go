@@ -0,0 +1,123 @@
+package k8s
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/atlassian-labs/cyclops/pkg/test"
+)
+
+func TestSelectPodsToEvict(t *testing.T) {
+	newPod := func(name string, wave int64, terminating bool) *corev1.Pod {
+		pod := test.BuildTestPod(test.PodOpts{Name: name, Namespace: "default", NodeName: "test-node"})
+		pod.Labels = map[string]string{"wave": strconv.FormatInt(wave, 10)}
+		if terminating {
+			now := metav1.Now()
+			pod.DeletionTimestamp = &now
+		}
+		return pod
+	}
+	waveOf := func(pod *corev1.Pod) int64 {
+		wave, _ := strconv.ParseInt(pod.Labels["wave"], 10, 64)
+		return wave
+	}
+	names := func(pods []*corev1.Pod) []string {
+		var names []string
+		for _, pod := range pods {
+			names = append(names, pod.Name)
+		}
+		return names
+	}
+
+	tests := []struct {
+		name          string
+		pods          []*corev1.Pod
+		waveOf        func(*corev1.Pod) int64
+		maxConcurrent int
+		waitFor       func(*corev1.Pod) bool
+		expect        []string
+	}{
+		{
+			"no pods",
+			nil,
+			waveOf,
+			0,
+			nil,
+			nil,
+		},
+		{
+			"no waves or limit",
+			[]*corev1.Pod{newPod("c", 2, false), newPod("a", 1, false), newPod("b", 1, true)},
+			nil,
+			0,
+			nil,
+			[]string{"a", "c"},
+		},
+		{
+			"lowest wave first",
+			[]*corev1.Pod{newPod("c", 2, false), newPod("b", 1, false), newPod("a", 1, false)},
+			waveOf,
+			0,
+			nil,
+			[]string{"a", "b"},
+		},
+		{
+			"next wave waits for terminating pods",
+			[]*corev1.Pod{newPod("c", 2, false), newPod("a", 1, true)},
+			waveOf,
+			0,
+			nil,
+			nil,
+		},
+		{
+			"limited evictions",
+			[]*corev1.Pod{newPod("c", 1, false), newPod("b", 1, false), newPod("a", 1, false)},
+			waveOf,
+			2,
+			nil,
+			[]string{"a", "b"},
+		},
+		{
+			"terminating pods count against the limit",
+			[]*corev1.Pod{newPod("c", 1, false), newPod("b", 1, false), newPod("a", 1, true)},
+			waveOf,
+			2,
+			nil,
+			[]string{"b"},
+		},
+		{
+			"pods left to complete hold back later waves",
+			[]*corev1.Pod{newPod("job", 1, false), newPod("a", 2, false)},
+			waveOf,
+			1,
+			func(pod *corev1.Pod) bool { return pod.Name == "job" },
+			nil,
+		},
+		{
+			"pods left to complete don't count against the limit",
+			[]*corev1.Pod{newPod("job", 1, false), newPod("a", 1, false), newPod("b", 1, false)},
+			waveOf,
+			1,
+			func(pod *corev1.Pod) bool { return pod.Name == "job" },
+			[]string{"a"},
+		},
+		{
+			"limit reached",
+			[]*corev1.Pod{newPod("c", 1, false), newPod("b", 1, true), newPod("a", 1, true)},
+			waveOf,
+			2,
+			nil,
+			nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expect, names(SelectPodsToEvict(tt.pods, tt.waveOf, tt.maxConcurrent, tt.waitFor)))
+		})
+	}
+}
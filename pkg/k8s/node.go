@@ -58,6 +58,17 @@ func AddLabelToNode(nodeName string, labelName string, labelValue string, client
 	return PatchNode(nodeName, patches, client)
 }
 
+// RemoveLabelFromNode performs a patch operation on a node to remove a label from the node
+func RemoveLabelFromNode(nodeName string, labelName string, client kubernetes.Interface) error {
+	patches := []Patch{
+		{
+			Op:   "remove",
+			Path: fmt.Sprintf("/metadata/labels/%s", strings.Replace(labelName, "/", "~1", -1)),
+		},
+	}
+	return PatchNode(nodeName, patches, client)
+}
+
 // NodeLister defines an object that can list nodes with a label selector
 type NodeLister interface {
 	List(labels.Selector) ([]*v1.Node, error)
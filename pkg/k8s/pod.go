@@ -31,8 +31,23 @@ func ForciblyDeletePod(podName, podNamespace, nodeName string, client kubernetes
 	})
 }
 
+// DeletePod deletes a pod from a Kubernetes node without regard to its PodDisruptionBudgets. The grace period
+// overrides the termination grace period of the pod when it is set.
+func DeletePod(pod *v1.Pod, gracePeriodSeconds *int64, client kubernetes.Interface) error {
+	log.Info("Deleting pod", "podName", pod.Name, "podNamespace", pod.Namespace,
+		"nodeName", pod.Spec.NodeName)
+	return client.CoreV1().Pods(pod.Namespace).Delete(context.TODO(), pod.Name, metaV1.DeleteOptions{
+		GracePeriodSeconds: gracePeriodSeconds,
+	})
+}
+
 // EvictPod evicts a single pod from a Kubernetes node
 func EvictPod(pod *v1.Pod, apiVersion string, client kubernetes.Interface) error {
+	return evictPod(pod, apiVersion, nil, client)
+}
+
+// evictPod evicts a single pod from a Kubernetes node, overriding its termination grace period if one is given
+func evictPod(pod *v1.Pod, apiVersion string, gracePeriodSeconds *int64, client kubernetes.Interface) error {
 	log.Info("Evicting pod", "podName", pod.Name, "podNamespace", pod.Namespace,
 		"nodeName", pod.Spec.NodeName, "apiVersion", apiVersion)
 	return client.CoreV1().Pods(pod.Namespace).Evict(context.TODO(), &v1beta1.Eviction{
@@ -41,40 +56,82 @@ func EvictPod(pod *v1.Pod, apiVersion string, client kubernetes.Interface) error
 			Kind:       evictionKind,
 		},
 		ObjectMeta:    pod.ObjectMeta,
-		DeleteOptions: &metaV1.DeleteOptions{},
+		DeleteOptions: &metaV1.DeleteOptions{GracePeriodSeconds: gracePeriodSeconds},
 	})
 }
 
 // EvictOrForciblyDeletePod tries to evict a pod, and if that fails will then check if it can forcibly remove the pod instead.
 func EvictOrForciblyDeletePod(pod *v1.Pod, apiVersion string, client kubernetes.Interface, unhealthyAfter time.Duration, now time.Time) error {
-	err := EvictPod(pod, apiVersion, client)
+	return DrainPod(pod, apiVersion, client, PodDrainOptions{UnhealthyAfter: unhealthyAfter}, now)
+}
+
+// PodDrainOptions control how a single pod is removed from a node being drained
+type PodDrainOptions struct {
+	// Delete deletes the pod instead of evicting it, without regard to its PodDisruptionBudgets
+	Delete bool
+
+	// GracePeriodSeconds overrides the termination grace period of the pod when it is set
+	GracePeriodSeconds *int64
+
+	// UnhealthyAfter is how long the pod must have been unhealthy for before it is forcibly removed when its
+	// eviction is blocked
+	UnhealthyAfter time.Duration
+}
+
+// DrainPod deletes or evicts a pod with the given options. A pod that is un-evictable is forcibly removed if it has
+// been unhealthy for longer than the UnhealthyAfter of the options.
+func DrainPod(pod *v1.Pod, apiVersion string, client kubernetes.Interface, options PodDrainOptions, now time.Time) error {
+	_, err := drainPod(pod, apiVersion, client, options, now)
+	return err
+}
+
+// drainPod deletes or evicts a pod with the given options, and returns true if the eviction of the pod is blocked
+// and the pod has been left on the node.
+func drainPod(pod *v1.Pod, apiVersion string, client kubernetes.Interface, options PodDrainOptions, now time.Time) (bool, error) {
+	if options.Delete {
+		return false, DeletePod(pod, options.GracePeriodSeconds, client)
+	}
+
+	err := evictPod(pod, apiVersion, options.GracePeriodSeconds, client)
 	if err != nil {
 		// If we couldn't drain the pod, double check if it's been unhealthy for too long and if it has then
 		// force it off the node so we can continue.
 		if serr, ok := err.(*errors.StatusError); ok && errors.IsTooManyRequests(serr) {
-			if PodIsLongtermUnhealthy(pod.Status, unhealthyAfter, now) {
+			if PodIsLongtermUnhealthy(pod.Status, options.UnhealthyAfter, now) {
 				log.Info("Pod is un-evictable and is unhealthy for longer than the unhealthy threshold",
 					"podName", pod.Name, "podNamespace", pod.Namespace, "nodeName", pod.Spec.NodeName,
-					"unhealthyThreshold", unhealthyAfter)
-				return ForciblyDeletePod(pod.Name, pod.Namespace, pod.Spec.NodeName, client)
+					"unhealthyThreshold", options.UnhealthyAfter)
+				return false, ForciblyDeletePod(pod.Name, pod.Namespace, pod.Spec.NodeName, client)
 			}
-		} else {
-			return err
+			return true, nil
 		}
+		return false, err
 	}
-	return nil
+	return false, nil
 }
 
 // EvictPods evicts multiple pods from a Kubernetes node. Forcibly removes a pod if it is old and unhealthy and
 // stopping the eviction as a result.
 func EvictPods(pods []*v1.Pod, apiVersion string, client kubernetes.Interface, unhealthyAfter time.Duration, now time.Time) (evictionErrors []error) {
+	_, evictionErrors = drainPods(pods, apiVersion, client, func(*v1.Pod) PodDrainOptions {
+		return PodDrainOptions{UnhealthyAfter: unhealthyAfter}
+	}, now)
+	return evictionErrors
+}
+
+// drainPods deletes or evicts multiple pods from a Kubernetes node with the options given for each of them. It returns
+// the pods whose eviction is blocked.
+func drainPods(pods []*v1.Pod, apiVersion string, client kubernetes.Interface, drainOptions func(*v1.Pod) PodDrainOptions, now time.Time) (blocked []*v1.Pod, drainErrors []error) {
 	for _, pod := range pods {
-		err := EvictOrForciblyDeletePod(pod, apiVersion, client, unhealthyAfter, now)
+		isBlocked, err := drainPod(pod, apiVersion, client, drainOptions(pod), now)
 		if err != nil && !errors.IsNotFound(err) {
-			evictionErrors = append(evictionErrors, err)
+			drainErrors = append(drainErrors, err)
+		}
+		if isBlocked {
+			blocked = append(blocked, pod)
 		}
 	}
-	return evictionErrors
+	return blocked, drainErrors
 }
 
 // PodIsDaemonSet returns true if the pod is a daemonset
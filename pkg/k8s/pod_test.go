@@ -99,6 +99,39 @@ func TestEvictOrForciblyDeletePod(t *testing.T) {
 		"pod deletion should have been attempted")
 }
 
+func TestDrainPod(t *testing.T) {
+	pod := test.BuildTestPod(test.PodOpts{
+		Name:      "test",
+		Namespace: "kube-system",
+		NodeName:  "test-node",
+	})
+	gracePeriod := int64(5)
+
+	var evictedGracePeriod *int64
+	var deleted bool
+	client, _ := test.BuildFakeClient(nil, []*corev1.Pod{pod})
+	client.Fake.AddReactor("create", "pods", func(action testingCore.Action) (bool, runtime.Object, error) {
+		eviction := action.(testingCore.CreateAction).GetObject().(*policyv1.Eviction)
+		evictedGracePeriod = eviction.DeleteOptions.GracePeriodSeconds
+		return true, nil, nil
+	})
+	client.Fake.AddReactor("delete", "pods", func(action testingCore.Action) (bool, runtime.Object, error) {
+		deleted = true
+		return true, nil, nil
+	})
+
+	// Evicting overrides the grace period
+	assert.NoError(t, DrainPod(pod, "core/v1", client, PodDrainOptions{GracePeriodSeconds: &gracePeriod}, timeNow()))
+	assert.Equal(t, &gracePeriod, evictedGracePeriod)
+	assert.False(t, deleted)
+
+	// Deleting doesn't evict the pod
+	evictedGracePeriod = nil
+	assert.NoError(t, DrainPod(pod, "core/v1", client, PodDrainOptions{Delete: true, GracePeriodSeconds: &gracePeriod}, timeNow()))
+	assert.Nil(t, evictedGracePeriod)
+	assert.True(t, deleted)
+}
+
 func TestEvictPods(t *testing.T) {
 	var pods []*corev1.Pod
 	pods = append(pods, test.BuildTestPod(test.PodOpts{
@@ -169,6 +202,35 @@ func TestEvictPods(t *testing.T) {
 	assert.Equal(t, true, unhealthyPodDeleted, "unhealthy pod should be deleted")
 }
 
+func TestDrainPodsBlocked(t *testing.T) {
+	pods := []*corev1.Pod{
+		test.BuildTestPod(test.PodOpts{Name: "test-allowed", Namespace: "kube-system", NodeName: "test-node"}),
+		test.BuildTestPod(test.PodOpts{Name: "test-blocked", Namespace: "kube-system", NodeName: "test-node"}),
+	}
+
+	client, _ := test.BuildFakeClient(nil, pods)
+	client.Fake.AddReactor("create", "pods", func(action testingCore.Action) (bool, runtime.Object, error) {
+		eviction := action.(testingCore.CreateAction).GetObject().(*policyv1.Eviction)
+		if eviction.Name == "test-blocked" {
+			return true, nil, apiErrors.NewTooManyRequests("", 10)
+		}
+		return true, nil, nil
+	})
+
+	blocked, errs := drainPods(pods, "core/v1", client, func(*corev1.Pod) PodDrainOptions {
+		return PodDrainOptions{UnhealthyAfter: testUnhealthyAfter}
+	}, timeNow())
+	assert.Empty(t, errs)
+	assert.Equal(t, []*corev1.Pod{pods[1]}, blocked)
+
+	// A deleted pod isn't blocked by a PodDisruptionBudget
+	blocked, errs = drainPods(pods, "core/v1", client, func(*corev1.Pod) PodDrainOptions {
+		return PodDrainOptions{Delete: true}
+	}, timeNow())
+	assert.Empty(t, errs)
+	assert.Empty(t, blocked)
+}
+
 func TestPodIsStatic(t *testing.T) {
 	pod := test.BuildTestPod(test.PodOpts{
 		Name: "test",
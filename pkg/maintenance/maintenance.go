@@ -0,0 +1,132 @@
+// Package maintenance works out whether the maintenance windows of NodeGroups and CycleNodeRequests are open.
+package maintenance
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	// The images are built from scratch, so embed the time zone database for maintenance windows
+	_ "time/tzdata"
+
+	"github.com/robfig/cron/v3"
+
+	v1 "github.com/atlassian-labs/cyclops/pkg/apis/atlassian/v1"
+)
+
+// timeFormat is the format of the start and end times of a MaintenanceWindow
+const timeFormat = "15:04"
+
+// ValidateWindow returns an error if the MaintenanceWindow is not valid, including its schedule, days, times and time
+// zone.
+func ValidateWindow(window *v1.MaintenanceWindow) error {
+	_, err := isOpen(window, time.Now())
+	return err
+}
+
+// isOpen returns true if the MaintenanceWindow is open at the given time.
+func isOpen(window *v1.MaintenanceWindow, t time.Time) (bool, error) {
+	if err := window.Validate(); err != nil {
+		return false, err
+	}
+
+	location := time.UTC
+	if window.TimeZone != "" {
+		var err error
+		if location, err = time.LoadLocation(window.TimeZone); err != nil {
+			return false, fmt.Errorf("invalid time zone %q: %v", window.TimeZone, err)
+		}
+	}
+	t = t.In(location)
+
+	if window.Schedule != "" {
+		schedule, err := cron.ParseStandard(window.Schedule)
+		if err != nil {
+			return false, fmt.Errorf("invalid schedule %q: %v", window.Schedule, err)
+		}
+
+		// The window is open if the schedule fired within the last duration
+		return !schedule.Next(t.Add(-window.Duration.Duration)).After(t), nil
+	}
+
+	days := make(map[time.Weekday]bool)
+	for _, day := range window.Days {
+		weekday, err := parseWeekday(day)
+		if err != nil {
+			return false, err
+		}
+		days[weekday] = true
+	}
+
+	// Default to the whole day if the times aren't given
+	start, err := parseTimeOfDay(window.StartTime)
+	if err != nil {
+		return false, err
+	}
+	end := start
+	if window.EndTime != "" {
+		if end, err = parseTimeOfDay(window.EndTime); err != nil {
+			return false, err
+		}
+	}
+
+	// Check the windows that opened today and yesterday, in case yesterday's window finishes after midnight
+	for _, offset := range []int{0, -1} {
+		day := t.AddDate(0, 0, offset)
+		if len(days) > 0 && !days[day.Weekday()] {
+			continue
+		}
+
+		opens := time.Date(day.Year(), day.Month(), day.Day(), int(start.Hours()), int(start.Minutes())%60, 0, 0, location)
+		closes := time.Date(day.Year(), day.Month(), day.Day(), int(end.Hours()), int(end.Minutes())%60, 0, 0, location)
+		if !closes.After(opens) {
+			closes = closes.AddDate(0, 0, 1)
+		}
+		if !t.Before(opens) && t.Before(closes) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// InWindow returns true if any of the maintenance windows are open at the given time. Returns true if there are no
+// maintenance windows, as nodes can be cycled at any time.
+func InWindow(windows []v1.MaintenanceWindow, t time.Time) (bool, error) {
+	if len(windows) == 0 {
+		return true, nil
+	}
+
+	for i := range windows {
+		open, err := isOpen(&windows[i], t)
+		if err != nil {
+			return false, err
+		}
+		if open {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// parseWeekday parses the full or short name of a day of the week, ignoring case
+func parseWeekday(day string) (time.Weekday, error) {
+	for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+		if strings.EqualFold(day, weekday.String()) || strings.EqualFold(day, weekday.String()[:3]) {
+			return weekday, nil
+		}
+	}
+	return time.Sunday, fmt.Errorf("invalid day %q", day)
+}
+
+// parseTimeOfDay parses a time of day in the maintenance window format into the duration since midnight
+func parseTimeOfDay(timeOfDay string) (time.Duration, error) {
+	if timeOfDay == "" {
+		return 0, nil
+	}
+	parsed, err := time.Parse(timeFormat, timeOfDay)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q, expected the format %q", timeOfDay, timeFormat)
+	}
+	return time.Duration(parsed.Hour())*time.Hour + time.Duration(parsed.Minute())*time.Minute, nil
+}
@@ -0,0 +1,146 @@
+package maintenance
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "github.com/atlassian-labs/cyclops/pkg/apis/atlassian/v1"
+)
+
+func TestInWindow(t *testing.T) {
+	// Wednesday
+	now := time.Date(2022, time.March, 16, 23, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		windows []v1.MaintenanceWindow
+		expect  bool
+		wantErr bool
+	}{
+		{"no windows", nil, true, false},
+		{
+			"inside time range",
+			[]v1.MaintenanceWindow{{StartTime: "22:00", EndTime: "23:59"}},
+			true,
+			false,
+		},
+		{
+			"outside time range",
+			[]v1.MaintenanceWindow{{StartTime: "09:00", EndTime: "17:00"}},
+			false,
+			false,
+		},
+		{
+			"time range over midnight",
+			[]v1.MaintenanceWindow{{Days: []string{"wed"}, StartTime: "22:00", EndTime: "02:00"}},
+			true,
+			false,
+		},
+		{
+			"time range over midnight from the previous day",
+			[]v1.MaintenanceWindow{{Days: []string{"Tuesday"}, StartTime: "22:00", EndTime: "02:00"}},
+			false,
+			false,
+		},
+		{
+			"whole day",
+			[]v1.MaintenanceWindow{{Days: []string{"Wed"}}},
+			true,
+			false,
+		},
+		{
+			"other days",
+			[]v1.MaintenanceWindow{{Days: []string{"Sat", "Sun"}}},
+			false,
+			false,
+		},
+		{
+			"time zone",
+			[]v1.MaintenanceWindow{{Days: []string{"Thu"}, StartTime: "09:00", EndTime: "12:00", TimeZone: "Australia/Sydney"}},
+			true,
+			false,
+		},
+		{
+			"any window open",
+			[]v1.MaintenanceWindow{{StartTime: "09:00", EndTime: "17:00"}, {StartTime: "23:00", EndTime: "01:00"}},
+			true,
+			false,
+		},
+		{
+			"inside schedule",
+			[]v1.MaintenanceWindow{{Schedule: "0 22 * * 1-5", Duration: &metav1.Duration{Duration: 2 * time.Hour}}},
+			true,
+			false,
+		},
+		{
+			"outside schedule",
+			[]v1.MaintenanceWindow{{Schedule: "0 22 * * 1-5", Duration: &metav1.Duration{Duration: time.Hour}}},
+			false,
+			false,
+		},
+		{
+			"schedule without duration",
+			[]v1.MaintenanceWindow{{Schedule: "0 22 * * 1-5"}},
+			false,
+			true,
+		},
+		{
+			"schedule with days",
+			[]v1.MaintenanceWindow{{Schedule: "0 22 * * *", Duration: &metav1.Duration{Duration: time.Hour}, Days: []string{"Mon"}}},
+			false,
+			true,
+		},
+		{
+			"invalid schedule",
+			[]v1.MaintenanceWindow{{Schedule: "every night", Duration: &metav1.Duration{Duration: time.Hour}}},
+			false,
+			true,
+		},
+		{
+			"invalid day",
+			[]v1.MaintenanceWindow{{Days: []string{"Someday"}}},
+			false,
+			true,
+		},
+		{
+			"invalid time",
+			[]v1.MaintenanceWindow{{StartTime: "10pm"}},
+			false,
+			true,
+		},
+		{
+			"invalid time zone",
+			[]v1.MaintenanceWindow{{StartTime: "22:00", TimeZone: "Mars/Olympus_Mons"}},
+			false,
+			true,
+		},
+		{
+			"empty window",
+			[]v1.MaintenanceWindow{{}},
+			false,
+			true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			open, err := InWindow(test.windows, now)
+			if test.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expect, open)
+		})
+	}
+}
+
+func TestValidateWindow(t *testing.T) {
+	assert.NoError(t, ValidateWindow(&v1.MaintenanceWindow{Days: []string{"Mon"}, StartTime: "22:00", TimeZone: "Australia/Sydney"}))
+	assert.Error(t, ValidateWindow(&v1.MaintenanceWindow{Schedule: "every night", Duration: &metav1.Duration{Duration: time.Hour}}))
+	assert.Error(t, ValidateWindow(&v1.MaintenanceWindow{StartTime: "22:00", TimeZone: "Mars/Olympus_Mons"}))
+	assert.Error(t, ValidateWindow(&v1.MaintenanceWindow{}))
+}
@@ -7,4 +7,5 @@ type Notifier interface {
 	CyclingStarted(*v1.CycleNodeRequest) error
 	PhaseTransitioned(*v1.CycleNodeRequest) error
 	NodesSelected(*v1.CycleNodeRequest) error
+	EvictionBlocked(*v1.CycleNodeRequest, *v1.CycleNodeStatus) error
 }
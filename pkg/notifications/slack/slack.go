@@ -22,9 +22,10 @@ var (
 	markdownType = "mrkdwn"
 
 	// Color of the attachment bar in the Slack status notification
-	blueColor  = "#3a72f4"
-	greenColor = "#1dd32c"
-	redColor   = "#e52023"
+	blueColor   = "#3a72f4"
+	greenColor  = "#1dd32c"
+	redColor    = "#e52023"
+	yellowColor = "#f2c744"
 
 	// Length of delay required to allow the reply message to enter the thread
 	timeDelay = 500 * time.Millisecond
@@ -55,6 +56,8 @@ func (n *notifier) generateThreadMessage(cnr *v1.CycleNodeRequest) slackapi.Atta
 		statusColor = greenColor
 	case v1.CycleNodeRequestFailed:
 		statusColor = redColor
+	case v1.CycleNodeRequestCancelled:
+		statusColor = yellowColor
 	default:
 		statusColor = blueColor
 	}
@@ -87,7 +90,7 @@ func (n *notifier) generateThreadMessage(cnr *v1.CycleNodeRequest) slackapi.Atta
 					slackapi.NewTextBlockObject(markdownType, fmt.Sprintf("*Cluster:*\n%s", cnr.ClusterName), false, false),
 					slackapi.NewTextBlockObject(markdownType, fmt.Sprintf("*Method:*\n%s", cnr.Spec.CycleSettings.Method), false, false),
 					slackapi.NewTextBlockObject(markdownType, fmt.Sprintf("*%s:*\n%s", nodeGroupTitle, strings.Join(nodeGroupList, "\n")), false, false),
-					slackapi.NewTextBlockObject(markdownType, fmt.Sprintf("*Concurrency:*\n%d", cnr.Spec.CycleSettings.Concurrency), false, false),
+					slackapi.NewTextBlockObject(markdownType, fmt.Sprintf("*Concurrency:*\n%s", cnr.Spec.CycleSettings.Concurrency.String()), false, false),
 				}, nil),
 			},
 		},
@@ -114,8 +117,8 @@ func (n *notifier) PhaseTransitioned(cnr *v1.CycleNodeRequest) error {
 		return fmt.Errorf("threadTimestamp not set in CycleNodeRequest")
 	}
 
-	// If the cycling succeeded, update the cycle status notification
-	if cnr.Status.Phase == v1.CycleNodeRequestSuccessful {
+	// If the cycling succeeded or was cancelled, update the cycle status notification
+	if cnr.Status.Phase == v1.CycleNodeRequestSuccessful || cnr.Status.Phase == v1.CycleNodeRequestCancelled {
 		if _, _, _, err := n.client.UpdateMessage(n.channelID, cnr.Status.ThreadTimestamp, slackapi.MsgOptionAttachments(n.generateThreadMessage(cnr))); err != nil {
 			return err
 		}
@@ -177,3 +180,29 @@ func (n *notifier) NodesSelected(cnr *v1.CycleNodeRequest) error {
 	_, _, _, err = n.client.UpdateMessage(n.channelID, cnr.Status.ThreadTimestamp, slackapi.MsgOptionAttachments(n.generateThreadMessage(cnr)))
 	return err
 }
+
+// EvictionBlocked pushes a threaded notification showing which pods on a node have had their eviction blocked for
+// too long
+func (n *notifier) EvictionBlocked(cnr *v1.CycleNodeRequest, cns *v1.CycleNodeStatus) error {
+	if cnr.Status.ThreadTimestamp == "" {
+		return fmt.Errorf("threadTimestamp not set in CycleNodeRequest")
+	}
+
+	var blockedPods []string
+	for _, pod := range cns.Status.BlockedPods {
+		blockedPods = append(blockedPods, fmt.Sprintf("%s/%s (%s)", pod.Namespace, pod.Name, strings.Join(pod.PodDisruptionBudgets, ", ")))
+	}
+
+	messageParameters := slackapi.NewPostMessageParameters()
+	messageParameters.ThreadTimestamp = cnr.Status.ThreadTimestamp
+
+	blocks := []slackapi.Block{
+		slackapi.NewSectionBlock(nil, []*slackapi.TextBlockObject{
+			slackapi.NewTextBlockObject(markdownType, fmt.Sprintf("Eviction blocked by a PodDisruptionBudget on node *%s*", cns.Status.CurrentNode.Name), false, false),
+			slackapi.NewTextBlockObject(markdownType, fmt.Sprintf("```%v```", strings.Join(blockedPods, "\n")), false, false),
+		}, nil),
+	}
+
+	_, _, err := n.client.PostMessage(n.channelID, slackapi.MsgOptionPostMessageParameters(messageParameters), slackapi.MsgOptionBlocks(blocks...))
+	return err
+}
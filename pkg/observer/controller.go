@@ -3,9 +3,11 @@ package observer
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net/http"
 	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/cenkalti/backoff/v4"
@@ -13,12 +15,16 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/prometheus/common/model"
 	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/klog"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	v1 "github.com/atlassian-labs/cyclops/pkg/apis/atlassian/v1"
 	"github.com/atlassian-labs/cyclops/pkg/generation"
 	"github.com/atlassian-labs/cyclops/pkg/k8s"
+	"github.com/atlassian-labs/cyclops/pkg/maintenance"
 	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
 )
 
@@ -209,7 +215,8 @@ func (c *controller) validNodeGroups() v1.NodeGroupList {
 }
 
 // inProgressCNRs lists the CNRs that are not in the phase CycleNodeRequestSuccessful
-// only successful CNRs are considered done. Failed is not done
+// only successful CNRs are considered done. Failed is not done, and neither is Cancelled so
+// that a cancelled nodegroup isn't cycled again until the CNR is removed
 func (c *controller) inProgressCNRs() v1.CycleNodeRequestList {
 	// List and check cnrs still in progress
 	options := &client.ListOptions{Namespace: c.Namespace}
@@ -251,6 +258,175 @@ func (c *controller) dropInProgressNodeGroups(nodeGroups v1.NodeGroupList, cnrs
 	return restingNodeGroups
 }
 
+// dropClosedNodeGroups filters out any nodeGroups that are outside of their maintenance windows at the given time
+func (c *controller) dropClosedNodeGroups(nodeGroups v1.NodeGroupList, now time.Time) v1.NodeGroupList {
+	var openNodeGroups v1.NodeGroupList
+	for i, nodeGroup := range nodeGroups.Items {
+		open, err := maintenance.InWindow(nodeGroup.Spec.MaintenanceWindows, now)
+		if err != nil {
+			klog.Warningf("nodegroup %q has an invalid maintenance window.. skipping this nodegroup: %s", nodeGroup.Name, err)
+			continue
+		}
+		if !open {
+			klog.V(2).Infof("nodegroup %q is outside of its maintenance windows.. skipping this nodegroup", nodeGroup.Name)
+			c.NodeGroupsWaiting.WithLabelValues(nodeGroup.Name).Inc()
+			continue
+		}
+		openNodeGroups.Items = append(openNodeGroups.Items, nodeGroups.Items[i])
+	}
+
+	return openNodeGroups
+}
+
+// updateNodeGroupStatuses records what was found for each of the nodegroups in their status, and saves the nodegroups
+// that changed. checkedNodeGroups are the nodegroups the observers were run on, and changedNodeGroups are the ones
+// they found to be out of date
+func (c *controller) updateNodeGroupStatuses(nodeGroups, checkedNodeGroups v1.NodeGroupList, changedNodeGroups []*ListedNodeGroups, now time.Time) {
+	options := &client.ListOptions{Namespace: c.Namespace}
+	allCNRs, err := generation.ListCNRs(c.client, options)
+	if err != nil {
+		klog.Errorln("could not list cnrs to update nodegroup statuses:", err)
+		return
+	}
+
+	checked := make(map[string]bool, len(checkedNodeGroups.Items))
+	for _, nodeGroup := range checkedNodeGroups.Items {
+		checked[nodeGroup.Name] = true
+	}
+	changed := make(map[string]*ListedNodeGroups, len(changedNodeGroups))
+	for i, nodeGroup := range changedNodeGroups {
+		changed[nodeGroup.NodeGroup.Name] = changedNodeGroups[i]
+	}
+
+	var updateOptions []client.UpdateOption
+	if c.DryMode {
+		updateOptions = append(updateOptions, client.DryRunAll)
+	}
+
+	for i := range nodeGroups.Items {
+		nodeGroup := &nodeGroups.Items[i]
+		original := nodeGroup.Status.DeepCopy()
+
+		c.setNodesCount(nodeGroup)
+		if checked[nodeGroup.Name] {
+			setNodeGroupDrift(nodeGroup, changed[nodeGroup.Name], now)
+		}
+		setNodeGroupCycleHistory(nodeGroup, allCNRs)
+		setNodeGroupConditions(nodeGroup, latestCNR(nodeGroup, allCNRs))
+
+		if apiequality.Semantic.DeepEqual(original, &nodeGroup.Status) {
+			continue
+		}
+		if err := c.client.Update(context.TODO(), nodeGroup, updateOptions...); err != nil {
+			klog.Errorf("failed to update status of nodegroup %q: %s", nodeGroup.Name, err)
+		}
+	}
+}
+
+// setNodesCount sets the number of nodes in the nodegroup. The count is left as is if the nodes can't be listed
+func (c *controller) setNodesCount(nodeGroup *v1.NodeGroup) {
+	selector, err := metav1.LabelSelectorAsSelector(&nodeGroup.Spec.NodeSelector)
+	if err != nil {
+		klog.Errorf("failed to parse selector %q for nodegroup %q: %s", nodeGroup.Spec.NodeSelector, nodeGroup.Name, err)
+		return
+	}
+	nodes, err := c.nodeLister.List(selector)
+	if err != nil {
+		klog.Errorf("failed to list nodes for nodegroup %q: %s", nodeGroup.Name, err)
+		return
+	}
+	nodeGroup.Status.NodesCount = len(nodes)
+}
+
+// setNodeGroupDrift records the result of checking the nodegroup for out of date nodes. changed is nil if the
+// nodegroup is up to date
+func setNodeGroupDrift(nodeGroup *v1.NodeGroup, changed *ListedNodeGroups, now time.Time) {
+	checkTime := metav1.NewTime(now)
+	nodeGroup.Status.LastCheckTime = &checkTime
+	nodeGroup.Status.NodesOutOfDate = 0
+	nodeGroup.Status.OutOfDateReasons = nil
+
+	if changed == nil {
+		return
+	}
+
+	nodeGroup.Status.NodesOutOfDate = len(changed.List)
+	for _, reason := range strings.Split(changed.Reason, "\n") {
+		if reason != "" {
+			nodeGroup.Status.OutOfDateReasons = append(nodeGroup.Status.OutOfDateReasons, reason)
+		}
+	}
+}
+
+// setNodeGroupCycleHistory records the latest CNR for the nodegroup and the time its nodes were last cycled
+// successfully. The last successful cycle time is kept once the CNR it came from has been deleted
+func setNodeGroupCycleHistory(nodeGroup *v1.NodeGroup, cnrs *v1.CycleNodeRequestList) {
+	if latest := latestCNR(nodeGroup, cnrs); latest != nil {
+		nodeGroup.Status.LastCycleNodeRequest = latest.Name
+		nodeGroup.Status.LastCycleNodeRequestPhase = latest.Status.Phase
+	}
+
+	for _, cnr := range cnrs.Items {
+		if cnr.Status.Phase != v1.CycleNodeRequestSuccessful || !sameNodeGroups(cnr.GetNodeGroupNames(), nodeGroup.GetNodeGroupNames()) {
+			continue
+		}
+
+		// The CNR finished when it stopped progressing. Fall back to when it was created for CNRs without conditions
+		finished := cnr.CreationTimestamp
+		if progressing := meta.FindStatusCondition(cnr.Status.Conditions, v1.CycleNodeRequestConditionProgressing); progressing != nil {
+			finished = progressing.LastTransitionTime
+		}
+
+		if nodeGroup.Status.LastSuccessfulCycleTime == nil || nodeGroup.Status.LastSuccessfulCycleTime.Before(&finished) {
+			nodeGroup.Status.LastSuccessfulCycleTime = finished.DeepCopy()
+		}
+	}
+}
+
+// latestCNR returns the most recently created CNR for the nodegroup, or nil if there are none
+func latestCNR(nodeGroup *v1.NodeGroup, cnrs *v1.CycleNodeRequestList) *v1.CycleNodeRequest {
+	var latest *v1.CycleNodeRequest
+	for i, cnr := range cnrs.Items {
+		if !sameNodeGroups(cnr.GetNodeGroupNames(), nodeGroup.GetNodeGroupNames()) {
+			continue
+		}
+		if latest == nil || latest.CreationTimestamp.Before(&cnr.CreationTimestamp) {
+			latest = &cnrs.Items[i]
+		}
+	}
+	return latest
+}
+
+// setNodeGroupConditions sets the Progressing and Degraded conditions of the nodegroup from the phase of its latest
+// CNR. It returns true if the conditions changed.
+func setNodeGroupConditions(nodeGroup *v1.NodeGroup, cnr *v1.CycleNodeRequest) bool {
+	progressing, degraded := metav1.ConditionFalse, metav1.ConditionFalse
+	reason, message := "NoCycleNodeRequest", "No CycleNodeRequests have been created for the nodegroup"
+
+	if cnr != nil {
+		reason = string(cnr.Status.Phase)
+		if reason == "" {
+			reason = "Created"
+		}
+		message = fmt.Sprintf("CycleNodeRequest %s is in phase %s", cnr.Name, reason)
+
+		switch cnr.Status.Phase {
+		case v1.CycleNodeRequestSuccessful, v1.CycleNodeRequestCancelled:
+		case v1.CycleNodeRequestFailed:
+			degraded = metav1.ConditionTrue
+			message = fmt.Sprintf("CycleNodeRequest %s failed: %s", cnr.Name, cnr.Status.Message)
+		case v1.CycleNodeRequestHealing:
+			progressing, degraded = metav1.ConditionTrue, metav1.ConditionTrue
+		default:
+			progressing = metav1.ConditionTrue
+		}
+	}
+
+	progressingChanged := k8s.SetCondition(&nodeGroup.Status.Conditions, nodeGroup.Generation, v1.NodeGroupConditionProgressing, progressing, reason, message)
+	degradedChanged := k8s.SetCondition(&nodeGroup.Status.Conditions, nodeGroup.Generation, v1.NodeGroupConditionDegraded, degraded, reason, message)
+	return progressingChanged || degradedChanged
+}
+
 // get the cluster-autoscaler last scaleUp activity time
 func stringToTime(s string) (time.Time, error) {
 	sec, err := strconv.ParseInt(s, 10, 64)
@@ -369,8 +545,9 @@ func (c *controller) checkIfSafeToStartCycle() bool {
 // implements cron.Job interface
 func (c *controller) Run() {
 	// get fresh valid nodegroups and in progress CNRs from the APIServer. These are not cached
-	nodeGroups := c.validNodeGroups()
+	validNodeGroups := c.validNodeGroups()
 	inProgressCNRs := c.inProgressCNRs()
+	nodeGroups := validNodeGroups
 
 	// Filter out any nodegroups that match in progress CNRs. This is done by NodeGroup (ASG) name
 	if len(inProgressCNRs.Items) == 0 {
@@ -379,8 +556,15 @@ func (c *controller) Run() {
 		nodeGroups = c.dropInProgressNodeGroups(nodeGroups, inProgressCNRs)
 	}
 
+	// Filter out any nodegroups that are outside of their maintenance windows
+	nodeGroups = c.dropClosedNodeGroups(nodeGroups, time.Now())
+
 	// observer the changes using the remaining nodegroups. This is stateless and will pickup changes again if restarted
 	changedNodeGroups := c.observeChanges(nodeGroups)
+
+	// Record what was found, and the state of the latest CNR, in the status of each nodegroup
+	c.updateNodeGroupStatuses(validNodeGroups, nodeGroups, changedNodeGroups, time.Now())
+
 	if len(changedNodeGroups) == 0 {
 		klog.V(2).Infoln("all nodegroups up to date. next check in", c.CheckInterval)
 		return
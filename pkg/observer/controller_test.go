@@ -5,12 +5,15 @@ import (
 	"fmt"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"testing"
+	"time"
 
 	atlassianv1 "github.com/atlassian-labs/cyclops/pkg/apis/atlassian/v1"
 	"github.com/atlassian-labs/cyclops/pkg/test"
 	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/meta"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
 
@@ -40,7 +43,7 @@ func TestController_validNodeGroups(t *testing.T) {
 		PodCount:     5,
 		PodsUpToDate: map[string]bool{"a": true, "b": true},
 	})
-	scenarioBad.Nodegroups["a"].Spec.CycleSettings.Concurrency = 0
+	scenarioBad.Nodegroups["a"].Spec.CycleSettings.Concurrency = intstr.FromInt(0)
 	scenarioBadFlat := scenarioBad.Flatten()
 
 	scenarioBadAll := test.BuildTestScenario(test.ScenarioOpts{
@@ -49,8 +52,8 @@ func TestController_validNodeGroups(t *testing.T) {
 		PodCount:     5,
 		PodsUpToDate: map[string]bool{"a": true, "b": true},
 	})
-	scenarioBadAll.Nodegroups["a"].Spec.CycleSettings.Concurrency = 0
-	scenarioBadAll.Nodegroups["b"].Spec.CycleSettings.Concurrency = 0
+	scenarioBadAll.Nodegroups["a"].Spec.CycleSettings.Concurrency = intstr.FromInt(0)
+	scenarioBadAll.Nodegroups["b"].Spec.CycleSettings.Concurrency = intstr.FromInt(0)
 	scenarioBadAllFlat := scenarioBadAll.Flatten()
 
 	tests := []struct {
@@ -80,7 +83,7 @@ func TestController_validNodeGroups(t *testing.T) {
 			scheme, _ := atlassianv1.SchemeBuilder.Build()
 			var objects []runtime.Object
 			for i := range tt.scenario.Nodegroups {
-				tt.scenario.Nodegroups[i].Spec.CycleSettings.Concurrency = 1
+				tt.scenario.Nodegroups[i].Spec.CycleSettings.Concurrency = intstr.FromInt(1)
 				objects = append(objects, tt.scenario.Nodegroups[i])
 			}
 			client := NewFakeClientWithScheme(scheme, objects...)
@@ -120,7 +123,7 @@ func Test_inProgressCNRs(t *testing.T) {
 				NodeGroupName: "test",
 				CycleSettings: atlassianv1.CycleSettings{
 					Method:      "Drain",
-					Concurrency: 1,
+					Concurrency: intstr.FromInt(1),
 				},
 			},
 		})
@@ -137,7 +140,7 @@ func Test_inProgressCNRs(t *testing.T) {
 				NodeGroupName: "test",
 				CycleSettings: atlassianv1.CycleSettings{
 					Method:      "Drain",
-					Concurrency: 1,
+					Concurrency: intstr.FromInt(1),
 				},
 			},
 			Status: atlassianv1.CycleNodeRequestStatus{
@@ -224,7 +227,7 @@ func Test_dropInProgressNodeGroups(t *testing.T) {
 				NodeGroupName: "nodegroup-x",
 				CycleSettings: atlassianv1.CycleSettings{
 					Method:      "Drain",
-					Concurrency: 1,
+					Concurrency: intstr.FromInt(1),
 				},
 			},
 		})
@@ -279,6 +282,270 @@ func Test_dropInProgressNodeGroups(t *testing.T) {
 	}
 }
 
+func Test_dropClosedNodeGroups(t *testing.T) {
+	scenario := test.BuildTestScenario(test.ScenarioOpts{
+		Keys:         []string{"a", "b", "c"},
+		NodeCount:    1,
+		PodCount:     1,
+		PodsUpToDate: map[string]bool{"a": true, "b": true, "c": true},
+	}).Flatten()
+
+	// Wednesday
+	now := time.Date(2022, time.March, 16, 23, 30, 0, 0, time.UTC)
+
+	nodeGroups := scenario.NodeGroupList()
+	nodeGroups.Items[0].Spec.MaintenanceWindows = []atlassianv1.MaintenanceWindow{{Days: []string{"Wed"}, StartTime: "22:00", EndTime: "02:00"}}
+	nodeGroups.Items[1].Spec.MaintenanceWindows = []atlassianv1.MaintenanceWindow{{Days: []string{"Sat", "Sun"}}}
+	nodeGroups.Items[2].Spec.MaintenanceWindows = nil
+
+	invalid := scenario.NodeGroupList()
+	invalid.Items[0].Spec.MaintenanceWindows = []atlassianv1.MaintenanceWindow{{StartTime: "late"}}
+
+	tests := []struct {
+		name   string
+		ng     atlassianv1.NodeGroupList
+		expect []atlassianv1.NodeGroup
+	}{
+		{
+			"test no maintenance windows",
+			scenario.NodeGroupList(),
+			scenario.NodeGroupList().Items,
+		},
+		{
+			"test mixed maintenance windows",
+			nodeGroups,
+			[]atlassianv1.NodeGroup{nodeGroups.Items[0], nodeGroups.Items[2]},
+		},
+		{
+			"test invalid maintenance window",
+			invalid,
+			invalid.Items[1:],
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := controller{metrics: newMetrics()}
+
+			got := c.dropClosedNodeGroups(tt.ng, now)
+			assert.ElementsMatch(t, tt.expect, got.Items)
+		})
+	}
+}
+
+func Test_setNodeGroupConditions(t *testing.T) {
+	newCNR := func(phase atlassianv1.CycleNodeRequestPhase) *atlassianv1.CycleNodeRequest {
+		return &atlassianv1.CycleNodeRequest{
+			ObjectMeta: v1.ObjectMeta{Name: "test-cnr", Namespace: "kube-system"},
+			Status:     atlassianv1.CycleNodeRequestStatus{Phase: phase, Message: "broken"},
+		}
+	}
+
+	tests := []struct {
+		name              string
+		cnr               *atlassianv1.CycleNodeRequest
+		expectProgressing v1.ConditionStatus
+		expectDegraded    v1.ConditionStatus
+		expectReason      string
+	}{
+		{"no cnr", nil, v1.ConditionFalse, v1.ConditionFalse, "NoCycleNodeRequest"},
+		{"new cnr", newCNR(atlassianv1.CycleNodeRequestUndefined), v1.ConditionTrue, v1.ConditionFalse, "Created"},
+		{"in progress", newCNR(atlassianv1.CycleNodeRequestScalingUp), v1.ConditionTrue, v1.ConditionFalse, "ScalingUp"},
+		{"healing", newCNR(atlassianv1.CycleNodeRequestHealing), v1.ConditionTrue, v1.ConditionTrue, "Healing"},
+		{"failed", newCNR(atlassianv1.CycleNodeRequestFailed), v1.ConditionFalse, v1.ConditionTrue, "Failed"},
+		{"successful", newCNR(atlassianv1.CycleNodeRequestSuccessful), v1.ConditionFalse, v1.ConditionFalse, "Successful"},
+		{"cancelled", newCNR(atlassianv1.CycleNodeRequestCancelled), v1.ConditionFalse, v1.ConditionFalse, "Cancelled"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var nodeGroup atlassianv1.NodeGroup
+
+			assert.True(t, setNodeGroupConditions(&nodeGroup, tt.cnr))
+			assert.False(t, setNodeGroupConditions(&nodeGroup, tt.cnr))
+
+			progressing := meta.FindStatusCondition(nodeGroup.Status.Conditions, atlassianv1.NodeGroupConditionProgressing)
+			degraded := meta.FindStatusCondition(nodeGroup.Status.Conditions, atlassianv1.NodeGroupConditionDegraded)
+			if assert.NotNil(t, progressing) && assert.NotNil(t, degraded) {
+				assert.Equal(t, tt.expectProgressing, progressing.Status)
+				assert.Equal(t, tt.expectDegraded, degraded.Status)
+				assert.Equal(t, tt.expectReason, progressing.Reason)
+			}
+		})
+	}
+}
+
+func Test_updateNodeGroupStatuses(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+
+	newNodeGroup := func(name string) *atlassianv1.NodeGroup {
+		return &atlassianv1.NodeGroup{
+			ObjectMeta: v1.ObjectMeta{Name: name},
+			Spec: atlassianv1.NodeGroupSpec{
+				NodeGroupName: name,
+				NodeSelector:  v1.LabelSelector{MatchLabels: map[string]string{"group": name}},
+			},
+		}
+	}
+	checkedGroup := newNodeGroup("test")
+	skippedGroup := newNodeGroup("skipped")
+
+	older := &atlassianv1.CycleNodeRequest{
+		ObjectMeta: v1.ObjectMeta{Name: "test-1", Namespace: "kube-system", CreationTimestamp: v1.NewTime(now.Add(-time.Hour))},
+		Spec:       atlassianv1.CycleNodeRequestSpec{NodeGroupName: "test"},
+		Status:     atlassianv1.CycleNodeRequestStatus{Phase: atlassianv1.CycleNodeRequestSuccessful},
+	}
+	latest := &atlassianv1.CycleNodeRequest{
+		ObjectMeta: v1.ObjectMeta{Name: "test-2", Namespace: "kube-system", CreationTimestamp: v1.NewTime(now)},
+		Spec:       atlassianv1.CycleNodeRequestSpec{NodeGroupName: "test"},
+		Status:     atlassianv1.CycleNodeRequestStatus{Phase: atlassianv1.CycleNodeRequestFailed, Message: "broken"},
+	}
+	other := &atlassianv1.CycleNodeRequest{
+		ObjectMeta: v1.ObjectMeta{Name: "other", Namespace: "kube-system", CreationTimestamp: v1.NewTime(now)},
+		Spec:       atlassianv1.CycleNodeRequestSpec{NodeGroupName: "other"},
+		Status:     atlassianv1.CycleNodeRequestStatus{Phase: atlassianv1.CycleNodeRequestScalingUp},
+	}
+
+	testNodes := test.BuildTestNodes(3, test.NodeOpts{LabelKey: "group", LabelValue: "test"})
+	skippedNodes := test.BuildTestNodes(2, test.NodeOpts{LabelKey: "group", LabelValue: "skipped"})
+
+	scheme, _ := atlassianv1.SchemeBuilder.Build()
+	c := controller{
+		client:     NewFakeClientWithScheme(scheme, checkedGroup, skippedGroup, older, latest, other),
+		nodeLister: test.NewTestNodeWatcher(append(testNodes, skippedNodes...), test.NodeListerOptions{}),
+		Options:    Options{Namespace: "kube-system"},
+	}
+
+	changed := []*ListedNodeGroups{{NodeGroup: checkedGroup, List: testNodes[:2], Reason: "old image\nold daemonset"}}
+	c.updateNodeGroupStatuses(
+		atlassianv1.NodeGroupList{Items: []atlassianv1.NodeGroup{*checkedGroup, *skippedGroup}},
+		atlassianv1.NodeGroupList{Items: []atlassianv1.NodeGroup{*checkedGroup}},
+		changed,
+		now,
+	)
+
+	var saved atlassianv1.NodeGroup
+	assert.NoError(t, c.client.Get(context.TODO(), client.ObjectKeyFromObject(checkedGroup), &saved))
+	assert.Equal(t, 3, saved.Status.NodesCount)
+	assert.Equal(t, 2, saved.Status.NodesOutOfDate)
+	assert.Equal(t, []string{"old image", "old daemonset"}, saved.Status.OutOfDateReasons)
+	assert.True(t, saved.Status.LastCheckTime.Equal(&v1.Time{Time: now}))
+	assert.Equal(t, "test-2", saved.Status.LastCycleNodeRequest)
+	assert.Equal(t, atlassianv1.CycleNodeRequestFailed, saved.Status.LastCycleNodeRequestPhase)
+	assert.True(t, saved.Status.LastSuccessfulCycleTime.Equal(&older.CreationTimestamp))
+	assert.True(t, meta.IsStatusConditionTrue(saved.Status.Conditions, atlassianv1.NodeGroupConditionDegraded))
+	assert.True(t, meta.IsStatusConditionFalse(saved.Status.Conditions, atlassianv1.NodeGroupConditionProgressing))
+	assert.Contains(t, meta.FindStatusCondition(saved.Status.Conditions, atlassianv1.NodeGroupConditionDegraded).Message, "test-2 failed: broken")
+
+	// Nodegroups that weren't checked still have their nodes counted, but no check recorded
+	var savedSkipped atlassianv1.NodeGroup
+	assert.NoError(t, c.client.Get(context.TODO(), client.ObjectKeyFromObject(skippedGroup), &savedSkipped))
+	assert.Equal(t, 2, savedSkipped.Status.NodesCount)
+	assert.Nil(t, savedSkipped.Status.LastCheckTime)
+	assert.Empty(t, savedSkipped.Status.LastCycleNodeRequest)
+}
+
+func Test_setNodeGroupDrift(t *testing.T) {
+	now := time.Now()
+	nodeGroup := &atlassianv1.NodeGroup{}
+
+	setNodeGroupDrift(nodeGroup, &ListedNodeGroups{
+		NodeGroup: nodeGroup,
+		List:      test.BuildTestNodes(2, test.NodeOpts{}),
+		Reason:    "old image",
+	}, now.Add(-time.Minute))
+	assert.Equal(t, 2, nodeGroup.Status.NodesOutOfDate)
+	assert.Equal(t, []string{"old image"}, nodeGroup.Status.OutOfDateReasons)
+
+	// A nodegroup found to be up to date has the previous drift cleared
+	setNodeGroupDrift(nodeGroup, nil, now)
+	assert.Equal(t, 0, nodeGroup.Status.NodesOutOfDate)
+	assert.Nil(t, nodeGroup.Status.OutOfDateReasons)
+	assert.True(t, nodeGroup.Status.LastCheckTime.Equal(&v1.Time{Time: now}))
+}
+
+func Test_setNodeGroupCycleHistory(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	finished := v1.NewTime(now.Add(-time.Minute))
+
+	newCNR := func(name string, phase atlassianv1.CycleNodeRequestPhase, created time.Time) atlassianv1.CycleNodeRequest {
+		return atlassianv1.CycleNodeRequest{
+			ObjectMeta: v1.ObjectMeta{Name: name, CreationTimestamp: v1.NewTime(created)},
+			Spec:       atlassianv1.CycleNodeRequestSpec{NodeGroupName: "test"},
+			Status:     atlassianv1.CycleNodeRequestStatus{Phase: phase},
+		}
+	}
+	withConditions := newCNR("test-2", atlassianv1.CycleNodeRequestSuccessful, now.Add(-time.Hour))
+	withConditions.Status.Conditions = []v1.Condition{{
+		Type:               atlassianv1.CycleNodeRequestConditionProgressing,
+		Status:             v1.ConditionFalse,
+		Reason:             string(atlassianv1.CycleNodeRequestSuccessful),
+		LastTransitionTime: finished,
+	}}
+
+	tests := []struct {
+		name                    string
+		cnrs                    []atlassianv1.CycleNodeRequest
+		lastSuccessfulCycleTime *v1.Time
+		expectCNR               string
+		expectPhase             atlassianv1.CycleNodeRequestPhase
+		expectSuccessfulTime    *v1.Time
+	}{
+		{
+			"no cnrs",
+			nil,
+			nil,
+			"",
+			"",
+			nil,
+		},
+		{
+			"successful cnr without conditions",
+			[]atlassianv1.CycleNodeRequest{newCNR("test-1", atlassianv1.CycleNodeRequestSuccessful, now.Add(-2*time.Hour))},
+			nil,
+			"test-1",
+			atlassianv1.CycleNodeRequestSuccessful,
+			&v1.Time{Time: now.Add(-2 * time.Hour)},
+		},
+		{
+			"successful cnr uses the time it finished",
+			[]atlassianv1.CycleNodeRequest{
+				newCNR("test-1", atlassianv1.CycleNodeRequestSuccessful, now.Add(-2*time.Hour)),
+				withConditions,
+				newCNR("test-3", atlassianv1.CycleNodeRequestScalingUp, now),
+			},
+			nil,
+			"test-3",
+			atlassianv1.CycleNodeRequestScalingUp,
+			&finished,
+		},
+		{
+			"last successful cycle time is kept after the cnr is deleted",
+			[]atlassianv1.CycleNodeRequest{newCNR("test-3", atlassianv1.CycleNodeRequestFailed, now)},
+			&finished,
+			"test-3",
+			atlassianv1.CycleNodeRequestFailed,
+			&finished,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nodeGroup := &atlassianv1.NodeGroup{Spec: atlassianv1.NodeGroupSpec{NodeGroupName: "test"}}
+			nodeGroup.Status.LastSuccessfulCycleTime = tt.lastSuccessfulCycleTime
+
+			setNodeGroupCycleHistory(nodeGroup, &atlassianv1.CycleNodeRequestList{Items: tt.cnrs})
+			assert.Equal(t, tt.expectCNR, nodeGroup.Status.LastCycleNodeRequest)
+			assert.Equal(t, tt.expectPhase, nodeGroup.Status.LastCycleNodeRequestPhase)
+			if tt.expectSuccessfulTime == nil {
+				assert.Nil(t, nodeGroup.Status.LastSuccessfulCycleTime)
+			} else if assert.NotNil(t, nodeGroup.Status.LastSuccessfulCycleTime) {
+				assert.True(t, tt.expectSuccessfulTime.Equal(nodeGroup.Status.LastSuccessfulCycleTime))
+			}
+		})
+	}
+}
+
 func Test_sameNodeGroups(t *testing.T) {
 	tests := []struct {
 		name   string
@@ -15,6 +15,7 @@ type metrics struct {
 	NodeGroupsOutOfDate *prometheus.CounterVec
 	CNRsCreated         *prometheus.CounterVec
 	NodeGroupsLocked    *prometheus.CounterVec
+	NodeGroupsWaiting   *prometheus.CounterVec
 	ObserverRunTimes    *prometheus.GaugeVec
 }
 
@@ -45,6 +46,14 @@ func newMetrics() *metrics {
 			},
 			[]string{"nodegroup"},
 		),
+		NodeGroupsWaiting: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name:      "nodegroups_waiting_maintenance_window",
+				Namespace: metricsNamespace,
+				Help:      "counter of nodegroups skipped because they are outside of their maintenance windows",
+			},
+			[]string{"nodegroup"},
+		),
 		ObserverRunTimes: prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
 				Name:      "run_times",
@@ -0,0 +1,75 @@
+package test
+
+import (
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute"
+)
+
+// MockScaleSetService is a mock implementation of the Azure Virtual Machine Scale Set API used by the azure cloud provider
+type MockScaleSetService struct {
+	ScaleSets   map[string]*armcompute.VirtualMachineScaleSet
+	ScaleSetVMs map[string][]*armcompute.VirtualMachineScaleSetVM
+
+	GetScaleSetErr         error
+	ListScaleSetVMsErr     error
+	SetScaleSetCapacityErr error
+	GetScaleSetVMErr       error
+	UpdateScaleSetVMErr    error
+	DeleteScaleSetVMErr    error
+
+	Capacities map[string]int64
+	UpdatedVMs []*armcompute.VirtualMachineScaleSetVM
+	DeletedVMs []string
+}
+
+// GetScaleSet mock implementation for MockScaleSetService. Scale sets are keyed by name
+func (m *MockScaleSetService) GetScaleSet(resourceGroup, name string) (*armcompute.VirtualMachineScaleSet, error) {
+	return m.ScaleSets[name], m.GetScaleSetErr
+}
+
+// ListScaleSetVMs mock implementation for MockScaleSetService. VMs are keyed by scale set name
+func (m *MockScaleSetService) ListScaleSetVMs(resourceGroup, name string) ([]*armcompute.VirtualMachineScaleSetVM, error) {
+	return m.ScaleSetVMs[name], m.ListScaleSetVMsErr
+}
+
+// SetScaleSetCapacity mock implementation for MockScaleSetService
+func (m *MockScaleSetService) SetScaleSetCapacity(resourceGroup, name string, capacity int64) error {
+	if m.SetScaleSetCapacityErr != nil {
+		return m.SetScaleSetCapacityErr
+	}
+	if m.Capacities == nil {
+		m.Capacities = make(map[string]int64)
+	}
+	m.Capacities[name] = capacity
+	return nil
+}
+
+// GetScaleSetVM mock implementation for MockScaleSetService
+func (m *MockScaleSetService) GetScaleSetVM(resourceGroup, name, instanceID string) (*armcompute.VirtualMachineScaleSetVM, error) {
+	if m.GetScaleSetVMErr != nil {
+		return nil, m.GetScaleSetVMErr
+	}
+	for _, vm := range m.ScaleSetVMs[name] {
+		if vm.InstanceID != nil && *vm.InstanceID == instanceID {
+			return vm, nil
+		}
+	}
+	return nil, nil
+}
+
+// UpdateScaleSetVM mock implementation for MockScaleSetService
+func (m *MockScaleSetService) UpdateScaleSetVM(resourceGroup, name, instanceID string, vm *armcompute.VirtualMachineScaleSetVM) error {
+	if m.UpdateScaleSetVMErr != nil {
+		return m.UpdateScaleSetVMErr
+	}
+	m.UpdatedVMs = append(m.UpdatedVMs, vm)
+	return nil
+}
+
+// DeleteScaleSetVM mock implementation for MockScaleSetService
+func (m *MockScaleSetService) DeleteScaleSetVM(resourceGroup, name, instanceID string) error {
+	if m.DeleteScaleSetVMErr != nil {
+		return m.DeleteScaleSetVMErr
+	}
+	m.DeletedVMs = append(m.DeletedVMs, instanceID)
+	return nil
+}
@@ -0,0 +1,84 @@
+package test
+
+import (
+	"google.golang.org/api/compute/v1"
+)
+
+// MockComputeService is a mock implementation of the GCP Compute API used by the gcp cloud provider
+type MockComputeService struct {
+	InstanceGroupManagers map[string]*compute.InstanceGroupManager
+	ManagedInstances      map[string][]*compute.ManagedInstance
+	Instances             map[string]*compute.Instance
+
+	GetInstanceGroupManagerErr    error
+	ListManagedInstancesErr       error
+	AbandonInstancesErr           error
+	ResizeInstanceGroupManagerErr error
+	GetInstanceErr                error
+	SetInstanceLabelsErr          error
+	DeleteInstanceErr             error
+
+	AbandonedInstances []string
+	ResizedTo          map[string]int64
+	DeletedInstances   []string
+}
+
+// GetInstanceGroupManager mock implementation for MockComputeService. Groups are keyed by name
+func (m *MockComputeService) GetInstanceGroupManager(project, zone, name string) (*compute.InstanceGroupManager, error) {
+	return m.InstanceGroupManagers[name], m.GetInstanceGroupManagerErr
+}
+
+// ListManagedInstances mock implementation for MockComputeService. Instances are keyed by group name
+func (m *MockComputeService) ListManagedInstances(project, zone, name string) ([]*compute.ManagedInstance, error) {
+	return m.ManagedInstances[name], m.ListManagedInstancesErr
+}
+
+// AbandonInstances mock implementation for MockComputeService
+func (m *MockComputeService) AbandonInstances(project, zone, name string, instanceURLs []string) error {
+	if m.AbandonInstancesErr != nil {
+		return m.AbandonInstancesErr
+	}
+	m.AbandonedInstances = append(m.AbandonedInstances, instanceURLs...)
+	return nil
+}
+
+// ResizeInstanceGroupManager mock implementation for MockComputeService
+func (m *MockComputeService) ResizeInstanceGroupManager(project, zone, name string, size int64) error {
+	if m.ResizeInstanceGroupManagerErr != nil {
+		return m.ResizeInstanceGroupManagerErr
+	}
+	if m.ResizedTo == nil {
+		m.ResizedTo = make(map[string]int64)
+	}
+	m.ResizedTo[name] = size
+	return nil
+}
+
+// GetInstance mock implementation for MockComputeService. Instances are keyed by name
+func (m *MockComputeService) GetInstance(project, zone, name string) (*compute.Instance, error) {
+	return m.Instances[name], m.GetInstanceErr
+}
+
+// SetInstanceLabels mock implementation for MockComputeService. The instance is added if it doesn't exist
+func (m *MockComputeService) SetInstanceLabels(project, zone, name string, labels map[string]string, fingerprint string) error {
+	if m.SetInstanceLabelsErr != nil {
+		return m.SetInstanceLabelsErr
+	}
+	if m.Instances == nil {
+		m.Instances = make(map[string]*compute.Instance)
+	}
+	if m.Instances[name] == nil {
+		m.Instances[name] = &compute.Instance{Name: name}
+	}
+	m.Instances[name].Labels = labels
+	return nil
+}
+
+// DeleteInstance mock implementation for MockComputeService
+func (m *MockComputeService) DeleteInstance(project, zone, name string) error {
+	if m.DeleteInstanceErr != nil {
+		return m.DeleteInstanceErr
+	}
+	m.DeletedInstances = append(m.DeletedInstances, name)
+	return nil
+}